@@ -0,0 +1,99 @@
+package cmd
+
+import (
+	"fmt"
+
+	"supertab/internal/ai"
+	"supertab/internal/cache"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// cacheCmd represents the cache command
+var cacheCmd = &cobra.Command{
+	Use:   "cache",
+	Short: "Manage the completion cache",
+	Long: `Manage the on-disk cache of completions.
+Completions are cached by input, directory, git branch and Kubernetes context.`,
+}
+
+// cacheStatsCmd represents the cache stats command
+var cacheStatsCmd = &cobra.Command{
+	Use:   "stats",
+	Short: "Show completion cache statistics",
+	Args:  cobra.NoArgs,
+	RunE:  runCacheStats,
+}
+
+// cacheClearCmd represents the cache clear command
+var cacheClearCmd = &cobra.Command{
+	Use:   "clear",
+	Short: "Remove all cached completions",
+	Args:  cobra.NoArgs,
+	RunE:  runCacheClear,
+}
+
+func init() {
+	rootCmd.AddCommand(cacheCmd)
+	cacheCmd.AddCommand(cacheStatsCmd)
+	cacheCmd.AddCommand(cacheClearCmd)
+
+	viper.SetDefault("cache.enabled", true)
+	viper.SetDefault("cache.ttl", cache.DefaultTTL)
+	viper.SetDefault("cache.max_size", "5MB")
+}
+
+// newCacheStore creates the completion cache from the cache section of the config
+func newCacheStore() *cache.Store {
+	dir := viper.GetString("cache.dir")
+	if dir == "" {
+		dir = cache.DefaultDir()
+	}
+	return cache.NewStore(dir, viper.GetDuration("cache.ttl"), int64(viper.GetSizeInBytes("cache.max_size")))
+}
+
+// newCompletionClient creates the client answering completions, behind the
+// completion cache when cached is set and the cache is enabled. The store of
+// the cache, nil without it, counts hits to flush before exiting.
+func newCompletionClient(cached bool) (ai.Client, *cache.Store, error) {
+	configs, err := providerConfigs("complete")
+	if err != nil {
+		return nil, nil, err
+	}
+	client, err := newChainClient(configs)
+	if err != nil || !cached || !viper.GetBool("cache.enabled") {
+		return client, nil, err
+	}
+
+	store := newCacheStore()
+	return cache.NewClient(client, store, cache.Scope(configs), viper.GetBool("debug")), store, nil
+}
+
+// runCacheStats executes the cache stats command logic
+func runCacheStats(cmd *cobra.Command, args []string) error {
+	stats, err := newCacheStore().Stats()
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Directory: %s\n", stats.Dir)
+	fmt.Printf("Entries: %d (%d expired)\n", stats.Entries, stats.Expired)
+	fmt.Printf("Size: %.1f KB\n", float64(stats.Bytes)/1024)
+	fmt.Printf("Hits: %d\n", stats.Hits)
+	fmt.Printf("Misses: %d\n", stats.Misses)
+	if total := stats.Hits + stats.Misses; total > 0 {
+		fmt.Printf("Hit rate: %.1f%%\n", float64(stats.Hits)*100/float64(total))
+	}
+
+	return nil
+}
+
+// runCacheClear executes the cache clear command logic
+func runCacheClear(cmd *cobra.Command, args []string) error {
+	if err := newCacheStore().Clear(); err != nil {
+		return err
+	}
+	fmt.Println("Completion cache cleared")
+	return nil
+}
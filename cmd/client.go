@@ -0,0 +1,159 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"supertab/internal/ai"
+
+	"github.com/spf13/viper"
+)
+
+// newAIClient resolves the configured or detected providers and creates a client.
+// Several providers are wrapped in a fallback chain that fails over in order.
+// The command name ("complete" or "predict") selects per-command overrides.
+func newAIClient(command string) (ai.Client, error) {
+	configs, err := providerConfigs(command)
+	if err != nil {
+		return nil, err
+	}
+	return newChainClient(configs)
+}
+
+// newChainClient creates a client for configs, falling back from each
+// provider to the next
+func newChainClient(configs []ai.Config) (ai.Client, error) {
+	var (
+		client ai.Client
+		err    error
+	)
+	if len(configs) == 1 {
+		client, err = ai.NewClient(configs[0])
+	} else {
+		client, err = ai.NewFallbackClient(configs, viper.GetDuration("fallback_timeout"))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create AI client: %w", err)
+	}
+
+	return client, nil
+}
+
+// providerConfigs returns the client configuration of each configured or
+// detected provider that is set up, in order
+func providerConfigs(command string) ([]ai.Config, error) {
+	providers := configuredProviders()
+
+	if len(providers) == 0 {
+		providers = ai.DetectProviders()
+		if len(providers) == 0 {
+			return nil, fmt.Errorf("%w: no AI provider found. Set one of: OPENAI_API_KEY, ANTHROPIC_API_KEY, GEMINI_API_KEY, GROQ_API_KEY, OLLAMA_HOST", ai.ErrMissingKey)
+		}
+	}
+
+	var configs []ai.Config
+	var skipped []error
+	for _, provider := range providers {
+		config, err := providerConfig(provider, command)
+		if err != nil {
+			// A chain keeps working without the providers that are not set up
+			skipped = append(skipped, err)
+			if viper.GetBool("debug") {
+				fmt.Fprintf(os.Stderr, "Debug: skipping provider %s: %v\n", provider, err)
+			}
+			continue
+		}
+		configs = append(configs, config)
+	}
+
+	if len(configs) == 0 {
+		return nil, errors.Join(skipped...)
+	}
+	return configs, nil
+}
+
+// configuredProviders returns the provider chain from the provider setting, which
+// may be a single name, a comma-separated list or a YAML list
+func configuredProviders() []ai.Provider {
+	var providers []ai.Provider
+	for _, value := range viper.GetStringSlice("provider") {
+		for _, name := range strings.Split(value, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				providers = append(providers, ai.Provider(name))
+			}
+		}
+	}
+	return providers
+}
+
+// providerConfig builds the client configuration for a provider from
+// the providers.<name> section of the config file and the environment.
+// The API key is read from the variable named by api_key_env there, or else
+// from the one ai.APIKeyEnv lists for the provider.
+// Generation settings in providers.<name>.<command> override the provider-wide ones.
+func providerConfig(provider ai.Provider, command string) (ai.Config, error) {
+	keyEnv, ok := ai.APIKeyEnv[provider]
+	if !ok {
+		return ai.Config{}, fmt.Errorf("unsupported provider: %s", provider)
+	}
+
+	prefix := "providers." + string(provider) + "."
+	if env := viper.GetString(prefix + "api_key_env"); env != "" {
+		keyEnv = env
+	}
+
+	config := ai.Config{
+		Provider: provider,
+		APIKey:   os.Getenv(keyEnv),
+		BaseURL:  viper.GetString(prefix + "base_url"),
+		Headers:  viper.GetStringMapString(prefix + "headers"),
+		Debug:    viper.GetBool("debug"),
+
+		MaxRetries: viper.GetInt("max_retries"),
+	}
+	if viper.IsSet(prefix + "max_retries") {
+		config.MaxRetries = viper.GetInt(prefix + "max_retries")
+	}
+
+	for _, p := range []string{prefix, prefix + command + "."} {
+		applyGenerationSettings(&config, p)
+	}
+
+	if config.APIKey == "" && provider.RequiresAPIKey() {
+		return ai.Config{}, fmt.Errorf("%w for provider %s (set %s)", ai.ErrMissingKey, provider, keyEnv)
+	}
+
+	if provider == ai.ProviderOllama {
+		if config.BaseURL == "" {
+			config.BaseURL = os.Getenv("OLLAMA_HOST")
+		}
+		if config.Model == "" {
+			config.Model = os.Getenv("OLLAMA_MODEL")
+		}
+	}
+
+	return config, nil
+}
+
+// applyGenerationSettings copies the model and sampling parameters set under prefix
+func applyGenerationSettings(config *ai.Config, prefix string) {
+	if viper.IsSet(prefix + "model") {
+		config.Model = viper.GetString(prefix + "model")
+	}
+	if viper.IsSet(prefix + "temperature") {
+		temperature := viper.GetFloat64(prefix + "temperature")
+		config.Temperature = &temperature
+	}
+	if viper.IsSet(prefix + "top_p") {
+		topP := viper.GetFloat64(prefix + "top_p")
+		config.TopP = &topP
+	}
+	if viper.IsSet(prefix + "max_tokens") {
+		config.MaxTokens = viper.GetInt(prefix + "max_tokens")
+	}
+	if viper.IsSet(prefix + "stop") {
+		config.Stop = viper.GetStringSlice(prefix + "stop")
+	}
+}
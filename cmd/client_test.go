@@ -0,0 +1,145 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"supertab/internal/ai"
+
+	"github.com/spf13/viper"
+)
+
+// useConfig loads config as the config file for the duration of the test
+func useConfig(t *testing.T, config string) {
+	viper.SetConfigType("yaml")
+	if err := viper.ReadConfig(strings.NewReader(config)); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { viper.ReadConfig(strings.NewReader("")) })
+}
+
+func TestProviderConfigAPIKey(t *testing.T) {
+	t.Setenv("OPENAI_API_KEY", "sk-openai")
+	t.Setenv("GATEWAY_TOKEN", "sk-gateway")
+	t.Setenv("ANTHROPIC_API_KEY", "")
+	t.Setenv("OPENAI_COMPATIBLE_API_KEY", "")
+
+	tests := []struct {
+		name     string
+		config   string
+		provider ai.Provider
+		want     string
+		wantErr  error
+	}{
+		{"default variable", "", ai.ProviderOpenAI, "sk-openai", nil},
+		{"api_key_env", "providers: {openai: {api_key_env: GATEWAY_TOKEN}}", ai.ProviderOpenAI, "sk-gateway", nil},
+		{"missing key", "", ai.ProviderAnthropic, "", ai.ErrMissingKey},
+		{"api_key_env unset", "providers: {anthropic: {api_key_env: UNSET_TOKEN}}", ai.ProviderAnthropic, "", ai.ErrMissingKey},
+		{"optional key", "", ai.ProviderOpenAICompatible, "", nil},
+		{"optional key from api_key_env", "providers: {openai-compatible: {api_key_env: GATEWAY_TOKEN}}", ai.ProviderOpenAICompatible, "sk-gateway", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useConfig(t, tt.config)
+			config, err := providerConfig(tt.provider, "complete")
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("providerConfig() error = %v, want %v", err, tt.wantErr)
+			}
+			if config.APIKey != tt.want {
+				t.Errorf("APIKey = %q, want %q", config.APIKey, tt.want)
+			}
+		})
+	}
+}
+
+func TestProviderConfigOpenAICompatible(t *testing.T) {
+	useConfig(t, `
+providers:
+  openai-compatible:
+    base_url: "http://vllm.internal:8000/v1"
+    model: "meta-llama/Llama-3.1-8B-Instruct"
+    headers:
+      X-Team: "sre"
+`)
+
+	config, err := providerConfig(ai.ProviderOpenAICompatible, "complete")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// viper lowercases keys; HTTP header names are case-insensitive
+	if config.BaseURL != "http://vllm.internal:8000/v1" || config.Model != "meta-llama/Llama-3.1-8B-Instruct" ||
+		!reflect.DeepEqual(config.Headers, map[string]string{"x-team": "sre"}) {
+		t.Errorf("providerConfig() = %+v", config)
+	}
+}
+
+func TestProviderConfigGenerationSettings(t *testing.T) {
+	t.Setenv("ANTHROPIC_API_KEY", "sk-ant")
+	config := `
+providers:
+  anthropic:
+    model: claude-3-5-sonnet-latest
+    temperature: 0.5
+    max_tokens: 200
+    stop: ["\n\n"]
+    complete:
+      temperature: 0
+      stop: ["\n"]
+    predict:
+      model: claude-3-5-haiku-latest
+      max_tokens: 50
+`
+	half, zero := 0.5, 0.0
+
+	tests := []struct {
+		name    string
+		config  string
+		command string
+		want    ai.Config
+	}{
+		{
+			name:    "provider defaults",
+			command: "complete",
+			want:    ai.Config{},
+		},
+		{
+			name:    "command overrides provider",
+			config:  config,
+			command: "complete",
+			want:    ai.Config{Model: "claude-3-5-sonnet-latest", Temperature: &zero, MaxTokens: 200, Stop: []string{"\n"}},
+		},
+		{
+			name:    "overrides of the other command left out",
+			config:  config,
+			command: "predict",
+			want:    ai.Config{Model: "claude-3-5-haiku-latest", Temperature: &half, MaxTokens: 50, Stop: []string{"\n\n"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useConfig(t, tt.config)
+			config, err := providerConfig(ai.ProviderAnthropic, tt.command)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := ai.Config{Model: config.Model, Temperature: config.Temperature, TopP: config.TopP, MaxTokens: config.MaxTokens, Stop: config.Stop}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("providerConfig() settings = %s, want %s", describeSettings(got), describeSettings(tt.want))
+			}
+		})
+	}
+}
+
+// describeSettings prints the generation settings of config, following pointers
+func describeSettings(config ai.Config) string {
+	temperature := "unset"
+	if config.Temperature != nil {
+		temperature = fmt.Sprint(*config.Temperature)
+	}
+	return fmt.Sprintf("model=%q temperature=%s max_tokens=%d stop=%q", config.Model, temperature, config.MaxTokens, config.Stop)
+}
@@ -2,12 +2,17 @@ package cmd
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"os"
+	"strings"
+	"sync"
 	"time"
 
 	"supertab/internal/ai"
-	contextpkg "supertab/internal/context"
+	"supertab/internal/daemon"
+	"supertab/internal/local"
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
@@ -24,12 +29,22 @@ The input can be provided as an argument or via stdin.`,
 	SilenceUsage: true, // Don't show usage on error
 }
 
+// localHistoryLimit is how many history commands the local stage matches against
+const localHistoryLimit = 1000
+
 func init() {
 	rootCmd.AddCommand(completeCmd)
 
 	// Command-specific flags
 	completeCmd.Flags().String("input", "", "input command to complete")
 	completeCmd.Flags().Duration("timeout", 30*time.Second, "request timeout")
+	completeCmd.Flags().Bool("stream", false, "write the suggestion incrementally as it is generated")
+	completeCmd.Flags().Bool("no-cache", false, "bypass the completion cache")
+	completeCmd.Flags().Bool("no-local", false, "always ask the provider, even when the completion is unambiguous")
+	addNoDaemonFlag(completeCmd)
+	addErrorFormatFlag(completeCmd)
+
+	viper.SetDefault("local.enabled", true)
 }
 
 // runComplete executes the complete command logic
@@ -49,52 +64,95 @@ func runComplete(cmd *cobra.Command, args []string) error {
 	ctx, cancel := context.WithTimeout(context.Background(), timeout)
 	defer cancel()
 
-	// Detect AI provider if not specified
-	providerName := viper.GetString("provider")
-	apiKey := ""
-
-	if providerName == "" {
-		var provider ai.Provider
-		provider, apiKey = ai.DetectProvider()
-		if provider == "" {
-			return fmt.Errorf("no AI provider found. Set one of: OPENAI_API_KEY, ANTHROPIC_API_KEY, GEMINI_API_KEY, GROQ_API_KEY")
-		}
-		providerName = string(provider)
-	} else {
-		// Get API key for specified provider
-		switch providerName {
-		case "openai":
-			apiKey = os.Getenv("OPENAI_API_KEY")
-		case "anthropic":
-			apiKey = os.Getenv("ANTHROPIC_API_KEY")
-		case "gemini":
-			apiKey = os.Getenv("GEMINI_API_KEY")
-		case "groq":
-			apiKey = os.Getenv("GROQ_API_KEY")
-		default:
-			return fmt.Errorf("unsupported provider: %s", providerName)
+	// History is read at most once, with enough entries for both the local
+	// stage and the aliases ranked for a request
+	recentHistory := sync.OnceValues(func() ([]ai.HistoryEntry, error) {
+		return newHistoryParser().GetRecentHistory(max(localHistoryLimit, daemon.AliasHistoryLimit))
+	})
+
+	// Answer unambiguous completions without calling the provider
+	if noLocal, _ := cmd.Flags().GetBool("no-local"); !noLocal && viper.GetBool("local.enabled") {
+		if result, ok := completeLocally(input, recentHistory); ok {
+			if viper.GetBool("debug") {
+				fmt.Fprintf(os.Stderr, "Debug: completed locally (%s)\n", result.Stage)
+			}
+			fmt.Printf("+%s", result.Response.Content)
+			return nil
 		}
 	}
 
-	if apiKey == "" {
-		return fmt.Errorf("API key not found for provider %s", providerName)
+	var onDelta ai.DeltaFunc
+	stream, _ := cmd.Flags().GetBool("stream")
+	if stream {
+		// The raw text already carries the +/= prefix, so it is printed as it arrives
+		onDelta = (&streamPrinter{out: os.Stdout}).write
+	}
+
+	response, err := requestCompletion(ctx, cmd, input, recentHistory, onDelta)
+	if err != nil {
+		return err
+	}
+	if stream {
+		return nil
 	}
 
-	// Create AI client
-	config := ai.Config{
-		Provider: ai.Provider(providerName),
-		APIKey:   apiKey,
-		Debug:    viper.GetBool("debug"),
+	// Output the result based on response type
+	switch response.Type {
+	case ai.TypeCompletion:
+		fmt.Printf("+%s", response.Content)
+	case ai.TypeReplacement:
+		fmt.Printf("=%s", response.Content)
+	default:
+		fmt.Print(response.Content)
+	}
+
+	return nil
+}
+
+// completeLocally runs the local completion stage against the working
+// directory and recent history
+func completeLocally(input string, recentHistory func() ([]ai.HistoryEntry, error)) (local.Result, bool) {
+	dir, _ := os.Getwd()
+	completer := &local.Completer{Dir: dir}
+
+	if entries, err := recentHistory(); err == nil {
+		for _, entry := range lastEntries(entries, localHistoryLimit) {
+			completer.History = append(completer.History, entry.Command)
+		}
+	}
+
+	return completer.Complete(input)
+}
+
+// requestCompletion asks a running daemon for the completion and falls back
+// to calling the provider directly when no daemon answers.
+// A non-nil onDelta streams the completion.
+func requestCompletion(ctx context.Context, cmd *cobra.Command, input string, recentHistory func() ([]ai.HistoryEntry, error), onDelta ai.DeltaFunc) (*ai.Response, error) {
+	noCache, _ := cmd.Flags().GetBool("no-cache")
+	if remote := daemonClient(cmd); remote != nil && !noCache {
+		dir, _ := os.Getwd()
+		response, err := remote.Complete(ctx, input, dir, onDelta)
+		if !errors.Is(err, daemon.ErrUnavailable) {
+			if err != nil {
+				return nil, fmt.Errorf("failed to get completion: %w", err)
+			}
+			return response, nil
+		}
+		if viper.GetBool("debug") {
+			fmt.Fprintf(os.Stderr, "Debug: %v, completing locally\n", err)
+		}
 	}
 
-	client, err := ai.NewClient(config)
+	client, cacheStore, err := newCompletionClient(!noCache)
 	if err != nil {
-		return fmt.Errorf("failed to create AI client: %w", err)
+		return nil, err
+	}
+	if cacheStore != nil {
+		defer cacheStore.Flush()
 	}
 
 	// Collect context
-	contextCollector := contextpkg.NewCollector()
-	contextInfo := contextCollector.Collect()
+	contextInfo := collectContext(ctx, input)
 
 	// Create completion request
 	req := ai.CompletionRequest{
@@ -102,21 +160,57 @@ func runComplete(cmd *cobra.Command, args []string) error {
 		Context: contextInfo,
 	}
 
-	// Call AI service
-	response, err := client.Complete(ctx, req)
+	// Recent history ranks the aliases sent with the request
+	if entries, err := recentHistory(); err == nil {
+		req.History = lastEntries(entries, daemon.AliasHistoryLimit)
+	}
+
+	var response *ai.Response
+	if onDelta != nil {
+		response, err = client.Stream(ctx, req, onDelta)
+	} else {
+		response, err = client.Complete(ctx, req)
+	}
 	if err != nil {
-		return fmt.Errorf("failed to get completion: %w", err)
+		return nil, fmt.Errorf("failed to get completion: %w", err)
 	}
 
-	// Output the result based on response type
-	switch response.Type {
-	case ai.TypeCompletion:
-		fmt.Printf("+%s", response.Content)
-	case ai.TypeReplacement:
-		fmt.Printf("=%s", response.Content)
-	default:
-		fmt.Print(response.Content)
+	return response, nil
+}
+
+// lastEntries returns the last limit entries
+func lastEntries(entries []ai.HistoryEntry, limit int) []ai.HistoryEntry {
+	if len(entries) > limit {
+		return entries[len(entries)-limit:]
 	}
+	return entries
+}
 
-	return nil
+// streamPrinter writes streamed text the way the non-streaming output would look:
+// leading whitespace is dropped and whitespace is only written once more text follows,
+// so the suggestion never ends in a newline. Newlines inside the suggestion, as in
+// a heredoc or a continued command, are kept.
+type streamPrinter struct {
+	out     io.Writer
+	started bool
+	pending string
+}
+
+// write handles one streamed chunk
+func (p *streamPrinter) write(delta string) {
+	if !p.started {
+		delta = strings.TrimLeft(delta, " \t\r\n")
+		if delta == "" {
+			return
+		}
+		p.started = true
+	}
+
+	text := p.pending + delta
+	trimmed := strings.TrimRight(text, " \t\r\n")
+	p.pending = text[len(trimmed):]
+
+	if trimmed != "" {
+		fmt.Fprint(p.out, trimmed)
+	}
 }
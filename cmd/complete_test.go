@@ -0,0 +1,35 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestStreamPrinter(t *testing.T) {
+	tests := []struct {
+		name   string
+		chunks []string
+		want   string // what the non-streaming path prints for the joined text
+	}{
+		{"completion", []string{"+", "at", "us"}, "+atus"},
+		{"leading whitespace", []string{"\n ", " +a", "tus"}, "+atus"},
+		{"trailing whitespace", []string{"=git status", " ", "\n", "\n"}, "=git status"},
+		{"inner whitespace", []string{"=git ", " ", "status"}, "=git  status"},
+		{"heredoc", []string{"+ <<EOF\n", "hello\n", "EOF", "\n"}, "+ <<EOF\nhello\nEOF"},
+		{"continued command", []string{"=docker run \\", "\n  --rm", " alpine\n"}, "=docker run \\\n  --rm alpine"},
+		{"whitespace only", []string{" ", "\n"}, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out strings.Builder
+			printer := &streamPrinter{out: &out}
+			for _, chunk := range tt.chunks {
+				printer.write(chunk)
+			}
+			if out.String() != tt.want {
+				t.Errorf("streamed %q, want %q", out.String(), tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,37 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"supertab/internal/ai"
+	contextpkg "supertab/internal/context"
+
+	"github.com/spf13/viper"
+)
+
+// collectContext collects the context for a request, reporting collectors
+// that ran out of time when debug is enabled. input is the partial command
+// being completed, if any.
+func collectContext(ctx context.Context, input string) ai.Context {
+	contextInfo, timings := contextpkg.NewCollector().WithSources(contextSources()).WithInput(input).Collect(ctx)
+	if viper.GetBool("debug") {
+		for _, timing := range timings {
+			if timing.Error != "" {
+				fmt.Fprintf(os.Stderr, "Debug: context %s gave up after %s: %s\n", timing.Name, timing.Duration.Round(time.Millisecond), timing.Error)
+			}
+		}
+	}
+	return contextInfo
+}
+
+// contextSources returns the context.sources switches from the config
+func contextSources() map[string]bool {
+	sources := make(map[string]bool)
+	for name := range viper.GetStringMap("context.sources") {
+		sources[name] = viper.GetBool("context.sources." + name)
+	}
+	return sources
+}
@@ -0,0 +1,202 @@
+package cmd
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	contextpkg "supertab/internal/context"
+	"supertab/internal/daemon"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// daemonCmd represents the daemon command
+var daemonCmd = &cobra.Command{
+	Use:   "daemon",
+	Short: "Run the background daemon",
+	Long: `Run a long-lived daemon that answers complete and predict requests over a Unix socket.
+The daemon keeps provider clients, collected context and parsed history warm, so
+sug complete and sug predict only pay for the model call. Both commands use the
+daemon when it is running and fall back to working locally when it is not, or
+when their settings (config file, --provider, API keys) differ from the ones
+the daemon started with. Restart the daemon to apply config changes to it.`,
+	Args: cobra.NoArgs,
+	RunE: runDaemon,
+}
+
+// daemonStatusCmd represents the daemon status command
+var daemonStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Check whether the daemon is running",
+	Args:  cobra.NoArgs,
+	RunE:  runDaemonStatus,
+}
+
+// daemonStopCmd represents the daemon stop command
+var daemonStopCmd = &cobra.Command{
+	Use:   "stop",
+	Short: "Stop the running daemon",
+	Args:  cobra.NoArgs,
+	RunE:  runDaemonStop,
+}
+
+func init() {
+	rootCmd.AddCommand(daemonCmd)
+	daemonCmd.AddCommand(daemonStatusCmd)
+	daemonCmd.AddCommand(daemonStopCmd)
+
+	daemonCmd.PersistentFlags().String("socket", "", "daemon socket path (default is $XDG_RUNTIME_DIR/sug/daemon.sock)")
+	viper.BindPFlag("daemon.socket", daemonCmd.PersistentFlags().Lookup("socket"))
+
+	viper.SetDefault("daemon.enabled", true)
+	viper.SetDefault("daemon.context_ttl", daemon.DefaultContextTTL)
+}
+
+// daemonSocketPath returns the configured socket path or the default one
+func daemonSocketPath() string {
+	if path := viper.GetString("daemon.socket"); path != "" {
+		return path
+	}
+	return daemon.DefaultSocketPath()
+}
+
+// daemonClient returns a client for the daemon, or nil when the command
+// should not use it
+func daemonClient(cmd *cobra.Command) *daemon.Client {
+	if noDaemon, _ := cmd.Flags().GetBool("no-daemon"); noDaemon || !viper.GetBool("daemon.enabled") {
+		return nil
+	}
+	return daemon.NewClient(daemonSocketPath()).
+		WithSettings(daemonSettings()).
+		WithEnv(contextpkg.CurrentEnv()).
+		WithHistory(historySource())
+}
+
+// daemonSettings fingerprints the settings that change what the daemon answers:
+// the providers and models with their parameters (and --provider), the cache,
+// the context sources and the history source. A daemon started with other
+// settings refuses requests, so config changes apply without a restart.
+func daemonSettings() string {
+	settings := map[string]any{
+		"fallback_timeout": viper.GetDuration("fallback_timeout"),
+		"cache":            viper.Get("cache"),
+		"sources":          contextSources(),
+		"history":          viper.GetString("history.source"),
+	}
+	for _, command := range []string{"complete", "predict"} {
+		configs, err := providerConfigs(command)
+		for i := range configs {
+			configs[i].Debug = false
+		}
+		settings[command] = configs
+		if err != nil {
+			settings[command+"_error"] = err.Error()
+		}
+	}
+
+	data, err := json.Marshal(settings)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// addNoDaemonFlag adds the --no-daemon flag to a command that can be served by the daemon
+func addNoDaemonFlag(cmd *cobra.Command) {
+	cmd.Flags().Bool("no-daemon", false, "do not use a running daemon")
+}
+
+// runDaemon executes the daemon command logic
+func runDaemon(cmd *cobra.Command, args []string) error {
+	debug := viper.GetBool("debug")
+
+	completeClient, cacheStore, err := newCompletionClient(true)
+	if err != nil {
+		return err
+	}
+	if cacheStore != nil {
+		defer cacheStore.Flush()
+	}
+
+	predictClient, err := newAIClient("predict")
+	if err != nil {
+		return err
+	}
+
+	path := daemonSocketPath()
+	ln, err := daemon.Listen(path)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(path)
+
+	server := daemon.NewServer(daemon.Options{
+		Complete:   completeClient,
+		Predict:    predictClient,
+		ContextTTL: viper.GetDuration("daemon.context_ttl"),
+		Sources:    contextSources(),
+		History:    historySource(),
+		Settings:   daemonSettings(),
+		Debug:      debug,
+	})
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(signals)
+	go func() {
+		<-signals
+		server.Shutdown()
+	}()
+
+	if debug {
+		fmt.Fprintf(os.Stderr, "Debug: daemon listening on %s\n", path)
+	}
+
+	return server.Serve(ln)
+}
+
+// runDaemonStatus executes the daemon status command logic
+func runDaemonStatus(cmd *cobra.Command, args []string) error {
+	path := daemonSocketPath()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	if err := daemon.NewClient(path).Ping(ctx); err != nil {
+		if errors.Is(err, daemon.ErrUnavailable) {
+			fmt.Printf("Daemon is not running (%s)\n", path)
+			return nil
+		}
+		return err
+	}
+
+	fmt.Printf("Daemon is running (%s)\n", path)
+	return nil
+}
+
+// runDaemonStop executes the daemon stop command logic
+func runDaemonStop(cmd *cobra.Command, args []string) error {
+	path := daemonSocketPath()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	if err := daemon.NewClient(path).Shutdown(ctx); err != nil {
+		if errors.Is(err, daemon.ErrUnavailable) {
+			fmt.Println("Daemon is not running")
+			return nil
+		}
+		return err
+	}
+
+	fmt.Println("Daemon stopped")
+	return nil
+}
@@ -1,15 +1,17 @@
 package cmd
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"os"
 	"os/exec"
+	"sort"
 	"strings"
+	"time"
 
 	"supertab/internal/ai"
 	contextpkg "supertab/internal/context"
-	"supertab/internal/history"
 
 	"github.com/spf13/cobra"
 )
@@ -30,6 +32,7 @@ func init() {
 	debugCmd.Flags().Int("history-limit", 5, "number of recent history entries to show")
 	debugCmd.Flags().Bool("json", false, "output in JSON format")
 	debugCmd.Flags().Bool("debug-aliases", false, "show detailed alias collection debug info")
+	debugCmd.Flags().String("input", "", "partial command to collect input-specific context for, e.g. file listings")
 }
 
 // runDebug executes the debug command logic
@@ -37,6 +40,7 @@ func runDebug(cmd *cobra.Command, args []string) error {
 	historyLimit, _ := cmd.Flags().GetInt("history-limit")
 	jsonOutput, _ := cmd.Flags().GetBool("json")
 	debugAliases, _ := cmd.Flags().GetBool("debug-aliases")
+	input, _ := cmd.Flags().GetString("input")
 
 	// Debug alias collection if requested
 	if debugAliases {
@@ -105,11 +109,11 @@ func runDebug(cmd *cobra.Command, args []string) error {
 	}
 
 	// Collect context
-	contextCollector := contextpkg.NewCollector()
-	contextInfo := contextCollector.Collect()
+	contextCollector := contextpkg.NewCollector().WithSources(contextSources()).WithInput(input)
+	contextInfo, timings := contextCollector.Collect(context.Background())
 
 	// Get recent history
-	historyParser := history.NewParser()
+	historyParser := newHistoryParser()
 	recentHistory, err := historyParser.GetRecentHistory(historyLimit)
 	if err != nil {
 		fmt.Printf("Warning: failed to get history: %v\n", err)
@@ -120,6 +124,7 @@ func runDebug(cmd *cobra.Command, args []string) error {
 		// Output in JSON format
 		debugInfo := map[string]interface{}{
 			"context": contextInfo,
+			"timings": timings,
 			"history": recentHistory,
 		}
 
@@ -145,6 +150,26 @@ func runDebug(cmd *cobra.Command, args []string) error {
 		// Git info
 		if contextInfo.IsGitRepo {
 			fmt.Printf("Git: Repository (branch: %s)\n", contextInfo.GitBranch)
+			if git := contextInfo.Git; git != nil {
+				fmt.Printf("  Root: %s\n", git.Root)
+				fmt.Printf("  Changes: %d staged, %d unstaged, %d untracked", git.Staged, git.Unstaged, git.Untracked)
+				if git.Conflicted > 0 {
+					fmt.Printf(", %d conflicted", git.Conflicted)
+				}
+				fmt.Println()
+				if git.Upstream != "" {
+					fmt.Printf("  Upstream: %s (ahead %d, behind %d)\n", git.Upstream, git.Ahead, git.Behind)
+				}
+				if git.Stashes > 0 {
+					fmt.Printf("  Stashes: %d\n", git.Stashes)
+				}
+				if git.Operation != "" {
+					fmt.Printf("  In progress: %s\n", git.Operation)
+				}
+				if git.LastCommit != "" {
+					fmt.Printf("  Last commit: %s\n", git.LastCommit)
+				}
+			}
 		} else {
 			fmt.Println("Git: Not a repository")
 		}
@@ -157,28 +182,144 @@ func runDebug(cmd *cobra.Command, args []string) error {
 			if contextInfo.K8sContext.ClusterInfo != "" {
 				fmt.Printf("  Cluster: %s\n", contextInfo.K8sContext.ClusterInfo)
 			}
+			k8s := contextInfo.K8sContext
+			for _, issue := range k8s.FailingPods {
+				fmt.Printf("  Failing: %s (%s)\n", issue.Name, issue.Reason)
+			}
+			if len(k8s.Pods) > 0 {
+				fmt.Printf("  Pods: %s\n", strings.Join(k8s.Pods, ", "))
+			}
+			if len(k8s.Deployments) > 0 {
+				fmt.Printf("  Deployments: %s\n", strings.Join(k8s.Deployments, ", "))
+			}
+			if len(k8s.Services) > 0 {
+				fmt.Printf("  Services: %s\n", strings.Join(k8s.Services, ", "))
+			}
 		} else {
 			fmt.Println("Kubernetes: Not available")
 		}
 
+		// Docker info
+		if docker := contextInfo.Docker; docker != nil {
+			fmt.Println("Docker: Detected")
+			if docker.ComposeFile != "" {
+				fmt.Printf("  Compose: %s (services: %s)\n", docker.ComposeFile, strings.Join(docker.ComposeServices, ", "))
+			}
+			if len(docker.Dockerfiles) > 0 {
+				fmt.Printf("  Dockerfiles: %s\n", strings.Join(docker.Dockerfiles, ", "))
+			}
+			if docker.CurrentContext != "" {
+				fmt.Printf("  Context: %s\n", docker.CurrentContext)
+			}
+			if len(docker.Containers) > 0 {
+				fmt.Printf("  Containers: %s\n", strings.Join(docker.Containers, ", "))
+			}
+		}
+
+		// Cloud info
+		if cloud := contextInfo.Cloud; cloud != nil {
+			if aws := cloud.AWS; aws != nil {
+				fmt.Printf("AWS: profile %s", aws.Profile)
+				if aws.Region != "" {
+					fmt.Printf(" (region: %s)", aws.Region)
+				}
+				fmt.Println()
+			}
+			if gcp := cloud.GCP; gcp != nil {
+				fmt.Printf("GCP: project %s, account %s (configuration: %s)\n", gcp.Project, gcp.Account, gcp.Configuration)
+			}
+			if azure := cloud.Azure; azure != nil {
+				fmt.Printf("Azure: subscription %s (%s)\n", azure.Subscription, azure.SubscriptionID)
+			}
+			if tf := cloud.Terraform; tf != nil {
+				fmt.Printf("Terraform: workspace %s (initialized: %t)\n", tf.Workspace, tf.Initialized)
+			}
+		}
+
+		// Project info
+		if project := contextInfo.Project; project != nil {
+			fmt.Printf("Project: %s", project.Root)
+			if len(project.Types) > 0 {
+				fmt.Printf(" (%s)", strings.Join(project.Types, ", "))
+			}
+			fmt.Println()
+			for _, targets := range project.Targets {
+				fmt.Printf("  %s (%s): %s\n", targets.Runner, targets.File, strings.Join(targets.Targets, ", "))
+			}
+		}
+
+		// File listings
+		for _, listing := range contextInfo.Files {
+			fmt.Printf("Files in %s", listing.Dir)
+			if listing.Prefix != "" {
+				fmt.Printf(" matching %q", listing.Prefix)
+			}
+			fmt.Printf(" (%d", len(listing.Entries))
+			if listing.Truncated {
+				fmt.Print(", truncated")
+			}
+			fmt.Printf("): %s\n", strings.Join(listing.Entries, " "))
+		}
+
+		// Functions, abbreviations and installed programs
+		if commands := contextInfo.Commands; commands != nil {
+			if len(commands.Functions) > 0 {
+				fmt.Printf("Functions: %s\n", strings.Join(commands.Functions, ", "))
+			}
+			for _, alias := range ai.RankAliases(commands.GlobalAliases, input, recentHistory, 10) {
+				fmt.Printf("Global alias: %s='%s'\n", alias.Name, alias.Command)
+			}
+			for _, abbreviation := range ai.RankAliases(commands.Abbreviations, input, recentHistory, 10) {
+				fmt.Printf("Abbreviation: %s='%s'\n", abbreviation.Name, abbreviation.Command)
+			}
+			if len(commands.Executables) > 0 {
+				fmt.Printf("Installed (%d): %s\n", len(commands.Executables), strings.Join(commands.Executables, " "))
+			}
+		}
+
+		// Context from other sources
+		if len(contextInfo.Extras) > 0 {
+			names := make([]string, 0, len(contextInfo.Extras))
+			for name := range contextInfo.Extras {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			for _, name := range names {
+				fmt.Printf("%s: %v\n", name, contextInfo.Extras[name])
+			}
+		}
+
+		// Collector timing
+		fmt.Printf("\n⏱️  CONTEXT COLLECTION TIMING\n")
+		fmt.Println("------------------------------")
+		for _, timing := range timings {
+			fmt.Printf("%-12s %6s", timing.Name, timing.Duration.Round(time.Millisecond))
+			if timing.Error != "" {
+				fmt.Printf(" (gave up: %s)", timing.Error)
+			}
+			fmt.Println()
+		}
+
 		// Aliases
 		fmt.Printf("\n📝 SHELL ALIASES (%d found)\n", len(contextInfo.Aliases))
 		fmt.Println("------------------------------")
-		aliasCount := 0
-		for name, command := range contextInfo.Aliases {
-			if aliasCount >= 10 {
-				fmt.Printf("... and %d more aliases\n", len(contextInfo.Aliases)-10)
-				break
-			}
-			fmt.Printf("%s='%s'\n", name, command)
-			aliasCount++
+		// Show the aliases a completion of --input would send, most relevant first
+		for _, alias := range ai.RankAliases(contextInfo.Aliases, input, recentHistory, 10) {
+			fmt.Printf("%s='%s'\n", alias.Name, alias.Command)
+		}
+		if len(contextInfo.Aliases) > 10 {
+			fmt.Printf("... and %d more aliases\n", len(contextInfo.Aliases)-10)
 		}
 
 		// History
-		fmt.Printf("\n📚 RECENT COMMAND HISTORY (%d entries)\n", len(recentHistory))
+		fmt.Printf("\n📚 RECENT COMMAND HISTORY (%d entries from %s)\n", len(recentHistory), historyParser.HistoryFile())
 		fmt.Println("----------------------------------------")
 		for i, entry := range recentHistory {
-			fmt.Printf("%d. [%s] %s", i+1, entry.Timestamp.Format("15:04:05"), entry.Command)
+			fmt.Printf("%d. ", i+1)
+			if !entry.Timestamp.IsZero() {
+				fmt.Printf("[%s] ", entry.Timestamp.Format("15:04:05"))
+			}
+			fmt.Print(entry.Command)
 			if entry.ExitCode != 0 {
 				fmt.Printf(" (exit: %d)", entry.ExitCode)
 			}
@@ -187,7 +328,7 @@ func runDebug(cmd *cobra.Command, args []string) error {
 			}
 			fmt.Println()
 
-			if entry.Output != "" && entry.Output != "[command output not available]" {
+			if entry.Output != "" {
 				fmt.Printf("   Output: %s\n", entry.Output)
 			}
 			if entry.ErrorOutput != "" {
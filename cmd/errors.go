@@ -0,0 +1,91 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"supertab/internal/ai"
+
+	"github.com/spf13/cobra"
+)
+
+// Exit codes by error kind. 1 remains the code for anything unclassified.
+const (
+	exitError           = 1
+	exitMissingKey      = 3
+	exitRateLimited     = 4
+	exitTimeout         = 5
+	exitInvalidResponse = 6
+	exitNetwork         = 7
+	exitAuth            = 8
+	exitOverloaded      = 9
+	exitBadRequest      = 10
+)
+
+// exitCodes maps ai.ErrorKind names to exit codes
+var exitCodes = map[string]int{
+	"missing_key":      exitMissingKey,
+	"rate_limited":     exitRateLimited,
+	"timeout":          exitTimeout,
+	"invalid_response": exitInvalidResponse,
+	"network":          exitNetwork,
+	"auth":             exitAuth,
+	"overloaded":       exitOverloaded,
+	"bad_request":      exitBadRequest,
+}
+
+// exitCode returns the process exit code for err
+func exitCode(err error) int {
+	if code, ok := exitCodes[ai.ErrorKind(err)]; ok {
+		return code
+	}
+	return exitError
+}
+
+// addErrorFormatFlag adds --error-format to a command. With "json", errors are
+// written to stderr as a single JSON object instead of cobra's text output.
+func addErrorFormatFlag(cmd *cobra.Command) {
+	cmd.Flags().String("error-format", "text", "error output format (text, json)")
+	cmd.PreRun = func(cmd *cobra.Command, args []string) {
+		if format, _ := cmd.Flags().GetString("error-format"); format == "json" {
+			cmd.SilenceErrors = true
+		}
+	}
+}
+
+// errorReport is the JSON shape written by --error-format json
+type errorReport struct {
+	Error      string  `json:"error"`
+	Message    string  `json:"message"`
+	ExitCode   int     `json:"exit_code"`
+	RetryAfter float64 `json:"retry_after,omitempty"` // seconds
+}
+
+// reportError writes err as JSON if the failed command asked for it;
+// otherwise cobra has already printed it
+func reportError(cmd *cobra.Command, err error) {
+	if cmd == nil {
+		return
+	}
+	if format, _ := cmd.Flags().GetString("error-format"); format != "json" {
+		return
+	}
+
+	data, marshalErr := json.Marshal(newErrorReport(err))
+	if marshalErr != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return
+	}
+	fmt.Fprintln(os.Stderr, string(data))
+}
+
+// newErrorReport describes err for --error-format json
+func newErrorReport(err error) errorReport {
+	return errorReport{
+		Error:      ai.ErrorKind(err),
+		Message:    err.Error(),
+		ExitCode:   exitCode(err),
+		RetryAfter: ai.RetryAfter(err).Seconds(),
+	}
+}
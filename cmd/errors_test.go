@@ -0,0 +1,93 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"supertab/internal/ai"
+)
+
+// rateLimitedServer answers every request with 429 and the given Retry-After
+func rateLimitedServer(t *testing.T, retryAfter string) string {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", retryAfter)
+		w.WriteHeader(http.StatusTooManyRequests)
+		fmt.Fprint(w, `{"error": {"message": "slow down"}}`)
+	}))
+	t.Cleanup(server.Close)
+	return server.URL
+}
+
+func TestExitCodeThroughFallbackChain(t *testing.T) {
+	var configs []ai.Config
+	for _, retryAfter := range []string{"20", "1.5"} {
+		configs = append(configs, ai.Config{
+			Provider:   ai.ProviderOpenAICompatible,
+			BaseURL:    rateLimitedServer(t, retryAfter),
+			Model:      "test",
+			MaxRetries: -1,
+		})
+	}
+	client, err := ai.NewFallbackClient(configs, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = client.Complete(context.Background(), ai.CompletionRequest{Input: "git st"})
+	if err == nil {
+		t.Fatal("Complete() succeeded against rate limited providers")
+	}
+
+	report := newErrorReport(err)
+	if report.ExitCode != exitRateLimited || report.Error != "rate_limited" {
+		t.Errorf("report = %+v, want exit code %d and kind rate_limited", report, exitRateLimited)
+	}
+	if report.RetryAfter != 1.5 {
+		t.Errorf("retry_after = %g, want 1.5", report.RetryAfter)
+	}
+
+	data, err := json.Marshal(report)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded map[string]any
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if decoded["retry_after"] != 1.5 {
+		t.Errorf("JSON retry_after = %v in %s", decoded["retry_after"], data)
+	}
+}
+
+func TestExitCode(t *testing.T) {
+	tests := []struct {
+		err  error
+		want int
+	}{
+		{errors.New("boom"), exitError},
+		{fmt.Errorf("no provider: %w", ai.ErrMissingKey), exitMissingKey},
+		{&ai.APIError{StatusCode: http.StatusTooManyRequests}, exitRateLimited},
+		{&ai.APIError{StatusCode: http.StatusUnauthorized}, exitAuth},
+		{&ai.APIError{StatusCode: http.StatusBadGateway}, exitOverloaded},
+		{&ai.APIError{StatusCode: http.StatusNotFound}, exitBadRequest},
+		{context.DeadlineExceeded, exitTimeout},
+		{fmt.Errorf("%w: dial", ai.ErrNetwork), exitNetwork},
+		{fmt.Errorf("%w: eof", ai.ErrInvalidResponse), exitInvalidResponse},
+		{&ai.ChainError{
+			Providers: []ai.Provider{ai.ProviderOpenAI, ai.ProviderAnthropic},
+			Errs:      []error{&ai.APIError{StatusCode: http.StatusTooManyRequests, RetryAfter: time.Second}, ai.ErrTimeout},
+		}, exitTimeout},
+	}
+
+	for _, tt := range tests {
+		if got := exitCode(tt.err); got != tt.want {
+			t.Errorf("exitCode(%v) = %d, want %d", tt.err, got, tt.want)
+		}
+	}
+}
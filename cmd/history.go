@@ -0,0 +1,183 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"supertab/internal/ai"
+	"supertab/internal/history"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// historyCmd represents the history command
+var historyCmd = &cobra.Command{
+	Use:   "history",
+	Short: "Show recorded command history",
+	Long: `Show the commands recorded by the shell hooks (see 'sug record'), most recent last.
+Filters combine: --here --prefix "git " lists the git commands run in this directory.`,
+	Args: cobra.NoArgs,
+	RunE: runHistory,
+}
+
+// historyImportCmd represents the history import command
+var historyImportCmd = &cobra.Command{
+	Use:   "import",
+	Short: "Seed the recorded history from another history",
+	Long: `Copy commands from another history into the one recorded by the shell hooks.
+Atuin and McFly databases carry the directory, exit code and session of each
+command (and Atuin the duration); shell histories carry commands and times only.
+Commands already recorded are skipped, so importing again is safe. Like
+recording, importing keeps only the commands within history.max_records and
+history.max_age.
+
+Reading Atuin and McFly databases needs the sqlite3 command.`,
+	Example: `  sug history import --from atuin`,
+	Args:    cobra.NoArgs,
+	RunE:    runHistoryImport,
+}
+
+func init() {
+	rootCmd.AddCommand(historyCmd)
+
+	historyCmd.Flags().String("dir", "", "only commands run in this directory")
+	historyCmd.Flags().Bool("here", false, "only commands run in the working directory")
+	historyCmd.Flags().String("repo", "", "only commands run in the git repository rooted here")
+	historyCmd.Flags().String("session", "", "only commands of this shell session")
+	historyCmd.Flags().Duration("since", 0, "only commands started within this duration, e.g. 2h")
+	historyCmd.Flags().String("prefix", "", "only commands starting with this prefix")
+	historyCmd.Flags().Int("limit", 20, "number of commands to show (0 shows all)")
+	historyCmd.Flags().Bool("json", false, "print records as JSON lines")
+
+	historyCmd.AddCommand(historyImportCmd)
+	historyImportCmd.Flags().String("from", "", "history to import: "+strings.Join(history.SourceNames, ", "))
+	historyImportCmd.Flags().Int("limit", 0, "import only the most recent commands (0 imports all)")
+	historyImportCmd.MarkFlagRequired("from")
+
+	viper.SetDefault("history.source", "auto")
+	viper.SetDefault("history.max_records", history.DefaultMaxRecords)
+	viper.SetDefault("history.max_age", history.DefaultMaxAge)
+}
+
+// newHistoryStore opens the store of recorded commands with the configured limits
+func newHistoryStore() *history.Store {
+	return history.NewStore(history.DefaultStorePath(), viper.GetInt("history.max_records"), viper.GetDuration("history.max_age"))
+}
+
+// historySource returns the history configured by history.source, falling
+// back to the one picked automatically when the name is not known
+func historySource() history.HistorySource {
+	source, err := history.SourceByName(viper.GetString("history.source"))
+	if err != nil {
+		if viper.GetBool("debug") {
+			fmt.Fprintf(os.Stderr, "Debug: %v\n", err)
+		}
+		return history.AutoSource()
+	}
+	return source
+}
+
+// newHistoryParser creates a history parser for the configured history
+func newHistoryParser() *history.Parser {
+	return history.NewParserFor(historySource())
+}
+
+// runHistory executes the history command logic
+func runHistory(cmd *cobra.Command, args []string) error {
+	query := history.Query{}
+	query.Dir, _ = cmd.Flags().GetString("dir")
+	query.Repo, _ = cmd.Flags().GetString("repo")
+	query.Session, _ = cmd.Flags().GetString("session")
+	query.Prefix, _ = cmd.Flags().GetString("prefix")
+	query.Limit, _ = cmd.Flags().GetInt("limit")
+	if here, _ := cmd.Flags().GetBool("here"); here {
+		query.Dir, _ = os.Getwd()
+	}
+	if since, _ := cmd.Flags().GetDuration("since"); since > 0 {
+		query.Since = time.Now().Add(-since)
+	}
+	asJSON, _ := cmd.Flags().GetBool("json")
+
+	records, err := newHistoryStore().Query(query)
+	if err != nil {
+		return fmt.Errorf("failed to read history: %w", err)
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	for _, record := range records {
+		if asJSON {
+			if err := enc.Encode(record); err != nil {
+				return err
+			}
+			continue
+		}
+
+		fmt.Printf("%s  %s", record.Start.Format("2006-01-02 15:04:05"), record.Command)
+		if record.ExitCode != 0 {
+			fmt.Printf("  (exit: %d)", record.ExitCode)
+		}
+		fmt.Printf("  (%s, in %s)\n", record.Duration().Round(time.Millisecond), record.Dir)
+	}
+	return nil
+}
+
+// runHistoryImport executes the history import command logic
+func runHistoryImport(cmd *cobra.Command, args []string) error {
+	from, _ := cmd.Flags().GetString("from")
+	limit, _ := cmd.Flags().GetInt("limit")
+	if from == "auto" {
+		return fmt.Errorf("--from needs a history name: %s", strings.Join(history.SourceNames, ", "))
+	}
+	if limit <= 0 {
+		limit = -1
+	}
+
+	source, err := history.SourceByName(from)
+	if err != nil {
+		return err
+	}
+
+	var records []history.Record
+	if recordSource, ok := source.(history.RecordSource); ok {
+		records, err = recordSource.Records(limit)
+	} else {
+		var entries []ai.HistoryEntry
+		entries, err = source.Read(limit)
+		records = entryRecords(entries)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read %s history from %s: %w", source.Name(), source.Path(), err)
+	}
+
+	store := newHistoryStore()
+	added, err := store.Import(records)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Imported %d of %d commands from %s into %s\n", added, len(records), source.Path(), store.Path())
+	return nil
+}
+
+// entryRecords converts the entries of a shell history into records
+func entryRecords(entries []ai.HistoryEntry) []history.Record {
+	records := make([]history.Record, 0, len(entries))
+	for _, entry := range entries {
+		record := history.Record{
+			Command:  entry.Command,
+			Dir:      entry.Directory,
+			Start:    entry.Timestamp,
+			End:      entry.Timestamp,
+			ExitCode: entry.ExitCode,
+		}
+		if duration, err := time.ParseDuration(entry.Duration); err == nil {
+			record.End = record.Start.Add(duration)
+		}
+		records = append(records, record)
+	}
+	return records
+}
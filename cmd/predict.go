@@ -2,13 +2,13 @@ package cmd
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"os"
 	"time"
 
 	"supertab/internal/ai"
-	contextpkg "supertab/internal/context"
-	"supertab/internal/history"
+	"supertab/internal/daemon"
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
@@ -30,6 +30,8 @@ func init() {
 	// Command-specific flags
 	predictCmd.Flags().Int("history-limit", 5, "number of recent history entries to analyze")
 	predictCmd.Flags().Duration("timeout", 10*time.Second, "request timeout")
+	addNoDaemonFlag(predictCmd)
+	addErrorFormatFlag(predictCmd)
 }
 
 // runPredict executes the predict command logic
@@ -41,55 +43,58 @@ func runPredict(cmd *cobra.Command, args []string) error {
 	ctx, cancel := context.WithTimeout(context.Background(), timeout)
 	defer cancel()
 
-	// Detect AI provider if not specified
-	providerName := viper.GetString("provider")
-	apiKey := ""
+	response, err := requestPrediction(ctx, cmd, historyLimit)
+	if err != nil {
+		return err
+	}
 
-	if providerName == "" {
-		var provider ai.Provider
-		provider, apiKey = ai.DetectProvider()
-		if provider == "" {
-			return fmt.Errorf("no AI provider found. Set one of: OPENAI_API_KEY, ANTHROPIC_API_KEY, GEMINI_API_KEY, GROQ_API_KEY")
-		}
-		providerName = string(provider)
-	} else {
-		// Get API key for specified provider
-		switch providerName {
-		case "openai":
-			apiKey = os.Getenv("OPENAI_API_KEY")
-		case "anthropic":
-			apiKey = os.Getenv("ANTHROPIC_API_KEY")
-		case "gemini":
-			apiKey = os.Getenv("GEMINI_API_KEY")
-		case "groq":
-			apiKey = os.Getenv("GROQ_API_KEY")
-		default:
-			return fmt.Errorf("unsupported provider: %s", providerName)
-		}
+	// For predict, we want the raw AI output without parsing
+	// The AI should return properly formatted response with + or = prefix
+	rawContent := response.Content
+
+	// Simple validation: ensure response starts with + or =
+	if len(rawContent) == 0 {
+		return fmt.Errorf("%w: empty response from AI", ai.ErrInvalidResponse)
 	}
 
-	if apiKey == "" {
-		return fmt.Errorf("API key not found for provider %s", providerName)
+	firstChar := rawContent[0]
+	if firstChar != '+' && firstChar != '=' {
+		return fmt.Errorf("%w: must start with + or =", ai.ErrInvalidResponse)
 	}
 
-	// Create AI client
-	config := ai.Config{
-		Provider: ai.Provider(providerName),
-		APIKey:   apiKey,
-		Debug:    viper.GetBool("debug"),
+	// Output the AI response directly
+	fmt.Print(rawContent)
+
+	return nil
+}
+
+// requestPrediction asks a running daemon for the prediction and falls back
+// to calling the provider directly when no daemon answers
+func requestPrediction(ctx context.Context, cmd *cobra.Command, historyLimit int) (*ai.Response, error) {
+	if remote := daemonClient(cmd); remote != nil {
+		dir, _ := os.Getwd()
+		response, err := remote.Predict(ctx, dir, historyLimit)
+		if !errors.Is(err, daemon.ErrUnavailable) {
+			if err != nil {
+				return nil, fmt.Errorf("failed to get prediction: %w", err)
+			}
+			return response, nil
+		}
+		if viper.GetBool("debug") {
+			fmt.Fprintf(os.Stderr, "Debug: %v, predicting locally\n", err)
+		}
 	}
 
-	client, err := ai.NewClient(config)
+	client, err := newAIClient("predict")
 	if err != nil {
-		return fmt.Errorf("failed to create AI client: %w", err)
+		return nil, err
 	}
 
 	// Collect context
-	contextCollector := contextpkg.NewCollector()
-	contextInfo := contextCollector.Collect()
+	contextInfo := collectContext(ctx, "")
 
 	// Get recent history
-	historyParser := history.NewParser()
+	historyParser := newHistoryParser()
 	recentHistory, err := historyParser.GetRecentHistory(historyLimit)
 	if err != nil {
 		if viper.GetBool("debug") {
@@ -108,25 +113,8 @@ func runPredict(cmd *cobra.Command, args []string) error {
 	// Call AI service
 	response, err := client.Predict(ctx, req)
 	if err != nil {
-		return fmt.Errorf("failed to get prediction: %w", err)
-	}
-
-	// For predict, we want the raw AI output without parsing
-	// The AI should return properly formatted response with + or = prefix
-	rawContent := response.Content
-
-	// Simple validation: ensure response starts with + or =
-	if len(rawContent) == 0 {
-		return fmt.Errorf("empty response from AI")
-	}
-
-	firstChar := rawContent[0]
-	if firstChar != '+' && firstChar != '=' {
-		return fmt.Errorf("invalid response format: must start with + or =")
+		return nil, fmt.Errorf("failed to get prediction: %w", err)
 	}
 
-	// Output the AI response directly
-	fmt.Print(rawContent)
-
-	return nil
+	return response, nil
 }
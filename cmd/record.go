@@ -0,0 +1,160 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	contextpkg "supertab/internal/context"
+	"supertab/internal/history"
+
+	"github.com/spf13/cobra"
+)
+
+// recordCmd represents the record command
+var recordCmd = &cobra.Command{
+	Use:   "record [flags] -- <command>",
+	Short: "Record the result of a command run in the shell",
+	Long: `Record a command with its directory, git repository and branch, host, shell
+session, start and end time, exit code, the suggestion shown for it and, when the
+shell captured it, the tail of its output.
+
+The zsh plugin calls this from its preexec and precmd hooks so predictions see
+what previous commands actually did. The shell writes captured output to the
+file passed with --stdout-file or --stderr-file plus a .part suffix and renames
+it when the output ends; record waits for that before reading. Capture files are
+removed once read.`,
+	Args:         cobra.ExactArgs(1),
+	RunE:         runRecord,
+	SilenceUsage: true,
+}
+
+func init() {
+	rootCmd.AddCommand(recordCmd)
+
+	recordCmd.Flags().String("dir", "", "directory the command ran in (default is the working directory)")
+	recordCmd.Flags().String("start", "", "start time in seconds since the epoch, e.g. $EPOCHREALTIME")
+	recordCmd.Flags().String("end", "", "end time in seconds since the epoch (default is now)")
+	recordCmd.Flags().Int("exit-code", 0, "exit code of the command")
+	recordCmd.Flags().String("session", "", "identifier of the shell session the command ran in")
+	recordCmd.Flags().String("suggestion", "", "completion or prediction shown for the command line, if any")
+	recordCmd.Flags().String("stdout-file", "", "file holding the captured standard output")
+	recordCmd.Flags().String("stderr-file", "", "file holding the captured standard error")
+}
+
+// runRecord executes the record command logic
+func runRecord(cmd *cobra.Command, args []string) error {
+	dir, _ := cmd.Flags().GetString("dir")
+	startFlag, _ := cmd.Flags().GetString("start")
+	endFlag, _ := cmd.Flags().GetString("end")
+	exitCode, _ := cmd.Flags().GetInt("exit-code")
+	session, _ := cmd.Flags().GetString("session")
+	suggestion, _ := cmd.Flags().GetString("suggestion")
+	stdoutFile, _ := cmd.Flags().GetString("stdout-file")
+	stderrFile, _ := cmd.Flags().GetString("stderr-file")
+
+	if strings.TrimSpace(args[0]) == "" {
+		return nil
+	}
+	if dir == "" {
+		dir, _ = os.Getwd()
+	}
+
+	end := time.Now()
+	if endFlag != "" {
+		parsed, err := parseEpoch(endFlag)
+		if err != nil {
+			return fmt.Errorf("invalid --end: %w", err)
+		}
+		end = parsed
+	}
+	start := end
+	if startFlag != "" {
+		parsed, err := parseEpoch(startFlag)
+		if err != nil {
+			return fmt.Errorf("invalid --start: %w", err)
+		}
+		start = parsed
+	}
+
+	repo, branch := contextpkg.GitRepo(dir)
+	hostname, _ := os.Hostname()
+
+	record := history.Record{
+		Command:    args[0],
+		Dir:        dir,
+		Repo:       repo,
+		Branch:     branch,
+		Hostname:   hostname,
+		Session:    session,
+		Start:      start,
+		End:        end,
+		ExitCode:   exitCode,
+		Suggestion: suggestion,
+	}
+	if stdoutFile != "" {
+		record.Output = readCapture(stdoutFile)
+	}
+	if stderrFile != "" {
+		record.ErrorOutput = readCapture(stderrFile)
+	}
+
+	return newHistoryStore().Append(record)
+}
+
+// captureWait is how long readCapture waits for the shell to finish writing
+var captureWait = 2 * time.Second
+
+// readCapture returns the tail of the output captured in path and removes the
+// capture. The shell writes to path + ".part" and renames it when the output
+// ends, which can be after the command returned. A background process that
+// inherited the output keeps it open, so after captureWait the partial file
+// is read instead.
+func readCapture(path string) string {
+	partial := path + ".part"
+	defer os.Remove(partial)
+	defer os.Remove(path)
+
+	deadline := time.Now().Add(captureWait)
+	for {
+		if _, err := os.Stat(path); err == nil {
+			break
+		}
+		if _, err := os.Stat(partial); err != nil {
+			// Renamed since, or nothing was captured
+			break
+		}
+		if time.Now().After(deadline) {
+			path = partial
+			break
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	output, _ := history.OutputTail(path)
+	return output
+}
+
+// parseEpoch parses seconds since the epoch with an optional fraction, as in
+// zsh's $EPOCHREALTIME
+func parseEpoch(value string) (time.Time, error) {
+	whole, fraction, _ := strings.Cut(value, ".")
+	seconds, err := strconv.ParseInt(whole, 10, 64)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	var nanos int64
+	if fraction != "" {
+		if len(fraction) > 9 {
+			fraction = fraction[:9]
+		}
+		nanos, err = strconv.ParseInt(fraction+strings.Repeat("0", 9-len(fraction)), 10, 64)
+		if err != nil {
+			return time.Time{}, err
+		}
+	}
+	return time.Unix(seconds, nanos), nil
+}
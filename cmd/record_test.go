@@ -0,0 +1,51 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestReadCapture(t *testing.T) {
+	defer func(wait time.Duration) { captureWait = wait }(captureWait)
+	captureWait = 200 * time.Millisecond
+
+	tests := []struct {
+		name     string
+		complete string        // content of the renamed file
+		partial  string        // content of the .part file
+		rename   time.Duration // when the .part file is renamed, if ever
+		want     string
+	}{
+		{name: "finished", complete: "done\n", want: "done"},
+		{name: "finishes after the command", partial: "late\n", rename: 50 * time.Millisecond, want: "late"},
+		{name: "never finishes", partial: "still open\n", want: "still open"},
+		{name: "nothing captured", want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "capture.out")
+			if tt.complete != "" {
+				os.WriteFile(path, []byte(tt.complete), 0o600)
+			}
+			if tt.partial != "" {
+				os.WriteFile(path+".part", []byte(tt.partial), 0o600)
+			}
+			if tt.rename > 0 {
+				timer := time.AfterFunc(tt.rename, func() { os.Rename(path+".part", path) })
+				defer timer.Stop()
+			}
+
+			if got := readCapture(path); got != tt.want {
+				t.Errorf("readCapture() = %q, want %q", got, tt.want)
+			}
+			for _, name := range []string{path, path + ".part"} {
+				if _, err := os.Stat(name); err == nil {
+					t.Errorf("%s was not removed", filepath.Base(name))
+				}
+			}
+		})
+	}
+}
@@ -19,10 +19,12 @@ It supports command completion and prediction based on context and history.`,
 }
 
 // Execute adds all child commands to the root command and sets flags appropriately.
+// Failures exit with a code that tells the shell plugin what went wrong.
 func Execute() {
-	err := rootCmd.Execute()
+	cmd, err := rootCmd.ExecuteC()
 	if err != nil {
-		os.Exit(1)
+		reportError(cmd, err)
+		os.Exit(exitCode(err))
 	}
 }
 
@@ -31,7 +33,7 @@ func init() {
 
 	// Global flags
 	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is $HOME/.sug.yaml)")
-	rootCmd.PersistentFlags().String("provider", "", "AI provider (openai, anthropic, gemini, groq)")
+	rootCmd.PersistentFlags().String("provider", "", "AI provider (openai, anthropic, gemini, groq, ollama, openai-compatible); a comma-separated list fails over in order")
 	rootCmd.PersistentFlags().Bool("debug", false, "enable debug mode")
 
 	// Bind flags to viper
@@ -129,6 +129,8 @@ echo "1. Set up your AI provider API key"
 echo "2. Add the plugin to your zsh configuration"
 echo "3. Restart your shell or run: source ~/.zshrc"
 echo "4. Use Ctrl+Z for completions and Down Arrow for predictions"
+echo "5. Optionally, record exit codes and durations for predictions by adding"
+echo "   ZSH_COPILOT_RECORD=true to ~/.zshrc before the plugin is loaded"
 echo ""
 echo -e "${BLUE}For help:${NC}"
 echo "   sug --help"
@@ -0,0 +1,102 @@
+package ai
+
+import (
+	"sort"
+	"strings"
+)
+
+// RankedAlias is an alias with its relevance score
+type RankedAlias struct {
+	Name    string
+	Command string
+	Tool    string // the program the alias runs
+	Score   int
+}
+
+// RankAliases returns the k aliases most relevant to the input and recent
+// history. An alias scores for its name matching the word being typed, for
+// its expansion running the typed tool or spelling out the input, and for how
+// often it or its tool appears in history. The result is deterministic and grouped by the tool
+// each alias runs, strongest group first.
+func RankAliases(aliases map[string]string, input string, history []HistoryEntry, k int) []RankedAlias {
+	if len(aliases) == 0 || k <= 0 {
+		return nil
+	}
+
+	typed := ""
+	if fields := strings.Fields(input); len(fields) > 0 {
+		typed = fields[0]
+	}
+
+	// Count how often each command word was run
+	uses := make(map[string]int)
+	for _, entry := range history {
+		if fields := strings.Fields(entry.Command); len(fields) > 0 {
+			uses[fields[0]]++
+		}
+	}
+
+	ranked := make([]RankedAlias, 0, len(aliases))
+	for name, command := range aliases {
+		alias := RankedAlias{Name: name, Command: command, Tool: commandTool(command)}
+
+		if typed != "" {
+			if name == typed {
+				alias.Score += 100
+			} else if strings.HasPrefix(name, typed) {
+				alias.Score += 50
+			}
+			for _, word := range strings.Fields(command) {
+				if word == typed {
+					alias.Score += 40
+					break
+				}
+			}
+			// The expansion spells out what is being typed, e.g. gp='git push' for "git pu"
+			if trimmed := strings.TrimSpace(input); trimmed != typed && strings.HasPrefix(command, trimmed) {
+				alias.Score += 60
+			}
+		}
+
+		alias.Score += 10 * min(uses[name], 10)
+		alias.Score += 2 * min(uses[alias.Tool], 10)
+
+		ranked = append(ranked, alias)
+	}
+
+	sort.Slice(ranked, func(i, j int) bool {
+		if ranked[i].Score != ranked[j].Score {
+			return ranked[i].Score > ranked[j].Score
+		}
+		return ranked[i].Name < ranked[j].Name
+	})
+	if len(ranked) > k {
+		ranked = ranked[:k]
+	}
+
+	// Group by tool, ordering groups by their best alias, which comes first
+	// in ranked order
+	groupRank := make(map[string]int)
+	for i, alias := range ranked {
+		if _, ok := groupRank[alias.Tool]; !ok {
+			groupRank[alias.Tool] = i
+		}
+	}
+	sort.SliceStable(ranked, func(i, j int) bool {
+		return groupRank[ranked[i].Tool] < groupRank[ranked[j].Tool]
+	})
+
+	return ranked
+}
+
+// commandTool returns the program an alias expansion runs, skipping
+// environment assignments and sudo
+func commandTool(command string) string {
+	for _, word := range strings.Fields(command) {
+		if word == "sudo" || strings.Contains(word, "=") {
+			continue
+		}
+		return word
+	}
+	return ""
+}
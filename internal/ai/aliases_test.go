@@ -0,0 +1,113 @@
+package ai
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCommandTool(t *testing.T) {
+	tests := []struct {
+		command string
+		want    string
+	}{
+		{"git status", "git"},
+		{"sudo systemctl restart", "systemctl"},
+		{"LANG=C sort -u", "sort"},
+		{"sudo -E FOO=1 make", "-E"},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		if got := commandTool(tt.command); got != tt.want {
+			t.Errorf("commandTool(%q) = %q, want %q", tt.command, got, tt.want)
+		}
+	}
+}
+
+func TestRankAliases(t *testing.T) {
+	aliases := map[string]string{
+		"gs":  "git status",
+		"gp":  "git push",
+		"gl":  "git log --oneline",
+		"k":   "kubectl",
+		"kgp": "kubectl get pods",
+		"ll":  "ls -la",
+		"dc":  "docker compose",
+	}
+	history := []HistoryEntry{
+		{Command: "kgp"}, {Command: "kgp"}, {Command: "kubectl logs web"}, {Command: "kubectl get svc"},
+		{Command: "ls"}, {Command: "git fetch"},
+	}
+
+	names := func(ranked []RankedAlias) []string {
+		var names []string
+		for _, alias := range ranked {
+			names = append(names, alias.Name)
+		}
+		return names
+	}
+
+	tests := []struct {
+		name    string
+		input   string
+		history []HistoryEntry
+		k       int
+		want    []string
+	}{
+		{"exact name first", "gs", nil, 1, []string{"gs"}},
+		{"expansion spells out the input", "git pu", nil, 1, []string{"gp"}},
+		{"typed tool groups its aliases", "git", nil, 3, []string{"gl", "gp", "gs"}},
+		{"prefix of a name", "kg", nil, 1, []string{"kgp"}},
+		{"history decides without input", "", history, 2, []string{"kgp", "k"}},
+		{"ties break by name", "", nil, 3, []string{"dc", "gl", "gp"}},
+		{"k larger than the aliases", "", nil, 20, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ranked := RankAliases(aliases, tt.input, tt.history, tt.k)
+			if tt.want == nil {
+				if len(ranked) != len(aliases) {
+					t.Errorf("got %d aliases, want %d", len(ranked), len(aliases))
+				}
+				return
+			}
+			if got := names(ranked); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("RankAliases(%q) = %v, want %v", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRankAliasesGroupsByTool(t *testing.T) {
+	aliases := map[string]string{
+		"gs": "git status",
+		"gp": "git push",
+		"k":  "kubectl",
+		"kd": "kubectl describe",
+		"ll": "ls -la",
+	}
+	ranked := RankAliases(aliases, "g", []HistoryEntry{{Command: "k"}, {Command: "ll"}}, 5)
+
+	seen := make(map[string]bool)
+	previous := ""
+	for _, alias := range ranked {
+		if alias.Tool != previous && seen[alias.Tool] {
+			t.Fatalf("aliases of %s are not grouped: %+v", alias.Tool, ranked)
+		}
+		seen[alias.Tool] = true
+		previous = alias.Tool
+	}
+	if ranked[0].Tool != "git" {
+		t.Errorf("strongest group = %s, want git", ranked[0].Tool)
+	}
+}
+
+func TestRankAliasesEmpty(t *testing.T) {
+	if got := RankAliases(nil, "git", nil, 5); got != nil {
+		t.Errorf("RankAliases(nil) = %v", got)
+	}
+	if got := RankAliases(map[string]string{"gs": "git status"}, "git", nil, 0); got != nil {
+		t.Errorf("RankAliases(k=0) = %v", got)
+	}
+}
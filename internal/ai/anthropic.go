@@ -1,20 +1,27 @@
 package ai
 
 import (
-	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
-	"os"
 	"strings"
 )
 
+const (
+	// defaultAnthropicModel is used when no model is configured
+	defaultAnthropicModel = "claude-3-5-sonnet-latest"
+
+	// defaultAnthropicMaxTokens is sent when max_tokens is not configured,
+	// since the Messages API requires it
+	defaultAnthropicMaxTokens = 1000
+)
+
 // AnthropicClient implements the Client interface for Anthropic
 type AnthropicClient struct {
 	config Config
-	client *http.Client
+	client *httpClient
 }
 
 // NewAnthropicClient creates a new Anthropic client
@@ -22,17 +29,27 @@ func NewAnthropicClient(config Config) *AnthropicClient {
 	if config.BaseURL == "" {
 		config.BaseURL = "https://api.anthropic.com"
 	}
+	if config.Model == "" {
+		config.Model = defaultAnthropicModel
+	}
+	if config.MaxTokens == 0 {
+		config.MaxTokens = defaultAnthropicMaxTokens
+	}
 	return &AnthropicClient{
 		config: config,
-		client: &http.Client{},
+		client: newHTTPClient(config),
 	}
 }
 
 type anthropicRequest struct {
-	Model     string             `json:"model"`
-	MaxTokens int                `json:"max_tokens"`
-	System    string             `json:"system"`
-	Messages  []anthropicMessage `json:"messages"`
+	Model         string             `json:"model"`
+	MaxTokens     int                `json:"max_tokens"`
+	System        string             `json:"system"`
+	Messages      []anthropicMessage `json:"messages"`
+	Temperature   *float64           `json:"temperature,omitempty"`
+	TopP          *float64           `json:"top_p,omitempty"`
+	StopSequences []string           `json:"stop_sequences,omitempty"`
+	Stream        bool               `json:"stream,omitempty"`
 }
 
 type anthropicMessage struct {
@@ -56,10 +73,23 @@ type anthropicError struct {
 	Message string `json:"message"`
 }
 
+type anthropicStreamEvent struct {
+	Type  string `json:"type"`
+	Delta struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	} `json:"delta"`
+	Error *anthropicError `json:"error,omitempty"`
+}
+
 // Complete generates command completions using Anthropic
 func (c *AnthropicClient) Complete(ctx context.Context, req CompletionRequest) (*Response, error) {
 	prompt := buildCompletionPrompt(req)
-	return c.makeRequest(ctx, prompt)
+	content, err := c.makeRequest(ctx, prompt)
+	if err != nil {
+		return nil, err
+	}
+	return parseResponse(content)
 }
 
 // Predict generates command predictions using Anthropic
@@ -67,7 +97,7 @@ func (c *AnthropicClient) Predict(ctx context.Context, req PredictionRequest) (*
 	prompt := buildPredictionPrompt(req)
 
 	// For prediction, we want the raw AI response without parsing
-	rawContent, err := c.makeRawRequest(ctx, prompt)
+	rawContent, err := c.makeRequest(ctx, prompt)
 	if err != nil {
 		return nil, err
 	}
@@ -79,110 +109,79 @@ func (c *AnthropicClient) Predict(ctx context.Context, req PredictionRequest) (*
 	}, nil
 }
 
-// makeRawRequest sends a request to Anthropic API and returns raw response
-func (c *AnthropicClient) makeRawRequest(ctx context.Context, userPrompt string) (string, error) {
-	reqBody := anthropicRequest{
-		Model:     "claude-3-5-sonnet-latest",
-		MaxTokens: 1000,
-		System:    getSystemPrompt(),
-		Messages: []anthropicMessage{
-			{Role: "user", Content: userPrompt},
-		},
-	}
+// Stream generates command completions using Anthropic, reporting text as it arrives
+func (c *AnthropicClient) Stream(ctx context.Context, req CompletionRequest, onDelta DeltaFunc) (*Response, error) {
+	reqBody := c.newRequest(buildCompletionPrompt(req))
+	reqBody.Stream = true
 
-	jsonData, err := json.Marshal(reqBody)
+	resp, err := c.client.post(ctx, c.config.BaseURL+"/v1/messages", reqBody, acceptEventStream(c.setHeaders))
 	if err != nil {
-		return "", fmt.Errorf("failed to marshal request: %w", err)
-	}
-
-	if c.config.Debug {
-		fmt.Fprintf(os.Stderr, "Debug: Request payload: %s\n", string(jsonData))
-	}
-
-	req, err := http.NewRequestWithContext(ctx, "POST", c.config.BaseURL+"/v1/messages", bytes.NewBuffer(jsonData))
-	if err != nil {
-		return "", fmt.Errorf("failed to create request: %w", err)
-	}
-
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("x-api-key", c.config.APIKey)
-	req.Header.Set("anthropic-version", "2023-06-01")
-
-	resp, err := c.client.Do(req)
-	if err != nil {
-		return "", fmt.Errorf("failed to send request: %w", err)
+		return nil, err
 	}
 	defer resp.Body.Close()
 
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return "", fmt.Errorf("failed to read response: %w", err)
-	}
-
-	var apiResp anthropicResponse
-	if err := json.Unmarshal(body, &apiResp); err != nil {
-		return "", fmt.Errorf("failed to unmarshal response: %w", err)
-	}
-
-	if apiResp.Type == "error" || apiResp.Error != nil {
-		msg := "unknown error"
-		if apiResp.Error != nil {
-			msg = apiResp.Error.Message
+	var content strings.Builder
+	err = readSSE(resp.Body, func(event, data string) error {
+		var ev anthropicStreamEvent
+		if err := json.Unmarshal([]byte(data), &ev); err != nil {
+			return fmt.Errorf("%w: failed to unmarshal stream event: %w", ErrInvalidResponse, err)
 		}
-		return "", fmt.Errorf("API error: %s", msg)
-	}
 
-	if len(apiResp.Content) == 0 {
-		return "", fmt.Errorf("no content in response")
+		switch ev.Type {
+		case "content_block_delta":
+			if ev.Delta.Type == "text_delta" && ev.Delta.Text != "" {
+				content.WriteString(ev.Delta.Text)
+				onDelta(ev.Delta.Text)
+			}
+		case "message_stop":
+			return io.EOF
+		case "error":
+			msg := "unknown error"
+			if ev.Error != nil {
+				msg = ev.Error.Message
+			}
+			return fmt.Errorf("API error: %s", msg)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	// Return raw content without parsing
-	return strings.TrimSpace(apiResp.Content[0].Text), nil
+	return parseResponse(content.String())
 }
 
-// makeRequest sends a request to Anthropic API and processes the response
-func (c *AnthropicClient) makeRequest(ctx context.Context, userPrompt string) (*Response, error) {
-	reqBody := anthropicRequest{
-		Model:     "claude-3-5-sonnet-latest",
-		MaxTokens: 1000,
+// newRequest builds a Messages API request body from the client configuration
+func (c *AnthropicClient) newRequest(userPrompt string) anthropicRequest {
+	return anthropicRequest{
+		Model:     c.config.Model,
+		MaxTokens: c.config.MaxTokens,
 		System:    getSystemPrompt(),
 		Messages: []anthropicMessage{
 			{Role: "user", Content: userPrompt},
 		},
+		Temperature:   c.config.Temperature,
+		TopP:          c.config.TopP,
+		StopSequences: c.config.Stop,
 	}
+}
 
-	jsonData, err := json.Marshal(reqBody)
-	if err != nil {
-		return nil, fmt.Errorf("failed to marshal request: %w", err)
-	}
-
-	if c.config.Debug {
-		fmt.Fprintf(os.Stderr, "Debug: Request payload: %s\n", string(jsonData))
-	}
-
-	req, err := http.NewRequestWithContext(ctx, "POST", c.config.BaseURL+"/v1/messages", bytes.NewBuffer(jsonData))
-	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
-	}
-
-	req.Header.Set("Content-Type", "application/json")
+// setHeaders applies the API key and API version headers
+func (c *AnthropicClient) setHeaders(req *http.Request) {
 	req.Header.Set("x-api-key", c.config.APIKey)
 	req.Header.Set("anthropic-version", "2023-06-01")
+}
 
-	resp, err := c.client.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("failed to send request: %w", err)
-	}
-	defer resp.Body.Close()
-
-	body, err := io.ReadAll(resp.Body)
+// makeRequest sends a request to Anthropic API and returns the raw response content
+func (c *AnthropicClient) makeRequest(ctx context.Context, userPrompt string) (string, error) {
+	body, err := c.client.postJSON(ctx, c.config.BaseURL+"/v1/messages", c.newRequest(userPrompt), c.setHeaders)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read response: %w", err)
+		return "", err
 	}
 
 	var apiResp anthropicResponse
 	if err := json.Unmarshal(body, &apiResp); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+		return "", fmt.Errorf("%w: failed to unmarshal response: %w", ErrInvalidResponse, err)
 	}
 
 	if apiResp.Type == "error" || apiResp.Error != nil {
@@ -190,12 +189,12 @@ func (c *AnthropicClient) makeRequest(ctx context.Context, userPrompt string) (*
 		if apiResp.Error != nil {
 			msg = apiResp.Error.Message
 		}
-		return nil, fmt.Errorf("API error: %s", msg)
+		return "", fmt.Errorf("API error: %s", msg)
 	}
 
 	if len(apiResp.Content) == 0 {
-		return nil, fmt.Errorf("no content in response")
+		return "", fmt.Errorf("%w: no content in response", ErrInvalidResponse)
 	}
 
-	return parseResponse(apiResp.Content[0].Text)
+	return strings.TrimSpace(apiResp.Content[0].Text), nil
 }
@@ -4,12 +4,17 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"strings"
 )
 
 // Client interface for AI providers
 type Client interface {
 	Complete(ctx context.Context, req CompletionRequest) (*Response, error)
 	Predict(ctx context.Context, req PredictionRequest) (*Response, error)
+
+	// Stream behaves like Complete but passes generated text to onDelta as it
+	// arrives. The returned Response is parsed from the full text.
+	Stream(ctx context.Context, req CompletionRequest, onDelta DeltaFunc) (*Response, error)
 }
 
 // Config holds configuration for AI clients
@@ -17,13 +22,25 @@ type Config struct {
 	Provider Provider
 	APIKey   string
 	BaseURL  string
+	Model    string
+	Headers  map[string]string
 	Debug    bool
+
+	// MaxRetries is the number of retries for temporary failures;
+	// zero uses the default and a negative value disables retries
+	MaxRetries int
+
+	// Generation parameters; nil or zero values leave the provider default
+	Temperature *float64
+	TopP        *float64
+	MaxTokens   int
+	Stop        []string
 }
 
 // NewClient creates a new AI client based on provider configuration
 func NewClient(config Config) (Client, error) {
-	if config.APIKey == "" {
-		return nil, fmt.Errorf("API key is required for provider %s", config.Provider)
+	if config.APIKey == "" && config.Provider.RequiresAPIKey() {
+		return nil, fmt.Errorf("%w: provider %s requires one", ErrMissingKey, config.Provider)
 	}
 
 	switch config.Provider {
@@ -35,6 +52,15 @@ func NewClient(config Config) (Client, error) {
 		return NewGeminiClient(config), nil
 	case ProviderGroq:
 		return NewGroqClient(config), nil
+	case ProviderOllama:
+		return NewOllamaClient(config), nil
+	case ProviderOpenAICompatible:
+		if config.BaseURL == "" || config.Model == "" {
+			return nil, fmt.Errorf("provider %s requires base_url and model to be configured", config.Provider)
+		}
+		// Accept base URLs in the OpenAI SDK style, which include the version
+		config.BaseURL = strings.TrimSuffix(strings.TrimSuffix(config.BaseURL, "/"), "/v1")
+		return NewOpenAIClient(config), nil
 	default:
 		return nil, fmt.Errorf("unsupported provider: %s", config.Provider)
 	}
@@ -42,17 +68,41 @@ func NewClient(config Config) (Client, error) {
 
 // DetectProvider automatically detects the available AI provider based on environment variables
 func DetectProvider() (Provider, string) {
-	if key := os.Getenv("OPENAI_API_KEY"); key != "" {
-		return ProviderOpenAI, key
-	}
-	if key := os.Getenv("ANTHROPIC_API_KEY"); key != "" {
-		return ProviderAnthropic, key
+	providers := DetectProviders()
+	if len(providers) == 0 {
+		return "", ""
 	}
-	if key := os.Getenv("GEMINI_API_KEY"); key != "" {
-		return ProviderGemini, key
+	return providers[0], os.Getenv(APIKeyEnv[providers[0]])
+}
+
+// DetectProviders returns every provider configured through environment variables,
+// in order of preference
+func DetectProviders() []Provider {
+	var providers []Provider
+	for _, provider := range []Provider{ProviderOpenAI, ProviderAnthropic, ProviderGemini, ProviderGroq} {
+		if os.Getenv(APIKeyEnv[provider]) != "" {
+			providers = append(providers, provider)
+		}
 	}
-	if key := os.Getenv("GROQ_API_KEY"); key != "" {
-		return ProviderGroq, key
+	// A configured local server is the last resort, e.g. on air-gapped hosts
+	if os.Getenv("OLLAMA_HOST") != "" {
+		providers = append(providers, ProviderOllama)
 	}
-	return "", ""
+	return providers
+}
+
+// APIKeyEnv maps each provider to the environment variable holding its API key.
+// Only the hosted providers are detected through it.
+var APIKeyEnv = map[Provider]string{
+	ProviderOpenAI:           "OPENAI_API_KEY",
+	ProviderAnthropic:        "ANTHROPIC_API_KEY",
+	ProviderGemini:           "GEMINI_API_KEY",
+	ProviderGroq:             "GROQ_API_KEY",
+	ProviderOllama:           "OLLAMA_API_KEY",
+	ProviderOpenAICompatible: "OPENAI_COMPATIBLE_API_KEY",
+}
+
+// RequiresAPIKey reports whether the provider refuses requests without an API key
+func (p Provider) RequiresAPIKey() bool {
+	return p != ProviderOllama && p != ProviderOpenAICompatible
 }
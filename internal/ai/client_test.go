@@ -0,0 +1,93 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestGenerationSettingsInRequests(t *testing.T) {
+	temperature, topP := 0.2, 0.9
+
+	tests := []struct {
+		provider Provider
+		section  string // object of the body holding the settings; empty means the body itself
+		want     map[string]any
+		unset    map[string]any // the settings sent when none are configured
+	}{
+		{
+			provider: ProviderOpenAI,
+			want:     map[string]any{"temperature": 0.2, "top_p": 0.9, "max_tokens": 64.0, "stop": []any{"\n"}},
+			unset:    map[string]any{},
+		},
+		{
+			provider: ProviderGroq,
+			want:     map[string]any{"temperature": 0.2, "top_p": 0.9, "max_tokens": 64.0, "stop": []any{"\n"}},
+			unset:    map[string]any{},
+		},
+		{
+			provider: ProviderOllama,
+			want:     map[string]any{"temperature": 0.2, "top_p": 0.9, "max_tokens": 64.0, "stop": []any{"\n"}},
+			unset:    map[string]any{},
+		},
+		{
+			provider: ProviderOpenAICompatible,
+			want:     map[string]any{"temperature": 0.2, "top_p": 0.9, "max_tokens": 64.0, "stop": []any{"\n"}},
+			unset:    map[string]any{},
+		},
+		{
+			provider: ProviderAnthropic,
+			want:     map[string]any{"temperature": 0.2, "top_p": 0.9, "max_tokens": 64.0, "stop_sequences": []any{"\n"}},
+			unset:    map[string]any{"max_tokens": float64(defaultAnthropicMaxTokens)},
+		},
+		{
+			provider: ProviderGemini,
+			section:  "generationConfig",
+			want:     map[string]any{"temperature": 0.2, "topP": 0.9, "maxOutputTokens": 64.0, "stopSequences": []any{"\n"}},
+			unset:    map[string]any{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.provider), func(t *testing.T) {
+			for _, configured := range []bool{true, false} {
+				var body map[string]any
+				server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					json.NewDecoder(r.Body).Decode(&body)
+					// The reply does not matter; only the request is checked
+					w.WriteHeader(http.StatusBadRequest)
+				}))
+
+				config := Config{Provider: tt.provider, APIKey: "key", BaseURL: server.URL, Model: "m", MaxRetries: -1}
+				want := tt.unset
+				if configured {
+					config.Temperature, config.TopP, config.MaxTokens, config.Stop = &temperature, &topP, 64, []string{"\n"}
+					want = tt.want
+				}
+				client, err := NewClient(config)
+				if err != nil {
+					t.Fatal(err)
+				}
+				client.Complete(context.Background(), CompletionRequest{Input: "git st"})
+				server.Close()
+
+				section := body
+				if tt.section != "" {
+					section, _ = body[tt.section].(map[string]any)
+				}
+				got := make(map[string]any)
+				for _, name := range []string{"temperature", "top_p", "topP", "max_tokens", "maxOutputTokens", "stop", "stop_sequences", "stopSequences"} {
+					if value, ok := section[name]; ok {
+						got[name] = value
+					}
+				}
+				if !reflect.DeepEqual(got, want) {
+					t.Errorf("configured %v: settings sent = %v, want %v", configured, got, want)
+				}
+			}
+		})
+	}
+}
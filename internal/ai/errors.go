@@ -0,0 +1,207 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Error kinds reported by provider clients. Match them with errors.Is.
+var (
+	ErrMissingKey      = errors.New("API key not configured")
+	ErrRateLimited     = errors.New("rate limited")
+	ErrAuth            = errors.New("authentication failed")
+	ErrOverloaded      = errors.New("provider overloaded")
+	ErrBadRequest      = errors.New("bad request")
+	ErrTimeout         = errors.New("request timed out")
+	ErrInvalidResponse = errors.New("invalid response format")
+	ErrNetwork         = errors.New("network unavailable")
+)
+
+// ErrorKind returns a stable name for the kind of err, for reporting to
+// callers outside Go such as the shell plugin
+func ErrorKind(err error) string {
+	switch {
+	case err == nil:
+		return ""
+	case errors.Is(err, ErrMissingKey):
+		return "missing_key"
+	case errors.Is(err, ErrRateLimited):
+		return "rate_limited"
+	case errors.Is(err, ErrAuth):
+		return "auth"
+	case errors.Is(err, ErrOverloaded):
+		return "overloaded"
+	case errors.Is(err, ErrBadRequest):
+		return "bad_request"
+	case errors.Is(err, ErrTimeout), errors.Is(err, context.DeadlineExceeded):
+		return "timeout"
+	case errors.Is(err, ErrInvalidResponse):
+		return "invalid_response"
+	case errors.Is(err, ErrNetwork):
+		return "network"
+	}
+	return "unknown"
+}
+
+// KindError returns the error matching a name produced by ErrorKind,
+// so errors reported across a process boundary keep their kind
+func KindError(kind string) error {
+	switch kind {
+	case "missing_key":
+		return ErrMissingKey
+	case "rate_limited":
+		return ErrRateLimited
+	case "auth":
+		return ErrAuth
+	case "overloaded":
+		return ErrOverloaded
+	case "bad_request":
+		return ErrBadRequest
+	case "timeout":
+		return ErrTimeout
+	case "invalid_response":
+		return ErrInvalidResponse
+	case "network":
+		return ErrNetwork
+	}
+	return nil
+}
+
+// retryDelayer is implemented by errors that carry a requested retry delay
+type retryDelayer interface {
+	RetryDelay() time.Duration
+}
+
+// RetryAfter returns the delay a provider asked for before retrying, if any
+func RetryAfter(err error) time.Duration {
+	var rd retryDelayer
+	if errors.As(err, &rd) {
+		return rd.RetryDelay()
+	}
+	return 0
+}
+
+// transportError classifies a failure to get any response from the provider
+func transportError(ctx context.Context, err error) error {
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return fmt.Errorf("%w: %w", ErrTimeout, ctx.Err())
+	}
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return fmt.Errorf("%w: failed to send request: %w", ErrTimeout, err)
+	}
+	return fmt.Errorf("%w: failed to send request: %w", ErrNetwork, err)
+}
+
+// APIError is returned when a provider answers with a non-success HTTP status
+type APIError struct {
+	Provider   Provider
+	StatusCode int
+	Message    string
+
+	// RetryAfter is the delay requested by the provider, if it sent one
+	RetryAfter time.Duration
+}
+
+func (e *APIError) Error() string {
+	status := strings.TrimSpace(fmt.Sprintf("%d %s", e.StatusCode, http.StatusText(e.StatusCode)))
+	if e.Message == "" {
+		return fmt.Sprintf("%s API error: %s", e.Provider, status)
+	}
+	return fmt.Sprintf("%s API error: %s: %s", e.Provider, status, e.Message)
+}
+
+// Is classifies the error by status code so callers can use errors.Is(err, ErrRateLimited)
+func (e *APIError) Is(target error) bool {
+	switch target {
+	case ErrRateLimited:
+		return e.StatusCode == http.StatusTooManyRequests
+	case ErrAuth:
+		return e.StatusCode == http.StatusUnauthorized || e.StatusCode == http.StatusForbidden
+	case ErrOverloaded:
+		return e.StatusCode >= 500
+	case ErrBadRequest:
+		return e.StatusCode >= 400 && e.StatusCode < 500 &&
+			e.StatusCode != http.StatusTooManyRequests &&
+			e.StatusCode != http.StatusUnauthorized &&
+			e.StatusCode != http.StatusForbidden
+	}
+	return false
+}
+
+// RetryDelay returns the delay the provider asked for before retrying
+func (e *APIError) RetryDelay() time.Duration {
+	return e.RetryAfter
+}
+
+// Temporary reports whether repeating the request later may succeed
+func (e *APIError) Temporary() bool {
+	return errors.Is(e, ErrRateLimited) || errors.Is(e, ErrOverloaded)
+}
+
+// statusError returns an *APIError if resp has a non-success status, using
+// the error message from body when the provider sent one
+func statusError(provider Provider, resp *http.Response, body []byte) error {
+	if resp.StatusCode < 300 {
+		return nil
+	}
+	return &APIError{
+		Provider:   provider,
+		StatusCode: resp.StatusCode,
+		Message:    errorMessage(body),
+		RetryAfter: parseRetryAfter(resp.Header, time.Now()),
+	}
+}
+
+// parseRetryAfter reads the Retry-After header, given either in seconds or as an HTTP date
+func parseRetryAfter(header http.Header, now time.Time) time.Duration {
+	value := strings.TrimSpace(header.Get("Retry-After"))
+	if value == "" {
+		return 0
+	}
+	var seconds float64
+	if _, err := fmt.Sscanf(value, "%g", &seconds); err == nil && seconds >= 0 {
+		return time.Duration(seconds * float64(time.Second))
+	}
+	if date, err := http.ParseTime(value); err == nil && date.After(now) {
+		return date.Sub(now)
+	}
+	return 0
+}
+
+// errorMessage extracts a human-readable message from a provider error body.
+// All supported providers use {"error": {"message": ...}}; some proxies send
+// {"error": "..."} or plain text instead.
+func errorMessage(body []byte) string {
+	var structured struct {
+		Error struct {
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(body, &structured); err == nil && structured.Error.Message != "" {
+		return structured.Error.Message
+	}
+
+	var plain struct {
+		Error string `json:"error"`
+	}
+	if err := json.Unmarshal(body, &plain); err == nil && plain.Error != "" {
+		return plain.Error
+	}
+
+	msg := strings.TrimSpace(string(body))
+	if len(msg) > 200 {
+		msg = msg[:200] + "..."
+	}
+	return msg
+}
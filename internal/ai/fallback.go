@@ -0,0 +1,196 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// FallbackClient tries an ordered list of providers and moves on to the next one
+// when a provider times out, is unreachable, rate limits, rejects the credentials
+// or fails with a server error
+type FallbackClient struct {
+	providers []Provider
+	clients   []Client
+	debug     bool
+
+	// attemptTimeout bounds every attempt except the last; zero splits the
+	// remaining time of the request evenly between the remaining providers
+	attemptTimeout time.Duration
+
+	mu       sync.Mutex
+	answered Provider
+}
+
+// NewFallbackClient creates a client for each configuration, in order
+func NewFallbackClient(configs []Config, attemptTimeout time.Duration) (*FallbackClient, error) {
+	if len(configs) == 0 {
+		return nil, fmt.Errorf("no providers configured")
+	}
+
+	f := &FallbackClient{attemptTimeout: attemptTimeout}
+	for _, config := range configs {
+		client, err := NewClient(config)
+		if err != nil {
+			return nil, err
+		}
+		f.providers = append(f.providers, config.Provider)
+		f.clients = append(f.clients, client)
+		f.debug = f.debug || config.Debug
+	}
+	return f, nil
+}
+
+// Answered returns the provider that produced the last successful response
+func (f *FallbackClient) Answered() Provider {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.answered
+}
+
+// Complete generates command completions using the first provider that answers
+func (f *FallbackClient) Complete(ctx context.Context, req CompletionRequest) (*Response, error) {
+	return f.try(ctx, func(ctx context.Context, client Client) (*Response, error) {
+		return client.Complete(ctx, req)
+	})
+}
+
+// Predict generates command predictions using the first provider that answers
+func (f *FallbackClient) Predict(ctx context.Context, req PredictionRequest) (*Response, error) {
+	return f.try(ctx, func(ctx context.Context, client Client) (*Response, error) {
+		return client.Predict(ctx, req)
+	})
+}
+
+// Stream streams a completion from the first provider that answers. Once a provider
+// has produced text the chain is committed to it, since the text is already out.
+func (f *FallbackClient) Stream(ctx context.Context, req CompletionRequest, onDelta DeltaFunc) (*Response, error) {
+	started := false
+	forward := func(delta string) {
+		started = true
+		onDelta(delta)
+	}
+
+	return f.try(ctx, func(ctx context.Context, client Client) (*Response, error) {
+		resp, err := client.Stream(ctx, req, forward)
+		if err != nil && started {
+			return nil, &committedError{err: err}
+		}
+		return resp, err
+	})
+}
+
+// try runs call against each provider in order until one succeeds or fails
+// with an error that another provider would not fix
+func (f *FallbackClient) try(ctx context.Context, call func(context.Context, Client) (*Response, error)) (*Response, error) {
+	chain := &ChainError{}
+
+	for i, client := range f.clients {
+		provider := f.providers[i]
+
+		attemptCtx, cancel := f.attemptContext(ctx, len(f.clients)-i)
+		resp, err := call(attemptCtx, client)
+		cancel()
+
+		if err == nil {
+			f.mu.Lock()
+			f.answered = provider
+			f.mu.Unlock()
+			if f.debug {
+				fmt.Fprintf(os.Stderr, "Debug: answered by provider %s\n", provider)
+			}
+			return resp, nil
+		}
+
+		var committed *committedError
+		if errors.As(err, &committed) {
+			return nil, committed.err
+		}
+
+		chain.Providers = append(chain.Providers, provider)
+		chain.Errs = append(chain.Errs, err)
+		if ctx.Err() != nil || !shouldFailover(err) {
+			break
+		}
+
+		if f.debug && i < len(f.clients)-1 {
+			fmt.Fprintf(os.Stderr, "Debug: provider %s failed (%v), trying %s\n", provider, err, f.providers[i+1])
+		}
+	}
+
+	if len(chain.Errs) == 1 {
+		return nil, fmt.Errorf("%s: %w", chain.Providers[0], chain.Errs[0])
+	}
+	return nil, chain
+}
+
+// attemptContext derives the context for one attempt, leaving time for the
+// remaining providers in the chain
+func (f *FallbackClient) attemptContext(ctx context.Context, remaining int) (context.Context, context.CancelFunc) {
+	if remaining <= 1 {
+		return context.WithCancel(ctx)
+	}
+	if f.attemptTimeout > 0 {
+		return context.WithTimeout(ctx, f.attemptTimeout)
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		return context.WithTimeout(ctx, time.Until(deadline)/time.Duration(remaining))
+	}
+	return context.WithCancel(ctx)
+}
+
+// shouldFailover reports whether err is a failure another provider may not have
+func shouldFailover(err error) bool {
+	return errors.Is(err, ErrRateLimited) ||
+		errors.Is(err, ErrAuth) ||
+		errors.Is(err, ErrOverloaded) ||
+		errors.Is(err, ErrTimeout) ||
+		errors.Is(err, context.DeadlineExceeded) ||
+		errors.Is(err, ErrNetwork)
+}
+
+// ChainError is returned when several providers of a FallbackClient failed.
+// It unwraps to the last failure, the one that ended the chain, so ErrorKind
+// and errors.As report that failure's kind.
+type ChainError struct {
+	Providers []Provider
+	Errs      []error
+}
+
+func (e *ChainError) Error() string {
+	failures := make([]string, len(e.Errs))
+	for i, err := range e.Errs {
+		failures[i] = fmt.Sprintf("%s: %v", e.Providers[i], err)
+	}
+	return "all providers failed: " + strings.Join(failures, "; ")
+}
+
+// Unwrap returns the last failure
+func (e *ChainError) Unwrap() error {
+	return e.Errs[len(e.Errs)-1]
+}
+
+// RetryDelay returns the shortest delay any provider asked for before
+// retrying, since retrying the chain then gets an answer soonest
+func (e *ChainError) RetryDelay() time.Duration {
+	var delay time.Duration
+	for _, err := range e.Errs {
+		if d := RetryAfter(err); d > 0 && (delay == 0 || d < delay) {
+			delay = d
+		}
+	}
+	return delay
+}
+
+// committedError marks a failure after streamed text was already delivered
+type committedError struct {
+	err error
+}
+
+func (e *committedError) Error() string {
+	return e.err.Error()
+}
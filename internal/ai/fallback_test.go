@@ -0,0 +1,158 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// fakeClient answers every call with a fixed response or error
+type fakeClient struct {
+	resp  *Response
+	err   error
+	calls atomic.Int32
+}
+
+func (c *fakeClient) Complete(ctx context.Context, req CompletionRequest) (*Response, error) {
+	c.calls.Add(1)
+	return c.resp, c.err
+}
+
+func (c *fakeClient) Predict(ctx context.Context, req PredictionRequest) (*Response, error) {
+	c.calls.Add(1)
+	return c.resp, c.err
+}
+
+func (c *fakeClient) Stream(ctx context.Context, req CompletionRequest, onDelta DeltaFunc) (*Response, error) {
+	c.calls.Add(1)
+	return c.resp, c.err
+}
+
+func newTestChain(clients ...*fakeClient) *FallbackClient {
+	f := &FallbackClient{}
+	for i, client := range clients {
+		f.providers = append(f.providers, []Provider{ProviderOpenAI, ProviderAnthropic, ProviderGroq}[i])
+		f.clients = append(f.clients, client)
+	}
+	return f
+}
+
+func rateLimited(retryAfter time.Duration) error {
+	return &APIError{Provider: ProviderOpenAI, StatusCode: http.StatusTooManyRequests, RetryAfter: retryAfter}
+}
+
+func TestFallbackClientErrors(t *testing.T) {
+	ok := &Response{Type: TypeCompletion, Content: "ls -la"}
+
+	tests := []struct {
+		name           string
+		clients        []*fakeClient
+		wantKind       string
+		wantRetryAfter time.Duration
+		wantCalls      []int
+	}{
+		{
+			name:      "first answers",
+			clients:   []*fakeClient{{resp: ok}, {resp: ok}},
+			wantCalls: []int{1, 0},
+		},
+		{
+			name:      "fails over on rate limit",
+			clients:   []*fakeClient{{err: rateLimited(0)}, {resp: ok}},
+			wantCalls: []int{1, 1},
+		},
+		{
+			name:           "single provider keeps kind",
+			clients:        []*fakeClient{{err: rateLimited(20 * time.Second)}},
+			wantKind:       "rate_limited",
+			wantRetryAfter: 20 * time.Second,
+			wantCalls:      []int{1},
+		},
+		{
+			name:           "all rate limited keeps kind and shortest retry",
+			clients:        []*fakeClient{{err: rateLimited(20 * time.Second)}, {err: rateLimited(5 * time.Second)}},
+			wantKind:       "rate_limited",
+			wantRetryAfter: 5 * time.Second,
+			wantCalls:      []int{1, 1},
+		},
+		{
+			name:           "last failure decides the kind",
+			clients:        []*fakeClient{{err: rateLimited(3 * time.Second)}, {err: ErrAuth}},
+			wantKind:       "auth",
+			wantRetryAfter: 3 * time.Second,
+			wantCalls:      []int{1, 1},
+		},
+		{
+			name:      "bad request stops the chain",
+			clients:   []*fakeClient{{err: &APIError{StatusCode: http.StatusBadRequest}}, {resp: ok}},
+			wantKind:  "bad_request",
+			wantCalls: []int{1, 0},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newTestChain(tt.clients...)
+			resp, err := f.Complete(context.Background(), CompletionRequest{})
+
+			if tt.wantKind == "" {
+				if err != nil {
+					t.Fatalf("Complete() error = %v", err)
+				}
+				if resp != ok {
+					t.Errorf("Complete() = %v, want %v", resp, ok)
+				}
+			} else if kind := ErrorKind(err); kind != tt.wantKind {
+				t.Errorf("ErrorKind() = %q, want %q (err: %v)", kind, tt.wantKind, err)
+			}
+			if got := RetryAfter(err); got != tt.wantRetryAfter {
+				t.Errorf("RetryAfter() = %s, want %s", got, tt.wantRetryAfter)
+			}
+			for i, client := range tt.clients {
+				if calls := int(client.calls.Load()); calls != tt.wantCalls[i] {
+					t.Errorf("provider %d called %d times, want %d", i, calls, tt.wantCalls[i])
+				}
+			}
+		})
+	}
+}
+
+func TestChainErrorAs(t *testing.T) {
+	f := newTestChain(&fakeClient{err: rateLimited(time.Second)}, &fakeClient{err: rateLimited(2 * time.Second)})
+	_, err := f.Predict(context.Background(), PredictionRequest{})
+
+	var chain *ChainError
+	if !errors.As(err, &chain) || len(chain.Errs) != 2 {
+		t.Fatalf("errors.As(*ChainError) failed for %v", err)
+	}
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusTooManyRequests {
+		t.Errorf("errors.As(*APIError) failed for %v", err)
+	}
+}
+
+func TestFallbackClientConcurrentAnswered(t *testing.T) {
+	f := newTestChain(&fakeClient{err: ErrTimeout}, &fakeClient{resp: &Response{}})
+
+	// Run with -race: the daemon shares one client between connections
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := f.Complete(context.Background(), CompletionRequest{}); err != nil {
+				t.Error(err)
+			}
+			f.Answered()
+		}()
+	}
+	wg.Wait()
+
+	if got := f.Answered(); got != ProviderAnthropic {
+		t.Errorf("Answered() = %s, want %s", got, ProviderAnthropic)
+	}
+}
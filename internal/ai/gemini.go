@@ -1,18 +1,20 @@
 package ai
 
 import (
-	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
-	"io"
 	"net/http"
+	"strings"
 )
 
+// defaultGeminiModel is used when no model is configured
+const defaultGeminiModel = "gemini-1.5-flash-latest"
+
 // GeminiClient implements the Client interface for Google Gemini
 type GeminiClient struct {
 	config Config
-	client *http.Client
+	client *httpClient
 }
 
 // NewGeminiClient creates a new Gemini client
@@ -20,15 +22,19 @@ func NewGeminiClient(config Config) *GeminiClient {
 	if config.BaseURL == "" {
 		config.BaseURL = "https://generativelanguage.googleapis.com"
 	}
+	if config.Model == "" {
+		config.Model = defaultGeminiModel
+	}
 	return &GeminiClient{
 		config: config,
-		client: &http.Client{},
+		client: newHTTPClient(config),
 	}
 }
 
 type geminiRequest struct {
 	Contents          []geminiContent          `json:"contents"`
 	SystemInstruction *geminiSystemInstruction `json:"systemInstruction,omitempty"`
+	GenerationConfig  *geminiGenerationConfig  `json:"generationConfig,omitempty"`
 }
 
 type geminiContent struct {
@@ -43,6 +49,13 @@ type geminiSystemInstruction struct {
 	Parts []geminiPart `json:"parts"`
 }
 
+type geminiGenerationConfig struct {
+	Temperature     *float64 `json:"temperature,omitempty"`
+	TopP            *float64 `json:"topP,omitempty"`
+	MaxOutputTokens int      `json:"maxOutputTokens,omitempty"`
+	StopSequences   []string `json:"stopSequences,omitempty"`
+}
+
 type geminiResponse struct {
 	Candidates []geminiCandidate `json:"candidates"`
 	Error      *geminiError      `json:"error,omitempty"`
@@ -61,66 +74,121 @@ type geminiError struct {
 // Complete generates command completions using Gemini
 func (c *GeminiClient) Complete(ctx context.Context, req CompletionRequest) (*Response, error) {
 	prompt := buildCompletionPrompt(req)
-	return c.makeRequest(ctx, prompt)
+	content, err := c.makeRequest(ctx, prompt)
+	if err != nil {
+		return nil, err
+	}
+	return parseResponse(content)
 }
 
 // Predict generates command predictions using Gemini
 func (c *GeminiClient) Predict(ctx context.Context, req PredictionRequest) (*Response, error) {
 	prompt := buildPredictionPrompt(req)
-	return c.makeRequest(ctx, prompt)
+
+	// For prediction, we want the raw AI response without parsing
+	rawContent, err := c.makeRequest(ctx, prompt)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Response{
+		Type:    TypePrediction,
+		Content: rawContent,
+	}, nil
 }
 
-// makeRequest sends a request to Gemini API and processes the response
-func (c *GeminiClient) makeRequest(ctx context.Context, userPrompt string) (*Response, error) {
-	reqBody := geminiRequest{
-		SystemInstruction: &geminiSystemInstruction{
-			Parts: []geminiPart{{Text: getSystemPrompt()}},
-		},
-		Contents: []geminiContent{
-			{
-				Parts: []geminiPart{{Text: userPrompt}},
-			},
-		},
+// generationConfig returns the configured sampling parameters, or nil if none are set
+func (c *GeminiClient) generationConfig() *geminiGenerationConfig {
+	if c.config.Temperature == nil && c.config.TopP == nil && c.config.MaxTokens == 0 && len(c.config.Stop) == 0 {
+		return nil
+	}
+	return &geminiGenerationConfig{
+		Temperature:     c.config.Temperature,
+		TopP:            c.config.TopP,
+		MaxOutputTokens: c.config.MaxTokens,
+		StopSequences:   c.config.Stop,
 	}
+}
+
+// Stream generates command completions using Gemini's streamGenerateContent, reporting text as it arrives
+func (c *GeminiClient) Stream(ctx context.Context, req CompletionRequest, onDelta DeltaFunc) (*Response, error) {
+	url := fmt.Sprintf("%s/v1beta/models/%s:streamGenerateContent?alt=sse", c.config.BaseURL, c.config.Model)
 
-	jsonData, err := json.Marshal(reqBody)
+	resp, err := c.client.post(ctx, url, c.newRequest(buildCompletionPrompt(req)), acceptEventStream(c.setHeaders))
 	if err != nil {
-		return nil, fmt.Errorf("failed to marshal request: %w", err)
+		return nil, err
 	}
+	defer resp.Body.Close()
 
-	url := fmt.Sprintf("%s/v1beta/models/gemini-1.5-flash-latest:generateContent?key=%s",
-		c.config.BaseURL, c.config.APIKey)
-
-	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonData))
+	var content strings.Builder
+	err = readSSE(resp.Body, func(event, data string) error {
+		// Each event carries a partial generateContent response
+		var chunk geminiResponse
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			return fmt.Errorf("%w: failed to unmarshal stream chunk: %w", ErrInvalidResponse, err)
+		}
+		if chunk.Error != nil {
+			return fmt.Errorf("API error: %s", chunk.Error.Message)
+		}
+
+		for _, candidate := range chunk.Candidates {
+			for _, part := range candidate.Content.Parts {
+				if part.Text != "" {
+					content.WriteString(part.Text)
+					onDelta(part.Text)
+				}
+			}
+		}
+		return nil
+	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+		return nil, err
 	}
 
-	req.Header.Set("Content-Type", "application/json")
+	return parseResponse(content.String())
+}
 
-	resp, err := c.client.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("failed to send request: %w", err)
+// newRequest builds a generateContent request body from the client configuration
+func (c *GeminiClient) newRequest(userPrompt string) geminiRequest {
+	return geminiRequest{
+		SystemInstruction: &geminiSystemInstruction{
+			Parts: []geminiPart{{Text: getSystemPrompt()}},
+		},
+		Contents: []geminiContent{
+			{
+				Parts: []geminiPart{{Text: userPrompt}},
+			},
+		},
+		GenerationConfig: c.generationConfig(),
 	}
-	defer resp.Body.Close()
+}
+
+// makeRequest sends a request to Gemini API and returns the raw response content
+func (c *GeminiClient) makeRequest(ctx context.Context, userPrompt string) (string, error) {
+	url := fmt.Sprintf("%s/v1beta/models/%s:generateContent", c.config.BaseURL, c.config.Model)
 
-	body, err := io.ReadAll(resp.Body)
+	body, err := c.client.postJSON(ctx, url, c.newRequest(userPrompt), c.setHeaders)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read response: %w", err)
+		return "", err
 	}
 
 	var apiResp geminiResponse
 	if err := json.Unmarshal(body, &apiResp); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+		return "", fmt.Errorf("%w: failed to unmarshal response: %w", ErrInvalidResponse, err)
 	}
 
 	if apiResp.Error != nil {
-		return nil, fmt.Errorf("API error: %s", apiResp.Error.Message)
+		return "", fmt.Errorf("API error: %s", apiResp.Error.Message)
 	}
 
 	if len(apiResp.Candidates) == 0 || len(apiResp.Candidates[0].Content.Parts) == 0 {
-		return nil, fmt.Errorf("no content in response")
+		return "", fmt.Errorf("%w: no content in response", ErrInvalidResponse)
 	}
 
-	return parseResponse(apiResp.Candidates[0].Content.Parts[0].Text)
+	return strings.TrimSpace(apiResp.Candidates[0].Content.Parts[0].Text), nil
+}
+
+// setHeaders applies the API key header, which keeps the key out of request URLs and error messages
+func (c *GeminiClient) setHeaders(req *http.Request) {
+	req.Header.Set("x-goog-api-key", c.config.APIKey)
 }
@@ -1,18 +1,20 @@
 package ai
 
 import (
-	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
-	"io"
 	"net/http"
+	"strings"
 )
 
+// defaultGroqModel is used when no model is configured
+const defaultGroqModel = "llama-3.1-70b-versatile"
+
 // GroqClient implements the Client interface for Groq
 type GroqClient struct {
 	config Config
-	client *http.Client
+	client *httpClient
 }
 
 // NewGroqClient creates a new Groq client
@@ -20,15 +22,23 @@ func NewGroqClient(config Config) *GroqClient {
 	if config.BaseURL == "" {
 		config.BaseURL = "https://api.groq.com/openai"
 	}
+	if config.Model == "" {
+		config.Model = defaultGroqModel
+	}
 	return &GroqClient{
 		config: config,
-		client: &http.Client{},
+		client: newHTTPClient(config),
 	}
 }
 
 type groqRequest struct {
-	Model    string        `json:"model"`
-	Messages []groqMessage `json:"messages"`
+	Model       string        `json:"model"`
+	Messages    []groqMessage `json:"messages"`
+	Temperature *float64      `json:"temperature,omitempty"`
+	TopP        *float64      `json:"top_p,omitempty"`
+	MaxTokens   int           `json:"max_tokens,omitempty"`
+	Stop        []string      `json:"stop,omitempty"`
+	Stream      bool          `json:"stream,omitempty"`
 }
 
 type groqMessage struct {
@@ -54,61 +64,87 @@ type groqError struct {
 // Complete generates command completions using Groq
 func (c *GroqClient) Complete(ctx context.Context, req CompletionRequest) (*Response, error) {
 	prompt := buildCompletionPrompt(req)
-	return c.makeRequest(ctx, prompt)
+	content, err := c.makeRequest(ctx, prompt)
+	if err != nil {
+		return nil, err
+	}
+	return parseResponse(content)
 }
 
 // Predict generates command predictions using Groq
 func (c *GroqClient) Predict(ctx context.Context, req PredictionRequest) (*Response, error) {
 	prompt := buildPredictionPrompt(req)
-	return c.makeRequest(ctx, prompt)
-}
 
-// makeRequest sends a request to Groq API and processes the response
-func (c *GroqClient) makeRequest(ctx context.Context, userPrompt string) (*Response, error) {
-	reqBody := groqRequest{
-		Model: "llama-3.1-70b-versatile",
-		Messages: []groqMessage{
-			{Role: "system", Content: getSystemPrompt()},
-			{Role: "user", Content: userPrompt},
-		},
+	// For prediction, we want the raw AI response without parsing
+	rawContent, err := c.makeRequest(ctx, prompt)
+	if err != nil {
+		return nil, err
 	}
 
-	jsonData, err := json.Marshal(reqBody)
+	return &Response{
+		Type:    TypePrediction,
+		Content: rawContent,
+	}, nil
+}
+
+// Stream generates command completions using Groq, reporting text as it arrives
+func (c *GroqClient) Stream(ctx context.Context, req CompletionRequest, onDelta DeltaFunc) (*Response, error) {
+	reqBody := c.newRequest(buildCompletionPrompt(req))
+	reqBody.Stream = true
+
+	resp, err := c.client.post(ctx, c.config.BaseURL+"/v1/chat/completions", reqBody, acceptEventStream(c.setHeaders))
 	if err != nil {
-		return nil, fmt.Errorf("failed to marshal request: %w", err)
+		return nil, err
 	}
+	defer resp.Body.Close()
 
-	req, err := http.NewRequestWithContext(ctx, "POST", c.config.BaseURL+"/v1/chat/completions", bytes.NewBuffer(jsonData))
+	// Groq streams in the same chunk format as OpenAI
+	content, err := readOpenAIStream(resp.Body, onDelta)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+		return nil, err
 	}
+	return parseResponse(content)
+}
 
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", "Bearer "+c.config.APIKey)
-
-	resp, err := c.client.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("failed to send request: %w", err)
+// newRequest builds a chat request body from the client configuration
+func (c *GroqClient) newRequest(userPrompt string) groqRequest {
+	return groqRequest{
+		Model: c.config.Model,
+		Messages: []groqMessage{
+			{Role: "system", Content: getSystemPrompt()},
+			{Role: "user", Content: userPrompt},
+		},
+		Temperature: c.config.Temperature,
+		TopP:        c.config.TopP,
+		MaxTokens:   c.config.MaxTokens,
+		Stop:        c.config.Stop,
 	}
-	defer resp.Body.Close()
+}
 
-	body, err := io.ReadAll(resp.Body)
+// makeRequest sends a request to Groq API and returns the raw response content
+func (c *GroqClient) makeRequest(ctx context.Context, userPrompt string) (string, error) {
+	body, err := c.client.postJSON(ctx, c.config.BaseURL+"/v1/chat/completions", c.newRequest(userPrompt), c.setHeaders)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read response: %w", err)
+		return "", err
 	}
 
 	var apiResp groqResponse
 	if err := json.Unmarshal(body, &apiResp); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+		return "", fmt.Errorf("%w: failed to unmarshal response: %w", ErrInvalidResponse, err)
 	}
 
 	if apiResp.Error != nil {
-		return nil, fmt.Errorf("API error: %s", apiResp.Error.Message)
+		return "", fmt.Errorf("API error: %s", apiResp.Error.Message)
 	}
 
 	if len(apiResp.Choices) == 0 {
-		return nil, fmt.Errorf("no choices in response")
+		return "", fmt.Errorf("%w: no choices in response", ErrInvalidResponse)
 	}
 
-	return parseResponse(apiResp.Choices[0].Message.Content)
+	return strings.TrimSpace(apiResp.Choices[0].Message.Content), nil
+}
+
+// setHeaders applies the authorization header
+func (c *GroqClient) setHeaders(req *http.Request) {
+	req.Header.Set("Authorization", "Bearer "+c.config.APIKey)
 }
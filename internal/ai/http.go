@@ -0,0 +1,146 @@
+package ai
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"os"
+	"time"
+)
+
+const (
+	// defaultMaxRetries is the number of retries after the first attempt
+	defaultMaxRetries = 2
+
+	// retryBaseDelay and retryMaxDelay bound the exponential backoff
+	retryBaseDelay = 250 * time.Millisecond
+	retryMaxDelay  = 4 * time.Second
+)
+
+// httpClient is the HTTP layer shared by the provider clients. It turns
+// non-success responses into *APIError values and retries temporary failures.
+type httpClient struct {
+	provider   Provider
+	client     *http.Client
+	maxRetries int
+	debug      bool
+}
+
+// newHTTPClient creates the HTTP layer for a provider configuration
+func newHTTPClient(config Config) *httpClient {
+	maxRetries := config.MaxRetries
+	if maxRetries == 0 {
+		maxRetries = defaultMaxRetries
+	} else if maxRetries < 0 {
+		maxRetries = 0
+	}
+	return &httpClient{
+		provider:   config.Provider,
+		client:     &http.Client{},
+		maxRetries: maxRetries,
+		debug:      config.Debug,
+	}
+}
+
+// postJSON sends payload as JSON to url and returns the body of a successful response
+func (h *httpClient) postJSON(ctx context.Context, url string, payload any, setHeaders func(*http.Request)) ([]byte, error) {
+	resp, err := h.post(ctx, url, payload, setHeaders)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	return body, nil
+}
+
+// post sends payload as JSON to url and returns the successful response, whose
+// body the caller must close. Rate limits, server errors and transport failures
+// are retried with exponential backoff, honoring Retry-After, as long as the
+// wait fits in the context deadline.
+func (h *httpClient) post(ctx context.Context, url string, payload any, setHeaders func(*http.Request)) (*http.Response, error) {
+	jsonData, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	if h.debug {
+		fmt.Fprintf(os.Stderr, "Debug: Request payload: %s\n", string(jsonData))
+	}
+
+	for attempt := 0; ; attempt++ {
+		req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewReader(jsonData))
+		if err != nil {
+			return nil, fmt.Errorf("failed to create request: %w", err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+		setHeaders(req)
+
+		resp, err := h.client.Do(req)
+		if err == nil && resp.StatusCode < 300 {
+			return resp, nil
+		}
+
+		var retryAfter time.Duration
+		if err != nil {
+			err = transportError(ctx, err)
+			if ctx.Err() != nil {
+				return nil, err
+			}
+		} else {
+			body, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+			resp.Body.Close()
+			err = statusError(h.provider, resp, body)
+
+			var apiErr *APIError
+			if errors.As(err, &apiErr) {
+				if !apiErr.Temporary() {
+					return nil, err
+				}
+				retryAfter = apiErr.RetryAfter
+			}
+		}
+
+		if attempt >= h.maxRetries {
+			return nil, err
+		}
+
+		delay := backoff(attempt, retryAfter)
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) <= delay {
+			// Waiting would outlive the request, so report the failure now
+			return nil, err
+		}
+
+		if h.debug {
+			fmt.Fprintf(os.Stderr, "Debug: %v; retrying in %s (attempt %d of %d)\n", err, delay.Round(time.Millisecond), attempt+2, h.maxRetries+1)
+		}
+
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return nil, err
+		}
+	}
+}
+
+// backoff returns the delay before the next attempt. A Retry-After value from the
+// provider wins; otherwise the delay doubles per attempt with jitter.
+func backoff(attempt int, retryAfter time.Duration) time.Duration {
+	if retryAfter > 0 {
+		return retryAfter
+	}
+	delay := retryBaseDelay << attempt
+	if delay > retryMaxDelay {
+		delay = retryMaxDelay
+	}
+	// Up to 25% jitter keeps concurrent shells from retrying in lockstep
+	return delay + time.Duration(rand.Int63n(int64(delay)/4+1))
+}
@@ -0,0 +1,181 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		value string
+		want  time.Duration
+	}{
+		{"", 0},
+		{"20", 20 * time.Second},
+		{" 3 ", 3 * time.Second},
+		{"1.5", 1500 * time.Millisecond},
+		{"0", 0},
+		{"-4", 0},
+		{now.Add(90 * time.Second).Format(http.TimeFormat), 90 * time.Second},
+		{now.Add(-time.Minute).Format(http.TimeFormat), 0},
+		{"soon", 0},
+	}
+
+	for _, tt := range tests {
+		header := http.Header{}
+		if tt.value != "" {
+			header.Set("Retry-After", tt.value)
+		}
+		if got := parseRetryAfter(header, now); got != tt.want {
+			t.Errorf("parseRetryAfter(%q) = %s, want %s", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	if got := backoff(3, 7*time.Second); got != 7*time.Second {
+		t.Errorf("backoff with Retry-After = %s, want 7s", got)
+	}
+
+	for attempt := 0; attempt < 8; attempt++ {
+		base := retryBaseDelay << attempt
+		if base > retryMaxDelay {
+			base = retryMaxDelay
+		}
+		for i := 0; i < 20; i++ {
+			if got := backoff(attempt, 0); got < base || got > base+base/4 {
+				t.Fatalf("backoff(%d) = %s, want between %s and %s", attempt, got, base, base+base/4)
+			}
+		}
+	}
+}
+
+func TestErrorMessage(t *testing.T) {
+	tests := []struct {
+		body string
+		want string
+	}{
+		{`{"error": {"message": "invalid key", "type": "auth"}}`, "invalid key"},
+		{`{"error": "upstream down"}`, "upstream down"},
+		{"  Bad Gateway\n", "Bad Gateway"},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		if got := errorMessage([]byte(tt.body)); got != tt.want {
+			t.Errorf("errorMessage(%q) = %q, want %q", tt.body, got, tt.want)
+		}
+	}
+}
+
+func TestAPIErrorKinds(t *testing.T) {
+	tests := []struct {
+		status    int
+		kind      string
+		temporary bool
+	}{
+		{http.StatusTooManyRequests, "rate_limited", true},
+		{http.StatusUnauthorized, "auth", false},
+		{http.StatusForbidden, "auth", false},
+		{http.StatusBadRequest, "bad_request", false},
+		{http.StatusNotFound, "bad_request", false},
+		{http.StatusInternalServerError, "overloaded", true},
+		{http.StatusServiceUnavailable, "overloaded", true},
+	}
+
+	for _, tt := range tests {
+		err := &APIError{Provider: ProviderOpenAI, StatusCode: tt.status}
+		if kind := ErrorKind(fmt.Errorf("wrapped: %w", err)); kind != tt.kind {
+			t.Errorf("ErrorKind(%d) = %q, want %q", tt.status, kind, tt.kind)
+		}
+		if err.Temporary() != tt.temporary {
+			t.Errorf("Temporary(%d) = %v, want %v", tt.status, err.Temporary(), tt.temporary)
+		}
+	}
+}
+
+// statusSequence serves the given statuses in turn, then 200 OK, and counts requests
+func statusSequence(t *testing.T, retryAfter string, statuses ...int) (*httptest.Server, *atomic.Int32) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := int(requests.Add(1))
+		if n <= len(statuses) {
+			if retryAfter != "" {
+				w.Header().Set("Retry-After", retryAfter)
+			}
+			w.WriteHeader(statuses[n-1])
+			fmt.Fprint(w, `{"error": {"message": "try again"}}`)
+			return
+		}
+		fmt.Fprint(w, `{"ok": true}`)
+	}))
+	t.Cleanup(server.Close)
+	return server, &requests
+}
+
+func TestHTTPClientRetries(t *testing.T) {
+	tests := []struct {
+		name         string
+		statuses     []int
+		retryAfter   string
+		maxRetries   int
+		timeout      time.Duration
+		wantKind     string
+		wantRequests int32
+	}{
+		{"success", nil, "", 0, time.Second, "", 1},
+		{"retries server errors", []int{503, 500}, "", 0, 5 * time.Second, "", 3},
+		{"honors Retry-After", []int{429}, "0.01", 0, time.Second, "", 2},
+		{"gives up after max retries", []int{503, 503, 503}, "0.01", 1, time.Second, "overloaded", 2},
+		{"retries disabled", []int{503}, "", -1, time.Second, "overloaded", 1},
+		{"does not retry bad requests", []int{400}, "", 0, time.Second, "bad_request", 1},
+		{"does not retry auth failures", []int{401}, "", 0, time.Second, "auth", 1},
+		{"Retry-After past the deadline", []int{429}, "30", 0, time.Second, "rate_limited", 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, requests := statusSequence(t, tt.retryAfter, tt.statuses...)
+			client := newHTTPClient(Config{Provider: ProviderOpenAI, MaxRetries: tt.maxRetries})
+
+			ctx, cancel := context.WithTimeout(context.Background(), tt.timeout)
+			defer cancel()
+			body, err := client.postJSON(ctx, server.URL, map[string]string{"q": "x"}, func(*http.Request) {})
+
+			if kind := ErrorKind(err); kind != tt.wantKind {
+				t.Errorf("ErrorKind() = %q, want %q (err: %v)", kind, tt.wantKind, err)
+			}
+			if err == nil && string(body) != `{"ok": true}` {
+				t.Errorf("body = %s", body)
+			}
+			if got := requests.Load(); got != tt.wantRequests {
+				t.Errorf("requests = %d, want %d", got, tt.wantRequests)
+			}
+		})
+	}
+}
+
+func TestHTTPClientRateLimitRetryAfter(t *testing.T) {
+	server, _ := statusSequence(t, "30", 429)
+	client := newHTTPClient(Config{Provider: ProviderOpenAI})
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	_, err := client.postJSON(ctx, server.URL, nil, func(*http.Request) {})
+
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.Message != "try again" {
+		t.Fatalf("error = %v, want *APIError", err)
+	}
+	if got := RetryAfter(err); got != 30*time.Second {
+		t.Errorf("RetryAfter() = %s, want 30s", got)
+	}
+}
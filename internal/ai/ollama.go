@@ -0,0 +1,123 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// defaultOllamaModel is used when no model is configured for the local server
+const defaultOllamaModel = "llama3.1"
+
+// OllamaClient implements the Client interface for a local Ollama or llama.cpp server.
+// Both servers expose an OpenAI-compatible chat endpoint and need no API key.
+type OllamaClient struct {
+	config Config
+	client *httpClient
+}
+
+// NewOllamaClient creates a new client for a local Ollama or llama.cpp server
+func NewOllamaClient(config Config) *OllamaClient {
+	if config.BaseURL == "" {
+		config.BaseURL = "http://localhost:11434"
+	}
+	if !strings.Contains(config.BaseURL, "://") {
+		// OLLAMA_HOST is commonly set as host:port without a scheme
+		config.BaseURL = "http://" + config.BaseURL
+	}
+	config.BaseURL = strings.TrimSuffix(config.BaseURL, "/")
+	if config.Model == "" {
+		config.Model = defaultOllamaModel
+	}
+	return &OllamaClient{
+		config: config,
+		client: newHTTPClient(config),
+	}
+}
+
+// Complete generates command completions using the local model
+func (c *OllamaClient) Complete(ctx context.Context, req CompletionRequest) (*Response, error) {
+	prompt := buildCompletionPrompt(req)
+	content, err := c.makeRequest(ctx, prompt)
+	if err != nil {
+		return nil, err
+	}
+	return parseResponse(content)
+}
+
+// Predict generates command predictions using the local model
+func (c *OllamaClient) Predict(ctx context.Context, req PredictionRequest) (*Response, error) {
+	prompt := buildPredictionPrompt(req)
+
+	// For prediction, we want the raw AI response without parsing
+	content, err := c.makeRequest(ctx, prompt)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Response{
+		Type:    TypePrediction,
+		Content: content,
+	}, nil
+}
+
+// Stream generates command completions using the local model, reporting text as it arrives
+func (c *OllamaClient) Stream(ctx context.Context, req CompletionRequest, onDelta DeltaFunc) (*Response, error) {
+	reqBody := newOpenAIRequest(c.config, buildCompletionPrompt(req))
+	reqBody.Stream = true
+
+	resp, err := c.client.post(ctx, c.config.BaseURL+"/v1/chat/completions", reqBody, acceptEventStream(c.setHeaders))
+	if err != nil {
+		return nil, c.explain(err)
+	}
+	defer resp.Body.Close()
+
+	content, err := readOpenAIStream(resp.Body, onDelta)
+	if err != nil {
+		return nil, err
+	}
+	return parseResponse(content)
+}
+
+// makeRequest sends a chat request to the local server and returns the raw content
+func (c *OllamaClient) makeRequest(ctx context.Context, userPrompt string) (string, error) {
+	body, err := c.client.postJSON(ctx, c.config.BaseURL+"/v1/chat/completions", newOpenAIRequest(c.config, userPrompt), c.setHeaders)
+	if err != nil {
+		return "", c.explain(err)
+	}
+
+	var apiResp openAIResponse
+	if err := json.Unmarshal(body, &apiResp); err != nil {
+		return "", fmt.Errorf("%w: failed to unmarshal response: %w", ErrInvalidResponse, err)
+	}
+
+	if apiResp.Error != nil {
+		return "", fmt.Errorf("API error: %s", apiResp.Error.Message)
+	}
+
+	if len(apiResp.Choices) == 0 {
+		return "", fmt.Errorf("%w: no choices in response", ErrInvalidResponse)
+	}
+
+	return strings.TrimSpace(apiResp.Choices[0].Message.Content), nil
+}
+
+// setHeaders applies the authorization header when a key is configured
+func (c *OllamaClient) setHeaders(req *http.Request) {
+	if c.config.APIKey != "" {
+		// Only needed when the local server sits behind an authenticating proxy
+		req.Header.Set("Authorization", "Bearer "+c.config.APIKey)
+	}
+}
+
+// explain adds a hint for the most common local setup problem, a model that was never pulled
+func (c *OllamaClient) explain(err error) error {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound {
+		return fmt.Errorf("model %q not found on %s (try: ollama pull %s): %w", c.config.Model, c.config.BaseURL, c.config.Model, err)
+	}
+	return err
+}
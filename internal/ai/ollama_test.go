@@ -0,0 +1,185 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// ollamaServer serves the chat endpoint with handler and records the request
+func ollamaServer(t *testing.T, handler func(w http.ResponseWriter, req openAIRequest)) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/v1/chat/completions" {
+			http.NotFound(w, r)
+			return
+		}
+		var req openAIRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("decode request: %v", err)
+		}
+		handler(w, req)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// chatReply writes an OpenAI-style chat response with content
+func chatReply(w http.ResponseWriter, content string) {
+	json.NewEncoder(w).Encode(openAIResponse{Choices: []choice{{Message: message{Role: "assistant", Content: content}}}})
+}
+
+func TestNewOllamaClientBaseURL(t *testing.T) {
+	tests := []struct {
+		baseURL string
+		want    string
+	}{
+		{"", "http://localhost:11434"},
+		{"127.0.0.1:11434", "http://127.0.0.1:11434"},
+		{"http://gpu-box:8080/", "http://gpu-box:8080"},
+		{"https://llm.example.com", "https://llm.example.com"},
+	}
+
+	for _, tt := range tests {
+		client := NewOllamaClient(Config{Provider: ProviderOllama, BaseURL: tt.baseURL})
+		if client.config.BaseURL != tt.want {
+			t.Errorf("BaseURL %q = %q, want %q", tt.baseURL, client.config.BaseURL, tt.want)
+		}
+		if client.config.Model != defaultOllamaModel {
+			t.Errorf("Model = %q, want %q", client.config.Model, defaultOllamaModel)
+		}
+	}
+}
+
+func TestOllamaComplete(t *testing.T) {
+	tests := []struct {
+		reply    string
+		wantType ResponseType
+		want     string
+	}{
+		{"+atus", TypeCompletion, "atus"},
+		{"  =git status --short\n", TypeReplacement, "git status --short"},
+	}
+
+	for _, tt := range tests {
+		var got openAIRequest
+		server := ollamaServer(t, func(w http.ResponseWriter, req openAIRequest) {
+			got = req
+			chatReply(w, tt.reply)
+		})
+
+		client := NewOllamaClient(Config{Provider: ProviderOllama, BaseURL: server.URL, Model: "qwen2.5-coder"})
+		resp, err := client.Complete(context.Background(), CompletionRequest{Input: "git st"})
+		if err != nil {
+			t.Fatalf("Complete() error = %v", err)
+		}
+		if resp.Type != tt.wantType || resp.Content != tt.want {
+			t.Errorf("Complete() = %+v, want %s %q", resp, tt.wantType, tt.want)
+		}
+		if got.Model != "qwen2.5-coder" || len(got.Messages) != 2 || !strings.Contains(got.Messages[1].Content, "git st") {
+			t.Errorf("request = %+v", got)
+		}
+	}
+}
+
+func TestOllamaPredict(t *testing.T) {
+	server := ollamaServer(t, func(w http.ResponseWriter, req openAIRequest) {
+		chatReply(w, " make test \n")
+	})
+
+	client := NewOllamaClient(Config{Provider: ProviderOllama, BaseURL: server.URL})
+	resp, err := client.Predict(context.Background(), PredictionRequest{History: []HistoryEntry{{Command: "make build"}}})
+	if err != nil {
+		t.Fatalf("Predict() error = %v", err)
+	}
+	if resp.Type != TypePrediction || resp.Content != "make test" {
+		t.Errorf("Predict() = %+v", resp)
+	}
+}
+
+func TestOllamaStream(t *testing.T) {
+	server := ollamaServer(t, func(w http.ResponseWriter, req openAIRequest) {
+		if !req.Stream {
+			t.Error("request is not streamed")
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, delta := range []string{"+", "at", "us"} {
+			fmt.Fprintf(w, "data: {\"choices\":[{\"delta\":{\"content\":%q}}]}\n\n", delta)
+		}
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	})
+
+	var deltas []string
+	client := NewOllamaClient(Config{Provider: ProviderOllama, BaseURL: server.URL})
+	resp, err := client.Stream(context.Background(), CompletionRequest{Input: "git st"}, func(delta string) {
+		deltas = append(deltas, delta)
+	})
+	if err != nil {
+		t.Fatalf("Stream() error = %v", err)
+	}
+	if strings.Join(deltas, "") != "+atus" || resp.Content != "atus" {
+		t.Errorf("Stream() deltas %q, response %+v", deltas, resp)
+	}
+}
+
+func TestOllamaErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		handler func(w http.ResponseWriter, req openAIRequest)
+		kind    error
+		message string
+	}{
+		{
+			name: "model not pulled",
+			handler: func(w http.ResponseWriter, req openAIRequest) {
+				w.WriteHeader(http.StatusNotFound)
+				fmt.Fprint(w, `{"error": {"message": "model \"llama3.1\" not found"}}`)
+			},
+			kind:    ErrBadRequest,
+			message: "ollama pull llama3.1",
+		},
+		{
+			name: "no choices",
+			handler: func(w http.ResponseWriter, req openAIRequest) {
+				fmt.Fprint(w, `{"choices": []}`)
+			},
+			kind: ErrInvalidResponse,
+		},
+		{
+			name: "not JSON",
+			handler: func(w http.ResponseWriter, req openAIRequest) {
+				fmt.Fprint(w, "<html>")
+			},
+			kind: ErrInvalidResponse,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := ollamaServer(t, tt.handler)
+			client := NewOllamaClient(Config{Provider: ProviderOllama, BaseURL: server.URL, MaxRetries: -1})
+
+			_, err := client.Complete(context.Background(), CompletionRequest{Input: "ls"})
+			if !errors.Is(err, tt.kind) {
+				t.Errorf("Complete() error = %v, want %v", err, tt.kind)
+			}
+			if err != nil && !strings.Contains(err.Error(), tt.message) {
+				t.Errorf("Complete() error = %v, want it to mention %q", err, tt.message)
+			}
+		})
+	}
+}
+
+func TestOllamaUnreachable(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	server.Close()
+
+	client := NewOllamaClient(Config{Provider: ProviderOllama, BaseURL: server.URL, MaxRetries: -1})
+	if _, err := client.Complete(context.Background(), CompletionRequest{Input: "ls"}); !errors.Is(err, ErrNetwork) {
+		t.Errorf("Complete() error = %v, want %v", err, ErrNetwork)
+	}
+}
@@ -1,19 +1,20 @@
 package ai
 
 import (
-	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
-	"io"
 	"net/http"
 	"strings"
 )
 
-// OpenAIClient implements the Client interface for OpenAI
+// defaultOpenAIModel is used when no model is configured
+const defaultOpenAIModel = "gpt-4o-mini"
+
+// OpenAIClient implements the Client interface for OpenAI and OpenAI-compatible servers
 type OpenAIClient struct {
 	config Config
-	client *http.Client
+	client *httpClient
 }
 
 // NewOpenAIClient creates a new OpenAI client
@@ -21,15 +22,23 @@ func NewOpenAIClient(config Config) *OpenAIClient {
 	if config.BaseURL == "" {
 		config.BaseURL = "https://api.openai.com"
 	}
+	if config.Model == "" {
+		config.Model = defaultOpenAIModel
+	}
 	return &OpenAIClient{
 		config: config,
-		client: &http.Client{},
+		client: newHTTPClient(config),
 	}
 }
 
 type openAIRequest struct {
-	Model    string    `json:"model"`
-	Messages []message `json:"messages"`
+	Model       string    `json:"model"`
+	Messages    []message `json:"messages"`
+	Temperature *float64  `json:"temperature,omitempty"`
+	TopP        *float64  `json:"top_p,omitempty"`
+	MaxTokens   int       `json:"max_tokens,omitempty"`
+	Stop        []string  `json:"stop,omitempty"`
+	Stream      bool      `json:"stream,omitempty"`
 }
 
 type message struct {
@@ -51,10 +60,29 @@ type apiError struct {
 	Type    string `json:"type"`
 }
 
+// newOpenAIRequest builds a chat request body from the client configuration
+func newOpenAIRequest(config Config, userPrompt string) openAIRequest {
+	return openAIRequest{
+		Model: config.Model,
+		Messages: []message{
+			{Role: "system", Content: getSystemPrompt()},
+			{Role: "user", Content: userPrompt},
+		},
+		Temperature: config.Temperature,
+		TopP:        config.TopP,
+		MaxTokens:   config.MaxTokens,
+		Stop:        config.Stop,
+	}
+}
+
 // Complete generates command completions using OpenAI
 func (c *OpenAIClient) Complete(ctx context.Context, req CompletionRequest) (*Response, error) {
 	prompt := buildCompletionPrompt(req)
-	return c.makeRequest(ctx, prompt)
+	content, err := c.makeRequest(ctx, prompt)
+	if err != nil {
+		return nil, err
+	}
+	return parseResponse(content)
 }
 
 // Predict generates command predictions using OpenAI
@@ -62,7 +90,7 @@ func (c *OpenAIClient) Predict(ctx context.Context, req PredictionRequest) (*Res
 	prompt := buildPredictionPrompt(req)
 
 	// For prediction, we want the raw AI response without parsing
-	rawContent, err := c.makeRawRequest(ctx, prompt)
+	rawContent, err := c.makeRequest(ctx, prompt)
 	if err != nil {
 		return nil, err
 	}
@@ -74,43 +102,34 @@ func (c *OpenAIClient) Predict(ctx context.Context, req PredictionRequest) (*Res
 	}, nil
 }
 
-// makeRawRequest sends a request to OpenAI API and returns raw response
-func (c *OpenAIClient) makeRawRequest(ctx context.Context, userPrompt string) (string, error) {
-	reqBody := openAIRequest{
-		Model: "gpt-4o-mini",
-		Messages: []message{
-			{Role: "system", Content: getSystemPrompt()},
-			{Role: "user", Content: userPrompt},
-		},
-	}
+// Stream generates command completions using OpenAI, reporting text as it arrives
+func (c *OpenAIClient) Stream(ctx context.Context, req CompletionRequest, onDelta DeltaFunc) (*Response, error) {
+	reqBody := newOpenAIRequest(c.config, buildCompletionPrompt(req))
+	reqBody.Stream = true
 
-	jsonData, err := json.Marshal(reqBody)
+	resp, err := c.client.post(ctx, c.config.BaseURL+"/v1/chat/completions", reqBody, acceptEventStream(c.setHeaders))
 	if err != nil {
-		return "", fmt.Errorf("failed to marshal request: %w", err)
-	}
-
-	req, err := http.NewRequestWithContext(ctx, "POST", c.config.BaseURL+"/v1/chat/completions", bytes.NewBuffer(jsonData))
-	if err != nil {
-		return "", fmt.Errorf("failed to create request: %w", err)
+		return nil, err
 	}
+	defer resp.Body.Close()
 
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", "Bearer "+c.config.APIKey)
-
-	resp, err := c.client.Do(req)
+	content, err := readOpenAIStream(resp.Body, onDelta)
 	if err != nil {
-		return "", fmt.Errorf("failed to send request: %w", err)
+		return nil, err
 	}
-	defer resp.Body.Close()
+	return parseResponse(content)
+}
 
-	body, err := io.ReadAll(resp.Body)
+// makeRequest sends a request to OpenAI API and returns the raw response content
+func (c *OpenAIClient) makeRequest(ctx context.Context, userPrompt string) (string, error) {
+	body, err := c.client.postJSON(ctx, c.config.BaseURL+"/v1/chat/completions", newOpenAIRequest(c.config, userPrompt), c.setHeaders)
 	if err != nil {
-		return "", fmt.Errorf("failed to read response: %w", err)
+		return "", err
 	}
 
 	var apiResp openAIResponse
 	if err := json.Unmarshal(body, &apiResp); err != nil {
-		return "", fmt.Errorf("failed to unmarshal response: %w", err)
+		return "", fmt.Errorf("%w: failed to unmarshal response: %w", ErrInvalidResponse, err)
 	}
 
 	if apiResp.Error != nil {
@@ -118,68 +137,27 @@ func (c *OpenAIClient) makeRawRequest(ctx context.Context, userPrompt string) (s
 	}
 
 	if len(apiResp.Choices) == 0 {
-		return "", fmt.Errorf("no choices in response")
+		return "", fmt.Errorf("%w: no choices in response", ErrInvalidResponse)
 	}
 
-	// Return raw content without parsing
 	return strings.TrimSpace(apiResp.Choices[0].Message.Content), nil
 }
 
-// makeRequest sends a request to OpenAI API and processes the response
-func (c *OpenAIClient) makeRequest(ctx context.Context, userPrompt string) (*Response, error) {
-	reqBody := openAIRequest{
-		Model: "gpt-4o-mini",
-		Messages: []message{
-			{Role: "system", Content: getSystemPrompt()},
-			{Role: "user", Content: userPrompt},
-		},
-	}
-
-	jsonData, err := json.Marshal(reqBody)
-	if err != nil {
-		return nil, fmt.Errorf("failed to marshal request: %w", err)
-	}
-
-	req, err := http.NewRequestWithContext(ctx, "POST", c.config.BaseURL+"/v1/chat/completions", bytes.NewBuffer(jsonData))
-	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+// setHeaders applies the authorization and any configured extra headers
+func (c *OpenAIClient) setHeaders(req *http.Request) {
+	if c.config.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+c.config.APIKey)
 	}
-
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", "Bearer "+c.config.APIKey)
-
-	resp, err := c.client.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("failed to send request: %w", err)
+	for name, value := range c.config.Headers {
+		req.Header.Set(name, value)
 	}
-	defer resp.Body.Close()
-
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read response: %w", err)
-	}
-
-	var apiResp openAIResponse
-	if err := json.Unmarshal(body, &apiResp); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
-	}
-
-	if apiResp.Error != nil {
-		return nil, fmt.Errorf("API error: %s", apiResp.Error.Message)
-	}
-
-	if len(apiResp.Choices) == 0 {
-		return nil, fmt.Errorf("no choices in response")
-	}
-
-	return parseResponse(apiResp.Choices[0].Message.Content)
 }
 
 // parseResponse parses the AI response and determines the response type
 func parseResponse(content string) (*Response, error) {
 	content = strings.TrimSpace(content)
 	if len(content) == 0 {
-		return nil, fmt.Errorf("empty response")
+		return nil, fmt.Errorf("%w: empty response", ErrInvalidResponse)
 	}
 
 	switch content[0] {
@@ -0,0 +1,72 @@
+package ai
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestOpenAICompatibleClient(t *testing.T) {
+	tests := []struct {
+		name     string
+		suffix   string // appended to the server URL to form base_url
+		apiKey   string
+		wantAuth string
+	}{
+		{"bare URL", "", "", ""},
+		{"trailing slash", "/", "", ""},
+		{"version suffix", "/v1", "sk-gateway", "Bearer sk-gateway"},
+		{"version suffix with slash", "/v1/", "sk-gateway", "Bearer sk-gateway"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got *http.Request
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got = r
+				if r.URL.Path != "/v1/chat/completions" {
+					http.NotFound(w, r)
+					return
+				}
+				chatReply(w, "+atus")
+			}))
+			defer server.Close()
+
+			client, err := NewClient(Config{
+				Provider: ProviderOpenAICompatible,
+				BaseURL:  server.URL + tt.suffix,
+				Model:    "meta-llama/Llama-3.1-8B-Instruct",
+				APIKey:   tt.apiKey,
+				Headers:  map[string]string{"X-Team": "sre"},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp, err := client.Complete(context.Background(), CompletionRequest{Input: "git st"})
+			if err != nil {
+				t.Fatalf("Complete() error = %v (path %s)", err, got.URL.Path)
+			}
+			if resp.Content != "atus" {
+				t.Errorf("Complete() = %+v", resp)
+			}
+			if team := got.Header.Get("X-Team"); team != "sre" {
+				t.Errorf("X-Team header = %q, want %q", team, "sre")
+			}
+			if auth := got.Header.Get("Authorization"); auth != tt.wantAuth {
+				t.Errorf("Authorization header = %q, want %q", auth, tt.wantAuth)
+			}
+		})
+	}
+}
+
+func TestOpenAICompatibleClientRequiresURLAndModel(t *testing.T) {
+	for _, config := range []Config{
+		{Provider: ProviderOpenAICompatible, Model: "m"},
+		{Provider: ProviderOpenAICompatible, BaseURL: "http://gateway/v1"},
+	} {
+		if _, err := NewClient(config); err == nil {
+			t.Errorf("NewClient(%+v) error = nil", config)
+		}
+	}
+}
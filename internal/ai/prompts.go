@@ -1,7 +1,9 @@
 package ai
 
 import (
+	"encoding/json"
 	"fmt"
+	"sort"
 	"strings"
 )
 
@@ -30,6 +32,9 @@ CRITICAL REQUIREMENTS:
 - Consider the user's shell, OS, and current context
 - For predictions, suggest commonly used commands based on patterns
 - If the result command matches user's aliases, use the alias instead of the full command
+- When targets are listed for a task runner (make, npm run, just, task, go run), only suggest targets from that list
+- When completing a file or directory argument, prefer names from the FILES listings
+- Use the user's functions, global aliases and abbreviations where they fit; when INSTALLED lists programs, do not suggest well-known tools missing from it
 
 When predicting next command, you should prioritize considering user's previous commands and their output. 
 `
@@ -55,18 +60,47 @@ func buildCompletionPrompt(req CompletionRequest) string {
 			gitInfo += fmt.Sprintf(" (branch: %s)", req.Context.GitBranch)
 		}
 		parts = append(parts, fmt.Sprintf("GIT: %s", gitInfo))
+
+		if git := req.Context.Git; git != nil {
+			if state := gitState(git); len(state) > 0 {
+				parts = append(parts, fmt.Sprintf("GIT STATUS: %s", strings.Join(state, ", ")))
+			}
+			if git.Upstream != "" {
+				parts = append(parts, fmt.Sprintf("GIT UPSTREAM: %s (ahead %d, behind %d)", git.Upstream, git.Ahead, git.Behind))
+			}
+			if git.LastCommit != "" {
+				parts = append(parts, fmt.Sprintf("GIT LAST COMMIT: %s", git.LastCommit))
+			}
+		}
 	}
 
 	// Add aliases information
-	if len(req.Context.Aliases) > 0 {
+	if aliases := RankAliases(req.Context.Aliases, req.Input, req.History, 10); len(aliases) > 0 {
 		parts = append(parts, "ALIASES:")
-		aliasCount := 0
-		for name, command := range req.Context.Aliases {
-			if aliasCount >= 10 { // Limit to most relevant aliases
-				break
+		for _, alias := range aliases {
+			parts = append(parts, fmt.Sprintf("  %s='%s'", alias.Name, alias.Command))
+		}
+	}
+
+	// Add functions, abbreviations and installed programs
+	if commands := req.Context.Commands; commands != nil {
+		if len(commands.GlobalAliases) > 0 {
+			parts = append(parts, "GLOBAL ALIASES:")
+			for _, alias := range RankAliases(commands.GlobalAliases, req.Input, req.History, 10) {
+				parts = append(parts, fmt.Sprintf("  %s='%s'", alias.Name, alias.Command))
+			}
+		}
+		if len(commands.Abbreviations) > 0 {
+			parts = append(parts, "ABBREVIATIONS:")
+			for _, abbreviation := range RankAliases(commands.Abbreviations, req.Input, req.History, 10) {
+				parts = append(parts, fmt.Sprintf("  %s='%s'", abbreviation.Name, abbreviation.Command))
 			}
-			parts = append(parts, fmt.Sprintf("  %s='%s'", name, command))
-			aliasCount++
+		}
+		if len(commands.Functions) > 0 {
+			parts = append(parts, fmt.Sprintf("FUNCTIONS: %s", strings.Join(commands.Functions, ", ")))
+		}
+		if len(commands.Executables) > 0 {
+			parts = append(parts, fmt.Sprintf("INSTALLED: %s", strings.Join(commands.Executables, ", ")))
 		}
 	}
 
@@ -78,6 +112,63 @@ func buildCompletionPrompt(req CompletionRequest) string {
 		}
 		k8sInfo += ")"
 		parts = append(parts, fmt.Sprintf("K8S: %s", k8sInfo))
+
+		k8s := req.Context.K8sContext
+		if len(k8s.FailingPods) > 0 {
+			parts = append(parts, fmt.Sprintf("K8S FAILING PODS: %s", formatPodIssues(k8s.FailingPods)))
+		}
+		if len(k8s.Pods) > 0 {
+			parts = append(parts, fmt.Sprintf("K8S PODS: %s", strings.Join(k8s.Pods, ", ")))
+		}
+		if len(k8s.Deployments) > 0 {
+			parts = append(parts, fmt.Sprintf("K8S DEPLOYMENTS: %s", strings.Join(k8s.Deployments, ", ")))
+		}
+		if len(k8s.Services) > 0 {
+			parts = append(parts, fmt.Sprintf("K8S SERVICES: %s", strings.Join(k8s.Services, ", ")))
+		}
+	}
+
+	// Add Docker context
+	if docker := req.Context.Docker; docker != nil {
+		if docker.ComposeFile != "" {
+			parts = append(parts, fmt.Sprintf("DOCKER COMPOSE: %s (services: %s)", docker.ComposeFile, strings.Join(docker.ComposeServices, ", ")))
+		}
+		if len(docker.Dockerfiles) > 0 {
+			parts = append(parts, fmt.Sprintf("DOCKERFILES: %s", strings.Join(docker.Dockerfiles, ", ")))
+		}
+		if docker.CurrentContext != "" {
+			parts = append(parts, fmt.Sprintf("DOCKER CONTEXT: %s", docker.CurrentContext))
+		}
+		if len(docker.Containers) > 0 {
+			parts = append(parts, fmt.Sprintf("DOCKER CONTAINERS: %s", strings.Join(docker.Containers, ", ")))
+		}
+	}
+
+	// Add active cloud accounts
+	for _, line := range cloudLines(req.Context.Cloud) {
+		parts = append(parts, fmt.Sprintf("%s: %s", strings.ToUpper(line.name), line.value))
+	}
+
+	// Add directory contents
+	for _, listing := range req.Context.Files {
+		parts = append(parts, fmt.Sprintf("FILES %s", formatListing(listing)))
+	}
+
+	// Add project type and runnable targets
+	if project := req.Context.Project; project != nil {
+		projectInfo := project.Root
+		if len(project.Types) > 0 {
+			projectInfo = fmt.Sprintf("%s (root: %s)", strings.Join(project.Types, ", "), project.Root)
+		}
+		parts = append(parts, fmt.Sprintf("PROJECT: %s", projectInfo))
+		for _, targets := range project.Targets {
+			parts = append(parts, fmt.Sprintf("TARGETS (%s, from %s): %s", targets.Runner, targets.File, strings.Join(targets.Targets, ", ")))
+		}
+	}
+
+	// Add context from other sources
+	for _, extra := range formatExtras(req.Context.Extras) {
+		parts = append(parts, fmt.Sprintf("%s: %s", strings.ToUpper(extra.name), extra.value))
 	}
 
 	parts = append(parts, fmt.Sprintf("USER: %s", req.Context.User))
@@ -96,27 +187,36 @@ func buildPredictionPrompt(req PredictionRequest) string {
 	if len(req.History) > 0 {
 		parts = append(parts, "\nRECENT HISTORY:")
 		for i, entry := range req.History {
-			timestamp := entry.Timestamp.Format("15:04:05")
+			// Some histories, such as bash without HISTTIMEFORMAT, have no times
+			timestamp := ""
+			if !entry.Timestamp.IsZero() {
+				timestamp = fmt.Sprintf("[%s] ", entry.Timestamp.Format("15:04:05"))
+			}
 			exitInfo := ""
 			if entry.ExitCode != 0 {
 				exitInfo = fmt.Sprintf(" (exit: %d)", entry.ExitCode)
 			}
 
 			// Show command with timing info
-			cmdInfo := fmt.Sprintf("%d. [%s] %s%s", i+1, timestamp, entry.Command, exitInfo)
+			cmdInfo := fmt.Sprintf("%d. %s%s%s", i+1, timestamp, entry.Command, exitInfo)
 			if entry.Duration != "" {
 				cmdInfo += fmt.Sprintf(" (%s)", entry.Duration)
 			}
+			if entry.Directory != "" && entry.Directory != req.Context.Directory {
+				cmdInfo += fmt.Sprintf(" (in %s)", entry.Directory)
+			}
+			if len(entry.Paths) > 0 {
+				cmdInfo += fmt.Sprintf(" (files: %s)", strings.Join(entry.Paths, ", "))
+			}
 			parts = append(parts, cmdInfo)
 
-			// Show output if available (truncated)
-			if entry.Output != "" && entry.Output != "[command output not available]" {
+			// Show the end of the output if available, where results and errors are
+			if entry.Output != "" {
 				output := entry.Output
 				if len(output) > 200 {
-					lines := strings.Split(output, "\n")
+					lines := strings.Split(strings.TrimSpace(output), "\n")
 					if len(lines) > 3 {
-						lines = lines[:3]
-						lines = append(lines, "...(truncated)")
+						lines = append([]string{"(truncated)..."}, lines[len(lines)-3:]...)
 					}
 					output = strings.Join(lines, "\n")
 				}
@@ -126,8 +226,8 @@ func buildPredictionPrompt(req PredictionRequest) string {
 			// Show error output if available
 			if entry.ErrorOutput != "" {
 				errorOutput := entry.ErrorOutput
-				if len(errorOutput) > 100 {
-					errorOutput = errorOutput[:100] + "...(truncated)"
+				if len(errorOutput) > 200 {
+					errorOutput = "(truncated)..." + strings.ToValidUTF8(errorOutput[len(errorOutput)-200:], "")
 				}
 				parts = append(parts, fmt.Sprintf("   Error: %s", strings.TrimSpace(errorOutput)))
 			}
@@ -149,6 +249,22 @@ func buildPredictionPrompt(req PredictionRequest) string {
 			gitInfo += fmt.Sprintf(" (branch: %s)", req.Context.GitBranch)
 		}
 		parts = append(parts, fmt.Sprintf("Git Repository: %s", gitInfo))
+
+		if git := req.Context.Git; git != nil {
+			if state := gitState(git); len(state) > 0 {
+				parts = append(parts, fmt.Sprintf("Git Status: %s", strings.Join(state, ", ")))
+			} else {
+				parts = append(parts, "Git Status: clean")
+			}
+			if git.Upstream != "" {
+				parts = append(parts, fmt.Sprintf("Git Upstream: %s (ahead %d, behind %d)", git.Upstream, git.Ahead, git.Behind))
+			} else if !git.Detached {
+				parts = append(parts, "Git Upstream: none (branch not pushed)")
+			}
+			if git.LastCommit != "" {
+				parts = append(parts, fmt.Sprintf("Last Commit: %s", git.LastCommit))
+			}
+		}
 	} else {
 		parts = append(parts, "Git Repository: No")
 	}
@@ -165,30 +281,250 @@ func buildPredictionPrompt(req PredictionRequest) string {
 		if req.Context.K8sContext.ClusterInfo != "" {
 			parts = append(parts, fmt.Sprintf("Cluster: %s", req.Context.K8sContext.ClusterInfo))
 		}
+
+		k8s := req.Context.K8sContext
+		if len(k8s.FailingPods) > 0 {
+			parts = append(parts, fmt.Sprintf("Failing Pods: %s", formatPodIssues(k8s.FailingPods)))
+		}
+		if len(k8s.Pods) > 0 {
+			parts = append(parts, fmt.Sprintf("Pods: %s", strings.Join(k8s.Pods, ", ")))
+		}
+		if len(k8s.Deployments) > 0 {
+			parts = append(parts, fmt.Sprintf("Deployments: %s", strings.Join(k8s.Deployments, ", ")))
+		}
+		if len(k8s.Services) > 0 {
+			parts = append(parts, fmt.Sprintf("Services: %s", strings.Join(k8s.Services, ", ")))
+		}
 	} else {
 		parts = append(parts, "Kubernetes: Not available")
 	}
 
+	// Add Docker context
+	if docker := req.Context.Docker; docker != nil {
+		if docker.ComposeFile != "" {
+			parts = append(parts, fmt.Sprintf("Docker Compose: %s (services: %s)", docker.ComposeFile, strings.Join(docker.ComposeServices, ", ")))
+		}
+		if len(docker.Dockerfiles) > 0 {
+			parts = append(parts, fmt.Sprintf("Dockerfiles: %s", strings.Join(docker.Dockerfiles, ", ")))
+		}
+		if docker.CurrentContext != "" {
+			parts = append(parts, fmt.Sprintf("Docker Context: %s", docker.CurrentContext))
+		}
+		if len(docker.Containers) > 0 {
+			parts = append(parts, fmt.Sprintf("Running Containers: %s", strings.Join(docker.Containers, ", ")))
+		}
+	}
+
+	// Add active cloud accounts
+	for _, line := range cloudLines(req.Context.Cloud) {
+		parts = append(parts, fmt.Sprintf("%s: %s", line.name, line.value))
+	}
+
+	// Add directory contents
+	for _, listing := range req.Context.Files {
+		parts = append(parts, fmt.Sprintf("Files %s", formatListing(listing)))
+	}
+
+	// Add project type and runnable targets
+	if project := req.Context.Project; project != nil {
+		projectInfo := project.Root
+		if len(project.Types) > 0 {
+			projectInfo = fmt.Sprintf("%s (root: %s)", strings.Join(project.Types, ", "), project.Root)
+		}
+		parts = append(parts, fmt.Sprintf("Project: %s", projectInfo))
+		for _, targets := range project.Targets {
+			parts = append(parts, fmt.Sprintf("  %s targets (%s): %s", targets.Runner, targets.File, strings.Join(targets.Targets, ", ")))
+		}
+	}
+
 	// Add relevant aliases
-	if len(req.Context.Aliases) > 0 {
+	// Show more aliases for prediction context, ranked by recent use
+	if aliases := RankAliases(req.Context.Aliases, "", req.History, 15); len(aliases) > 0 {
 		parts = append(parts, "\nAVAILABLE ALIASES:")
-		aliasCount := 0
-		for name, command := range req.Context.Aliases {
-			if aliasCount >= 15 { // Show more aliases for prediction context
-				break
+		for _, alias := range aliases {
+			parts = append(parts, fmt.Sprintf("  %s='%s'", alias.Name, alias.Command))
+		}
+	}
+
+	// Add functions, abbreviations and installed programs
+	if commands := req.Context.Commands; commands != nil {
+		if len(commands.GlobalAliases) > 0 {
+			parts = append(parts, "\nGLOBAL ALIASES:")
+			for _, alias := range RankAliases(commands.GlobalAliases, "", req.History, 15) {
+				parts = append(parts, fmt.Sprintf("  %s='%s'", alias.Name, alias.Command))
 			}
-			parts = append(parts, fmt.Sprintf("  %s='%s'", name, command))
-			aliasCount++
+		}
+		if len(commands.Abbreviations) > 0 {
+			parts = append(parts, "\nABBREVIATIONS:")
+			for _, abbreviation := range RankAliases(commands.Abbreviations, "", req.History, 15) {
+				parts = append(parts, fmt.Sprintf("  %s='%s'", abbreviation.Name, abbreviation.Command))
+			}
+		}
+		if len(commands.Functions) > 0 {
+			parts = append(parts, fmt.Sprintf("\nSHELL FUNCTIONS: %s", strings.Join(commands.Functions, ", ")))
+		}
+		if len(commands.Executables) > 0 {
+			parts = append(parts, fmt.Sprintf("INSTALLED PROGRAMS: %s", strings.Join(commands.Executables, ", ")))
+		}
+	}
+
+	// Add context from other sources
+	if extras := formatExtras(req.Context.Extras); len(extras) > 0 {
+		parts = append(parts, "\nADDITIONAL CONTEXT:")
+		for _, extra := range extras {
+			parts = append(parts, fmt.Sprintf("  %s: %s", extra.name, extra.value))
 		}
 	}
 
 	parts = append(parts, "\nBased on the command history patterns, current context, available aliases, and Kubernetes environment, what command is the user most likely to run next?")
 	parts = append(parts, "Consider:")
 	parts = append(parts, "- Command execution patterns and failures")
-	parts = append(parts, "- Directory context and git repository state")
+	parts = append(parts, "- Directory context and git repository state (e.g. an operation in progress, unpushed commits)")
 	parts = append(parts, "- Kubernetes context and common operations")
-	parts = append(parts, "- Available aliases that might be useful")
+	parts = append(parts, "- The active cloud account, project and Terraform workspace")
+	parts = append(parts, "- Available aliases, functions and abbreviations that might be useful")
+	parts = append(parts, "- Only programs that are installed")
 	parts = append(parts, "- Time of day and typical workflow patterns")
 
 	return strings.Join(parts, "\n")
 }
+
+// gitState summarizes the operation in progress and the working tree of a repository
+func gitState(git *GitContext) []string {
+	var state []string
+	if git.Operation != "" {
+		state = append(state, git.Operation+" in progress")
+	}
+	if git.Detached {
+		state = append(state, "detached HEAD")
+	}
+	if git.Worktree {
+		state = append(state, "linked worktree")
+	}
+	if git.Conflicted > 0 {
+		state = append(state, fmt.Sprintf("%d conflicted", git.Conflicted))
+	}
+	if git.Staged > 0 {
+		state = append(state, fmt.Sprintf("%d staged", git.Staged))
+	}
+	if git.Unstaged > 0 {
+		state = append(state, fmt.Sprintf("%d unstaged", git.Unstaged))
+	}
+	if git.Untracked > 0 {
+		state = append(state, fmt.Sprintf("%d untracked", git.Untracked))
+	}
+	if git.Stashes == 1 {
+		state = append(state, "1 stash")
+	} else if git.Stashes > 1 {
+		state = append(state, fmt.Sprintf("%d stashes", git.Stashes))
+	}
+	return state
+}
+
+// formatPodIssues renders failing pods as "name (reason)"
+func formatPodIssues(issues []PodIssue) string {
+	formatted := make([]string, len(issues))
+	for i, issue := range issues {
+		formatted[i] = fmt.Sprintf("%s (%s)", issue.Name, issue.Reason)
+	}
+	return strings.Join(formatted, ", ")
+}
+
+// formatListing renders a directory listing as "in <dir> [matching ...]: names"
+func formatListing(listing DirListing) string {
+	header := "in " + listing.Dir
+	if listing.Prefix != "" {
+		header += fmt.Sprintf(" matching %q", listing.Prefix)
+	}
+	entries := strings.Join(listing.Entries, " ")
+	if listing.Truncated {
+		entries += " ...(truncated)"
+	}
+	return fmt.Sprintf("%s: %s", header, entries)
+}
+
+// cloudLines renders the active cloud accounts and Terraform workspace
+func cloudLines(cloud *CloudContext) []extra {
+	if cloud == nil {
+		return nil
+	}
+
+	var lines []extra
+	if aws := cloud.AWS; aws != nil {
+		value := "profile " + aws.Profile
+		if aws.Region != "" {
+			value += ", region " + aws.Region
+		}
+		lines = append(lines, extra{name: "AWS", value: value})
+	}
+	if gcp := cloud.GCP; gcp != nil {
+		value := "configuration " + gcp.Configuration
+		if gcp.Project != "" {
+			value = "project " + gcp.Project + ", " + value
+		}
+		if gcp.Account != "" {
+			value += ", account " + gcp.Account
+		}
+		if gcp.Region != "" {
+			value += ", region " + gcp.Region
+		}
+		lines = append(lines, extra{name: "GCP", value: value})
+	}
+	if azure := cloud.Azure; azure != nil {
+		value := "subscription " + azure.Subscription
+		if azure.SubscriptionID != "" {
+			value += fmt.Sprintf(" (%s)", azure.SubscriptionID)
+		}
+		if azure.User != "" {
+			value += ", user " + azure.User
+		}
+		lines = append(lines, extra{name: "Azure", value: value})
+	}
+	if tf := cloud.Terraform; tf != nil {
+		value := "workspace " + tf.Workspace
+		if !tf.Initialized {
+			value += ", not initialized (run terraform init)"
+		}
+		lines = append(lines, extra{name: "Terraform", value: value})
+	}
+	return lines
+}
+
+// extra is a rendered entry of Context.Extras
+type extra struct {
+	name  string
+	value string
+}
+
+// formatExtras renders Context.Extras sorted by name. Strings and string lists
+// are written as text, anything else as compact JSON.
+func formatExtras(extras map[string]any) []extra {
+	names := make([]string, 0, len(extras))
+	for name := range extras {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var formatted []extra
+	for _, name := range names {
+		var value string
+		switch v := extras[name].(type) {
+		case string:
+			value = v
+		case []string:
+			value = strings.Join(v, ", ")
+		case fmt.Stringer:
+			value = v.String()
+		default:
+			data, err := json.Marshal(v)
+			if err != nil {
+				continue
+			}
+			value = string(data)
+		}
+		if value != "" {
+			formatted = append(formatted, extra{name: name, value: value})
+		}
+	}
+	return formatted
+}
@@ -0,0 +1,109 @@
+package ai
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// DeltaFunc receives each chunk of generated text as it arrives
+type DeltaFunc func(delta string)
+
+// readSSE reads a server-sent event stream and calls onEvent for every event with data.
+// Returning io.EOF from onEvent stops reading without an error.
+func readSSE(r io.Reader, onEvent func(event, data string) error) error {
+	reader := bufio.NewReader(r)
+	var event string
+	var data []string
+
+	dispatch := func() error {
+		if len(data) == 0 {
+			event = ""
+			return nil
+		}
+		err := onEvent(event, strings.Join(data, "\n"))
+		event, data = "", nil
+		return err
+	}
+
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil && err != io.EOF {
+			return fmt.Errorf("failed to read stream: %w", err)
+		}
+		line = strings.TrimRight(line, "\r\n")
+
+		switch {
+		case line == "":
+			if dispatchErr := dispatch(); dispatchErr != nil {
+				if dispatchErr == io.EOF {
+					return nil
+				}
+				return dispatchErr
+			}
+		case strings.HasPrefix(line, ":"):
+			// Comment line, used by some servers as keep-alive
+		case strings.HasPrefix(line, "event:"):
+			event = strings.TrimSpace(strings.TrimPrefix(line, "event:"))
+		case strings.HasPrefix(line, "data:"):
+			data = append(data, strings.TrimPrefix(strings.TrimPrefix(line, "data:"), " "))
+		}
+
+		if err == io.EOF {
+			if dispatchErr := dispatch(); dispatchErr != nil && dispatchErr != io.EOF {
+				return dispatchErr
+			}
+			return nil
+		}
+	}
+}
+
+// acceptEventStream wraps setHeaders to also request a server-sent event stream
+func acceptEventStream(setHeaders func(*http.Request)) func(*http.Request) {
+	return func(req *http.Request) {
+		setHeaders(req)
+		req.Header.Set("Accept", "text/event-stream")
+	}
+}
+
+type openAIStreamChunk struct {
+	Choices []struct {
+		Delta message `json:"delta"`
+	} `json:"choices"`
+	Error *apiError `json:"error,omitempty"`
+}
+
+// readOpenAIStream reads an OpenAI-style chat completion stream and returns
+// the full generated text once the stream ends
+func readOpenAIStream(body io.Reader, onDelta DeltaFunc) (string, error) {
+	var content strings.Builder
+	err := readSSE(body, func(event, data string) error {
+		if data == "[DONE]" {
+			return io.EOF
+		}
+
+		var chunk openAIStreamChunk
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			return fmt.Errorf("%w: failed to unmarshal stream chunk: %w", ErrInvalidResponse, err)
+		}
+		if chunk.Error != nil {
+			return fmt.Errorf("API error: %s", chunk.Error.Message)
+		}
+
+		for _, c := range chunk.Choices {
+			if c.Delta.Content != "" {
+				content.WriteString(c.Delta.Content)
+				onDelta(c.Delta.Content)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return content.String(), nil
+}
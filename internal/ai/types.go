@@ -10,12 +10,18 @@ const (
 	ProviderAnthropic Provider = "anthropic"
 	ProviderGemini    Provider = "gemini"
 	ProviderGroq      Provider = "groq"
+	ProviderOllama    Provider = "ollama"
+
+	// ProviderOpenAICompatible targets any server speaking the OpenAI chat API,
+	// such as vLLM, LiteLLM or an internal gateway
+	ProviderOpenAICompatible Provider = "openai-compatible"
 )
 
 // CompletionRequest represents a request for command completion
 type CompletionRequest struct {
-	Input   string  `json:"input"`
-	Context Context `json:"context"`
+	Input   string         `json:"input"`
+	Context Context        `json:"context"`
+	History []HistoryEntry `json:"history,omitempty"` // recent commands, used to rank aliases
 }
 
 // PredictionRequest represents a request for command prediction
@@ -34,9 +40,118 @@ type Context struct {
 	Platform   string            `json:"platform"`
 	IsGitRepo  bool              `json:"is_git_repo"`
 	GitBranch  string            `json:"git_branch,omitempty"`
+	Git        *GitContext       `json:"git,omitempty"`
 	DateTime   time.Time         `json:"datetime"`
 	Aliases    map[string]string `json:"aliases"`
 	K8sContext *K8sContext       `json:"k8s_context,omitempty"`
+	Docker     *DockerContext    `json:"docker,omitempty"`
+	Project    *ProjectContext   `json:"project,omitempty"`
+	Cloud      *CloudContext     `json:"cloud,omitempty"`
+	Files      []DirListing      `json:"files,omitempty"` // the directory, then the one a partial path in the input points at
+	Commands   *CommandContext   `json:"commands,omitempty"`
+
+	// Extras holds context from sources without a dedicated field, keyed by source name
+	Extras map[string]any `json:"extras,omitempty"`
+}
+
+// SetExtra stores value under key in Extras
+func (c *Context) SetExtra(key string, value any) {
+	if c.Extras == nil {
+		c.Extras = make(map[string]any)
+	}
+	c.Extras[key] = value
+}
+
+// GitContext describes the state of the git repository around the directory
+type GitContext struct {
+	Root       string `json:"root"`
+	Worktree   bool   `json:"worktree,omitempty"` // a linked worktree rather than the main checkout
+	Detached   bool   `json:"detached,omitempty"`
+	Staged     int    `json:"staged"`
+	Unstaged   int    `json:"unstaged"`
+	Untracked  int    `json:"untracked"`
+	Conflicted int    `json:"conflicted,omitempty"`
+	Upstream   string `json:"upstream,omitempty"`
+	Ahead      int    `json:"ahead,omitempty"`
+	Behind     int    `json:"behind,omitempty"`
+	Stashes    int    `json:"stashes,omitempty"`
+	LastCommit string `json:"last_commit,omitempty"` // subject of HEAD
+	Operation  string `json:"operation,omitempty"`   // rebase, merge, cherry-pick, revert or bisect in progress
+}
+
+// DockerContext describes the Docker setup of the project and the Docker daemon
+type DockerContext struct {
+	Dockerfiles     []string `json:"dockerfiles,omitempty"`
+	ComposeFile     string   `json:"compose_file,omitempty"`
+	ComposeServices []string `json:"compose_services,omitempty"`
+	CurrentContext  string   `json:"current_context,omitempty"`
+	Containers      []string `json:"containers,omitempty"` // names of running containers
+}
+
+// ProjectContext describes the project around the directory and what can be run in it
+type ProjectContext struct {
+	Root    string        `json:"root"`
+	Types   []string      `json:"types,omitempty"` // go, node, rust, python
+	Targets []TaskTargets `json:"targets,omitempty"`
+}
+
+// TaskTargets lists the targets a task runner can run
+type TaskTargets struct {
+	Runner  string   `json:"runner"` // the command that runs a target, e.g. "make" or "npm run"
+	File    string   `json:"file"`
+	Targets []string `json:"targets"`
+}
+
+// CommandContext describes the commands available to the user besides aliases
+type CommandContext struct {
+	Functions     []string          `json:"functions,omitempty"`      // user-defined shell functions
+	GlobalAliases map[string]string `json:"global_aliases,omitempty"` // zsh aliases expanded anywhere in a command line
+	Abbreviations map[string]string `json:"abbreviations,omitempty"`  // fish abbreviations
+	Executables   []string          `json:"executables,omitempty"`    // notable and user-installed programs on $PATH
+}
+
+// DirListing is a bounded listing of a directory. Directory names end in "/".
+type DirListing struct {
+	Dir       string   `json:"dir"`              // as referenced from the working directory, e.g. "." or "src/"
+	Prefix    string   `json:"prefix,omitempty"` // entries were filtered to names starting with Prefix
+	Entries   []string `json:"entries"`
+	Truncated bool     `json:"truncated,omitempty"`
+}
+
+// CloudContext describes the active cloud accounts and infrastructure tooling,
+// read from local configuration only
+type CloudContext struct {
+	AWS       *AWSContext       `json:"aws,omitempty"`
+	GCP       *GCPContext       `json:"gcp,omitempty"`
+	Azure     *AzureContext     `json:"azure,omitempty"`
+	Terraform *TerraformContext `json:"terraform,omitempty"`
+}
+
+// AWSContext is the active AWS CLI profile
+type AWSContext struct {
+	Profile string `json:"profile"`
+	Region  string `json:"region,omitempty"`
+}
+
+// GCPContext is the active gcloud configuration
+type GCPContext struct {
+	Configuration string `json:"configuration"`
+	Project       string `json:"project,omitempty"`
+	Account       string `json:"account,omitempty"`
+	Region        string `json:"region,omitempty"`
+}
+
+// AzureContext is the default Azure CLI subscription
+type AzureContext struct {
+	Subscription   string `json:"subscription"`
+	SubscriptionID string `json:"subscription_id,omitempty"`
+	User           string `json:"user,omitempty"`
+}
+
+// TerraformContext describes the Terraform configuration in the directory
+type TerraformContext struct {
+	Workspace   string `json:"workspace"`
+	Initialized bool   `json:"initialized"` // .terraform exists
 }
 
 // K8sContext contains Kubernetes environment information
@@ -45,11 +160,26 @@ type K8sContext struct {
 	CurrentContext   string `json:"current_context,omitempty"`
 	CurrentNamespace string `json:"current_namespace,omitempty"`
 	ClusterInfo      string `json:"cluster_info,omitempty"`
+
+	// Resources in the current namespace, collected only when the
+	// k8s_resources source is switched on
+	Pods        []string   `json:"pods,omitempty"`
+	Deployments []string   `json:"deployments,omitempty"`
+	Services    []string   `json:"services,omitempty"`
+	FailingPods []PodIssue `json:"failing_pods,omitempty"`
+}
+
+// PodIssue is a pod that is not running normally
+type PodIssue struct {
+	Name   string `json:"name"`
+	Reason string `json:"reason"` // e.g. CrashLoopBackOff, Error or Pending
 }
 
 // HistoryEntry represents a shell command and its result
 type HistoryEntry struct {
 	Command     string    `json:"command"`
+	Directory   string    `json:"directory,omitempty"` // where the command ran, when known
+	Paths       []string  `json:"paths,omitempty"`     // files the command referred to, as fish records them
 	Output      string    `json:"output"`
 	ErrorOutput string    `json:"error_output"`
 	ExitCode    int       `json:"exit_code"`
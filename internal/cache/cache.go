@@ -0,0 +1,361 @@
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"supertab/internal/ai"
+)
+
+const (
+	// DefaultTTL is how long a cached completion stays valid
+	DefaultTTL = 24 * time.Hour
+
+	// DefaultMaxSize bounds the total size of cached entries in bytes
+	DefaultMaxSize = 5 << 20
+
+	entrySuffix = ".json"
+	statsFile   = "stats.json"
+
+	// statsBatch is how many lookups are counted in memory before the
+	// counters on disk are updated
+	statsBatch = 32
+)
+
+// Store is a file-based cache of AI responses, one file per key
+type Store struct {
+	dir     string
+	ttl     time.Duration
+	maxSize int64
+
+	mu      sync.Mutex
+	pending counters // hits and misses not written to disk yet
+}
+
+// entry is the on-disk representation of a cached response
+type entry struct {
+	Input    string      `json:"input"`
+	Created  time.Time   `json:"created"`
+	Response ai.Response `json:"response"`
+}
+
+// Stats describes the cache contents and its hit rate
+type Stats struct {
+	Dir     string `json:"dir"`
+	Entries int    `json:"entries"`
+	Expired int    `json:"expired"`
+	Bytes   int64  `json:"bytes"`
+	Hits    int64  `json:"hits"`
+	Misses  int64  `json:"misses"`
+}
+
+// counters are persisted between runs so stats cover every shell
+type counters struct {
+	Hits   int64 `json:"hits"`
+	Misses int64 `json:"misses"`
+}
+
+// DefaultDir returns $XDG_CACHE_HOME/sug, falling back to ~/.cache/sug
+func DefaultDir() string {
+	if dir := os.Getenv("XDG_CACHE_HOME"); dir != "" {
+		return filepath.Join(dir, "sug")
+	}
+	return filepath.Join(os.Getenv("HOME"), ".cache", "sug")
+}
+
+// NewStore creates a store keeping completions under dir. Zero ttl or
+// maxSize select the defaults.
+func NewStore(dir string, ttl time.Duration, maxSize int64) *Store {
+	if ttl <= 0 {
+		ttl = DefaultTTL
+	}
+	if maxSize <= 0 {
+		maxSize = DefaultMaxSize
+	}
+	return &Store{
+		dir:     filepath.Join(dir, "completions"),
+		ttl:     ttl,
+		maxSize: maxSize,
+	}
+}
+
+// Scope identifies the providers and models answering completions, in the
+// order they are tried, so answers cached with one setup are not served
+// after switching to another
+func Scope(configs []ai.Config) string {
+	var scope strings.Builder
+	for _, config := range configs {
+		model := config.Model
+		if model == "" {
+			model = "default"
+		}
+		fmt.Fprintf(&scope, "%s/%s@%s", config.Provider, model, config.BaseURL)
+		if config.Temperature != nil {
+			fmt.Fprintf(&scope, " temperature=%g", *config.Temperature)
+		}
+		if config.TopP != nil {
+			fmt.Fprintf(&scope, " top_p=%g", *config.TopP)
+		}
+		if config.MaxTokens > 0 {
+			fmt.Fprintf(&scope, " max_tokens=%d", config.MaxTokens)
+		}
+		if len(config.Stop) > 0 {
+			fmt.Fprintf(&scope, " stop=%q", config.Stop)
+		}
+		scope.WriteString(";")
+	}
+	return scope.String()
+}
+
+// Key derives the cache key for a completion request from the scope of the
+// client answering it, the normalized input and the context fields that
+// change what a good completion looks like
+func Key(scope string, req ai.CompletionRequest) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "scope=%s\n", scope)
+	fmt.Fprintf(h, "input=%s\n", normalizeInput(req.Input))
+	fmt.Fprintf(h, "dir=%s\n", req.Context.Directory)
+	fmt.Fprintf(h, "branch=%s\n", req.Context.GitBranch)
+	if git := req.Context.Git; git != nil && git.Operation != "" {
+		fmt.Fprintf(h, "git=%s\n", git.Operation)
+	}
+	if k8s := req.Context.K8sContext; k8s != nil && k8s.IsAvailable {
+		fmt.Fprintf(h, "k8s=%s/%s\n", k8s.CurrentContext, k8s.CurrentNamespace)
+	}
+	if cloud := req.Context.Cloud; cloud != nil {
+		if cloud.AWS != nil {
+			fmt.Fprintf(h, "aws=%s/%s\n", cloud.AWS.Profile, cloud.AWS.Region)
+		}
+		if cloud.GCP != nil {
+			fmt.Fprintf(h, "gcp=%s\n", cloud.GCP.Project)
+		}
+		if cloud.Azure != nil {
+			fmt.Fprintf(h, "azure=%s\n", cloud.Azure.SubscriptionID)
+		}
+		if cloud.Terraform != nil {
+			fmt.Fprintf(h, "terraform=%s\n", cloud.Terraform.Workspace)
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// normalizeInput collapses runs of whitespace inside the input but keeps a
+// trailing space, which changes what the next token should be
+func normalizeInput(input string) string {
+	normalized := strings.Join(strings.Fields(input), " ")
+	if strings.HasSuffix(input, " ") && normalized != "" {
+		normalized += " "
+	}
+	return normalized
+}
+
+// Get returns the cached response for key if it exists and has not expired
+func (s *Store) Get(key string) (*ai.Response, bool) {
+	data, err := os.ReadFile(s.path(key))
+	if err != nil {
+		s.count(false)
+		return nil, false
+	}
+
+	var e entry
+	if err := json.Unmarshal(data, &e); err != nil || time.Since(e.Created) > s.ttl {
+		os.Remove(s.path(key))
+		s.count(false)
+		return nil, false
+	}
+
+	s.count(true)
+	return &e.Response, true
+}
+
+// Put stores resp under key and evicts the oldest entries if the cache grew too large
+func (s *Store) Put(key, input string, resp *ai.Response) error {
+	if err := os.MkdirAll(s.dir, 0o700); err != nil {
+		return fmt.Errorf("failed to create cache directory: %w", err)
+	}
+
+	data, err := json.Marshal(entry{Input: input, Created: time.Now(), Response: *resp})
+	if err != nil {
+		return fmt.Errorf("failed to marshal cache entry: %w", err)
+	}
+
+	if err := WriteFileAtomic(s.path(key), data); err != nil {
+		return fmt.Errorf("failed to write cache entry: %w", err)
+	}
+
+	return s.prune()
+}
+
+// Stats reports the number and size of cached entries and the hit counters
+func (s *Store) Stats() (Stats, error) {
+	stats := Stats{Dir: s.dir}
+
+	files, err := s.entries()
+	if err != nil {
+		return stats, err
+	}
+	for _, f := range files {
+		stats.Entries++
+		stats.Bytes += f.size
+		if time.Since(f.modTime) > s.ttl {
+			stats.Expired++
+		}
+	}
+
+	c := s.readCounters()
+	s.mu.Lock()
+	stats.Hits, stats.Misses = c.Hits+s.pending.Hits, c.Misses+s.pending.Misses
+	s.mu.Unlock()
+	return stats, nil
+}
+
+// Clear removes every cached entry and resets the counters
+func (s *Store) Clear() error {
+	s.mu.Lock()
+	s.pending = counters{}
+	s.mu.Unlock()
+	if err := os.RemoveAll(s.dir); err != nil {
+		return fmt.Errorf("failed to clear cache: %w", err)
+	}
+	return nil
+}
+
+// path returns the file holding key
+func (s *Store) path(key string) string {
+	return filepath.Join(s.dir, key+entrySuffix)
+}
+
+type fileInfo struct {
+	path    string
+	size    int64
+	modTime time.Time
+}
+
+// entries lists the cache entry files
+func (s *Store) entries() ([]fileInfo, error) {
+	dirEntries, err := os.ReadDir(s.dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cache directory: %w", err)
+	}
+
+	var files []fileInfo
+	for _, de := range dirEntries {
+		if de.IsDir() || !strings.HasSuffix(de.Name(), entrySuffix) || de.Name() == statsFile {
+			continue
+		}
+		info, err := de.Info()
+		if err != nil {
+			continue
+		}
+		files = append(files, fileInfo{
+			path:    filepath.Join(s.dir, de.Name()),
+			size:    info.Size(),
+			modTime: info.ModTime(),
+		})
+	}
+	return files, nil
+}
+
+// prune removes expired entries, then the oldest ones until the cache fits maxSize
+func (s *Store) prune() error {
+	files, err := s.entries()
+	if err != nil {
+		return err
+	}
+
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].modTime.Before(files[j].modTime)
+	})
+
+	var total int64
+	for _, f := range files {
+		total += f.size
+	}
+
+	for _, f := range files {
+		if total <= s.maxSize && time.Since(f.modTime) <= s.ttl {
+			break
+		}
+		if err := os.Remove(f.path); err == nil {
+			total -= f.size
+		}
+	}
+	return nil
+}
+
+// count records a hit or miss, writing the counters every statsBatch lookups
+func (s *Store) count(hit bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if hit {
+		s.pending.Hits++
+	} else {
+		s.pending.Misses++
+	}
+	if s.pending.Hits+s.pending.Misses >= statsBatch {
+		s.flush()
+	}
+}
+
+// Flush writes the hits and misses counted since the last write
+func (s *Store) Flush() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.flush()
+}
+
+// flush adds the pending counts to the counters on disk. Updates from
+// concurrent shells may race; the counters are informational only.
+func (s *Store) flush() {
+	if s.pending == (counters{}) {
+		return
+	}
+	c := s.readCounters()
+	c.Hits += s.pending.Hits
+	c.Misses += s.pending.Misses
+	s.pending = counters{}
+	if data, err := json.Marshal(c); err == nil {
+		if os.MkdirAll(s.dir, 0o700) == nil {
+			WriteFileAtomic(filepath.Join(s.dir, statsFile), data)
+		}
+	}
+}
+
+// readCounters loads the persisted hit counters
+func (s *Store) readCounters() counters {
+	var c counters
+	if data, err := os.ReadFile(filepath.Join(s.dir, statsFile)); err == nil {
+		json.Unmarshal(data, &c)
+	}
+	return c
+}
+
+// WriteFileAtomic writes data to a temporary file and renames it into place,
+// so readers in other shells never see a partial entry
+func WriteFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
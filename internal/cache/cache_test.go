@@ -0,0 +1,277 @@
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"supertab/internal/ai"
+)
+
+func TestNormalizeInput(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"git status", "git status"},
+		{"  git   status ", "git status "},
+		{"git\tcommit  -m", "git commit -m"},
+		{"   ", ""},
+	}
+
+	for _, tt := range tests {
+		if got := normalizeInput(tt.input); got != tt.want {
+			t.Errorf("normalizeInput(%q) = %q, want %q", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestKey(t *testing.T) {
+	base := ai.CompletionRequest{
+		Input:   "git st",
+		Context: ai.Context{Directory: "/src/app", GitBranch: "main", User: "me", DateTime: time.Now()},
+	}
+	with := func(change func(*ai.CompletionRequest)) ai.CompletionRequest {
+		req := base
+		change(&req)
+		return req
+	}
+
+	same := []struct {
+		name string
+		req  ai.CompletionRequest
+	}{
+		{"extra whitespace", with(func(r *ai.CompletionRequest) { r.Input = "git  st" })},
+		{"other time", with(func(r *ai.CompletionRequest) { r.Context.DateTime = time.Now().Add(time.Hour) })},
+		{"other history", with(func(r *ai.CompletionRequest) { r.History = []ai.HistoryEntry{{Command: "ls"}} })},
+		{"no git operation", with(func(r *ai.CompletionRequest) { r.Context.Git = &ai.GitContext{} })},
+	}
+	for _, tt := range same {
+		if Key("openai/gpt-4o-mini", tt.req) != Key("openai/gpt-4o-mini", base) {
+			t.Errorf("%s: key changed", tt.name)
+		}
+	}
+
+	different := []struct {
+		name string
+		req  ai.CompletionRequest
+	}{
+		{"input", with(func(r *ai.CompletionRequest) { r.Input = "git sh" })},
+		{"trailing space", with(func(r *ai.CompletionRequest) { r.Input = "git st " })},
+		{"directory", with(func(r *ai.CompletionRequest) { r.Context.Directory = "/src/other" })},
+		{"branch", with(func(r *ai.CompletionRequest) { r.Context.GitBranch = "dev" })},
+		{"git operation", with(func(r *ai.CompletionRequest) { r.Context.Git = &ai.GitContext{Operation: "rebase"} })},
+		{"k8s namespace", with(func(r *ai.CompletionRequest) {
+			r.Context.K8sContext = &ai.K8sContext{IsAvailable: true, CurrentContext: "prod", CurrentNamespace: "api"}
+		})},
+		{"aws profile", with(func(r *ai.CompletionRequest) {
+			r.Context.Cloud = &ai.CloudContext{AWS: &ai.AWSContext{Profile: "prod"}}
+		})},
+	}
+	for _, tt := range different {
+		if Key("openai/gpt-4o-mini", tt.req) == Key("openai/gpt-4o-mini", base) {
+			t.Errorf("%s: key did not change", tt.name)
+		}
+	}
+	if Key("ollama/llama3.1", base) == Key("openai/gpt-4o-mini", base) {
+		t.Error("scope: key did not change")
+	}
+}
+
+func TestScope(t *testing.T) {
+	zero := 0.0
+	base := []ai.Config{{Provider: ai.ProviderGroq}, {Provider: ai.ProviderOpenAI, Model: "gpt-4o-mini"}}
+	with := func(change func([]ai.Config)) []ai.Config {
+		configs := append([]ai.Config(nil), base...)
+		change(configs)
+		return configs
+	}
+
+	tests := []struct {
+		name    string
+		configs []ai.Config
+		same    bool
+	}{
+		{"other API key", with(func(c []ai.Config) { c[1].APIKey = "sk-other" }), true},
+		{"other headers", with(func(c []ai.Config) { c[1].Headers = map[string]string{"X-Team": "sre"} }), true},
+		{"other retries", with(func(c []ai.Config) { c[0].MaxRetries = 5 }), true},
+		{"other model", with(func(c []ai.Config) { c[1].Model = "gpt-4o" }), false},
+		{"model set", with(func(c []ai.Config) { c[0].Model = "llama-3.1-8b-instant" }), false},
+		{"other order", []ai.Config{base[1], base[0]}, false},
+		{"fewer providers", base[1:], false},
+		{"other base URL", with(func(c []ai.Config) { c[1].BaseURL = "http://gateway/v1" }), false},
+		{"temperature", with(func(c []ai.Config) { c[1].Temperature = &zero }), false},
+		{"max tokens", with(func(c []ai.Config) { c[1].MaxTokens = 50 }), false},
+		{"stop", with(func(c []ai.Config) { c[1].Stop = []string{"\n"} }), false},
+	}
+
+	for _, tt := range tests {
+		if same := Scope(tt.configs) == Scope(base); same != tt.same {
+			t.Errorf("%s: same scope = %v, want %v", tt.name, same, tt.same)
+		}
+	}
+}
+
+func TestStoreGetPut(t *testing.T) {
+	store := NewStore(t.TempDir(), time.Hour, 0)
+	resp := &ai.Response{Type: ai.TypeCompletion, Content: "atus"}
+
+	if _, ok := store.Get("k"); ok {
+		t.Fatal("Get() hit on an empty cache")
+	}
+	if err := store.Put("k", "git st", resp); err != nil {
+		t.Fatal(err)
+	}
+	got, ok := store.Get("k")
+	if !ok || *got != *resp {
+		t.Fatalf("Get() = %v, %v, want %v", got, ok, resp)
+	}
+
+	stats, err := store.Stats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Entries != 1 || stats.Hits != 1 || stats.Misses != 1 {
+		t.Errorf("Stats() = %+v, want 1 entry, 1 hit and 1 miss", stats)
+	}
+
+	if err := store.Clear(); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := store.Get("k"); ok {
+		t.Error("Get() hit after Clear()")
+	}
+}
+
+func TestStoreCountsBatched(t *testing.T) {
+	dir := t.TempDir()
+	store := NewStore(dir, time.Hour, 0)
+	statsPath := filepath.Join(store.dir, statsFile)
+
+	store.Get("missing")
+	if _, err := os.Stat(statsPath); !os.IsNotExist(err) {
+		t.Error("a single lookup wrote the counters")
+	}
+
+	// Another process sees the counts once they are flushed
+	store.Flush()
+	if stats, _ := NewStore(dir, time.Hour, 0).Stats(); stats.Misses != 1 {
+		t.Errorf("Stats() after Flush() = %+v, want 1 miss", stats)
+	}
+
+	// And every statsBatch lookups without flushing
+	for i := 0; i < statsBatch; i++ {
+		store.Get("missing")
+	}
+	if stats, _ := NewStore(dir, time.Hour, 0).Stats(); stats.Misses != 1+statsBatch {
+		t.Errorf("Stats() after %d lookups = %+v, want %d misses", statsBatch, stats, 1+statsBatch)
+	}
+}
+
+func TestStoreExpiry(t *testing.T) {
+	store := NewStore(t.TempDir(), time.Hour, 0)
+	if err := os.MkdirAll(store.dir, 0o700); err != nil {
+		t.Fatal(err)
+	}
+
+	data, _ := json.Marshal(entry{Input: "ls", Created: time.Now().Add(-2 * time.Hour), Response: ai.Response{Content: "old"}})
+	if err := os.WriteFile(store.path("old"), data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := store.Get("old"); ok {
+		t.Error("Get() returned an expired entry")
+	}
+	if _, err := os.Stat(store.path("old")); !os.IsNotExist(err) {
+		t.Error("expired entry was not removed")
+	}
+}
+
+func TestStorePrune(t *testing.T) {
+	store := NewStore(t.TempDir(), time.Hour, 600)
+	resp := &ai.Response{Type: ai.TypeCompletion, Content: strings.Repeat("x", 100)}
+
+	for i, key := range []string{"a", "b", "c", "d", "e"} {
+		if err := store.Put(key, key, resp); err != nil {
+			t.Fatal(err)
+		}
+		// Entries are evicted oldest first by modification time
+		old := time.Now().Add(time.Duration(i-10) * time.Minute)
+		os.Chtimes(store.path(key), old, old)
+	}
+
+	stats, _ := store.Stats()
+	if stats.Bytes > 600 {
+		t.Errorf("cache holds %d bytes, want at most 600", stats.Bytes)
+	}
+	if _, err := os.Stat(store.path("e")); err != nil {
+		t.Error("newest entry was evicted")
+	}
+	if _, err := os.Stat(store.path("a")); !os.IsNotExist(err) {
+		t.Error("oldest entry was kept")
+	}
+}
+
+// countingClient answers completions and counts the calls that reach it
+type countingClient struct {
+	calls int
+}
+
+func (c *countingClient) Complete(ctx context.Context, req ai.CompletionRequest) (*ai.Response, error) {
+	c.calls++
+	return &ai.Response{Type: ai.TypeReplacement, Content: "git status"}, nil
+}
+
+func (c *countingClient) Predict(ctx context.Context, req ai.PredictionRequest) (*ai.Response, error) {
+	c.calls++
+	return &ai.Response{Type: ai.TypePrediction, Content: "make"}, nil
+}
+
+func (c *countingClient) Stream(ctx context.Context, req ai.CompletionRequest, onDelta ai.DeltaFunc) (*ai.Response, error) {
+	onDelta("=git status")
+	return c.Complete(ctx, req)
+}
+
+func TestClient(t *testing.T) {
+	next := &countingClient{}
+	store := NewStore(t.TempDir(), 0, 0)
+	client := NewClient(next, store, "openai/gpt-4o-mini", false)
+	req := ai.CompletionRequest{Input: "git st"}
+
+	for i := 0; i < 2; i++ {
+		if _, err := client.Complete(context.Background(), req); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if next.calls != 1 {
+		t.Errorf("provider called %d times for a repeated completion, want 1", next.calls)
+	}
+
+	var streamed string
+	resp, err := client.Stream(context.Background(), req, func(delta string) { streamed += delta })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if next.calls != 1 || streamed != "=git status" || resp.Content != "git status" {
+		t.Errorf("cached stream sent %q (%d calls)", streamed, next.calls)
+	}
+
+	// Answers of other providers or models are not reused
+	if _, err := NewClient(next, store, "ollama/llama3.1", false).Complete(context.Background(), req); err != nil {
+		t.Fatal(err)
+	}
+	if next.calls != 2 {
+		t.Errorf("provider called %d times after switching models, want 2", next.calls)
+	}
+
+	for i := 0; i < 2; i++ {
+		client.Predict(context.Background(), ai.PredictionRequest{})
+	}
+	if next.calls != 4 {
+		t.Errorf("predictions were cached: %d calls, want 4", next.calls)
+	}
+}
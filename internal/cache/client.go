@@ -0,0 +1,94 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"supertab/internal/ai"
+)
+
+// Client wraps an ai.Client and answers repeated completions from a Store.
+// Predictions depend on history and are always passed through.
+type Client struct {
+	next  ai.Client
+	store *Store
+	scope string
+	debug bool
+}
+
+// NewClient creates a caching client in front of next, which answers with
+// the providers and models of scope; see Scope
+func NewClient(next ai.Client, store *Store, scope string, debug bool) *Client {
+	return &Client{
+		next:  next,
+		store: store,
+		scope: scope,
+		debug: debug,
+	}
+}
+
+// Complete returns a cached completion if there is one, otherwise asks the
+// wrapped client and caches its answer
+func (c *Client) Complete(ctx context.Context, req ai.CompletionRequest) (*ai.Response, error) {
+	key := Key(c.scope, req)
+	if resp, ok := c.store.Get(key); ok {
+		if c.debug {
+			fmt.Fprintf(os.Stderr, "Debug: completion cache hit for %q\n", req.Input)
+		}
+		return resp, nil
+	}
+
+	resp, err := c.next.Complete(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	c.put(key, req.Input, resp)
+	return resp, nil
+}
+
+// Predict passes predictions through uncached
+func (c *Client) Predict(ctx context.Context, req ai.PredictionRequest) (*ai.Response, error) {
+	return c.next.Predict(ctx, req)
+}
+
+// Stream replays a cached completion as a single chunk, otherwise streams
+// from the wrapped client and caches the final answer
+func (c *Client) Stream(ctx context.Context, req ai.CompletionRequest, onDelta ai.DeltaFunc) (*ai.Response, error) {
+	key := Key(c.scope, req)
+	if resp, ok := c.store.Get(key); ok {
+		if c.debug {
+			fmt.Fprintf(os.Stderr, "Debug: completion cache hit for %q\n", req.Input)
+		}
+		onDelta(rawContent(resp))
+		return resp, nil
+	}
+
+	resp, err := c.next.Stream(ctx, req, onDelta)
+	if err != nil {
+		return nil, err
+	}
+
+	c.put(key, req.Input, resp)
+	return resp, nil
+}
+
+// put stores a response; a cache that cannot be written only costs speed
+func (c *Client) put(key, input string, resp *ai.Response) {
+	if err := c.store.Put(key, input, resp); err != nil && c.debug {
+		fmt.Fprintf(os.Stderr, "Debug: failed to cache completion: %v\n", err)
+	}
+}
+
+// rawContent restores the +/= prefix the model produced for a parsed response
+func rawContent(resp *ai.Response) string {
+	switch resp.Type {
+	case ai.TypeCompletion:
+		return "+" + resp.Content
+	case ai.TypeReplacement:
+		return "=" + resp.Content
+	default:
+		return resp.Content
+	}
+}
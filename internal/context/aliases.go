@@ -0,0 +1,119 @@
+package context
+
+import (
+	"bufio"
+	"context"
+	"os"
+	"strings"
+	"time"
+
+	"supertab/internal/ai"
+)
+
+// aliasesSource collects the user's shell aliases
+type aliasesSource struct{}
+
+func init() {
+	Register(aliasesSource{})
+}
+
+// Name returns the source name
+func (aliasesSource) Name() string { return "aliases" }
+
+// Enabled reports whether the source is switched on
+func (aliasesSource) Enabled(opts Options) bool { return opts.SourceEnabled("aliases", true) }
+
+// Timeout returns the time budget of the source. Spawning an interactive
+// shell is the slowest part of context collection.
+func (aliasesSource) Timeout() time.Duration { return 1500 * time.Millisecond }
+
+// Collect gathers shell aliases from various sources
+func (aliasesSource) Collect(ctx context.Context, opts Options, out *ai.Context) error {
+	out.Aliases = make(map[string]string)
+
+	// Method 1: Get aliases from the interactive shell, in the run shared with
+	// the functions source
+	if strings.Contains(opts.Shell, "zsh") || strings.Contains(opts.Shell, "bash") {
+		if output, err := shellDefinitions(ctx, opts); err == nil {
+			parseAliases(output.aliases, out.Aliases)
+		}
+	}
+
+	// Method 2: Try sourcing rc files and getting aliases
+	if len(out.Aliases) == 0 {
+		var rcCommand string
+		if strings.Contains(opts.Shell, "zsh") {
+			rcCommand = "source ~/.zshrc 2>/dev/null; alias 2>/dev/null"
+		} else if strings.Contains(opts.Shell, "bash") {
+			rcCommand = "source ~/.bashrc 2>/dev/null; alias 2>/dev/null"
+		}
+
+		if rcCommand != "" {
+			cmd := opts.command(ctx, "sh", "-c", rcCommand)
+			if output, err := cmd.Output(); err == nil {
+				parseAliases(string(output), out.Aliases)
+			}
+		}
+	}
+
+	// Method 3: Read common alias files
+	aliasFiles := []string{
+		os.Getenv("HOME") + "/.aliases",
+		os.Getenv("HOME") + "/.bash_aliases",
+		os.Getenv("HOME") + "/.zsh_aliases",
+	}
+
+	for _, file := range aliasFiles {
+		readAliasFile(file, out.Aliases)
+	}
+	return nil
+}
+
+// parseAliases parses alias command output
+func parseAliases(output string, aliases map[string]string) {
+	lines := strings.Split(output, "\n")
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+
+		if strings.HasPrefix(line, "alias ") {
+			// Parse format: alias name='command'
+			parts := strings.SplitN(strings.TrimPrefix(line, "alias "), "=", 2)
+			if len(parts) == 2 {
+				name := strings.TrimSpace(parts[0])
+				value := strings.Trim(strings.TrimSpace(parts[1]), "'\"")
+				aliases[name] = value
+			}
+		} else if strings.Contains(line, "=") && !strings.HasPrefix(line, "-e") {
+			// Handle lines that start directly with alias definitions (zsh -i -c alias format)
+			// Exclude lines starting with -e (options)
+			parts := strings.SplitN(line, "=", 2)
+			if len(parts) == 2 {
+				name := strings.TrimSpace(parts[0])
+				value := strings.Trim(strings.TrimSpace(parts[1]), "'\"")
+				if name != "" && value != "" && !strings.Contains(name, " ") {
+					aliases[name] = value
+				}
+			}
+		}
+	}
+}
+
+// readAliasFile reads aliases from a file
+func readAliasFile(filename string, aliases map[string]string) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "alias ") {
+			parseAliases(line, aliases)
+		}
+	}
+}
@@ -0,0 +1,220 @@
+package context
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"supertab/internal/ai"
+)
+
+// The cloud sources read CLI configuration files only; they never run the
+// cloud CLIs or contact an API.
+
+// awsSource reports the active AWS profile and region
+type awsSource struct{}
+
+// gcpSource reports the active gcloud project and account
+type gcpSource struct{}
+
+// azureSource reports the default Azure subscription
+type azureSource struct{}
+
+// cloudTimeout is the time budget of each cloud source
+const cloudTimeout = 300 * time.Millisecond
+
+func init() {
+	Register(awsSource{})
+	Register(gcpSource{})
+	Register(azureSource{})
+}
+
+// Name returns the source name
+func (awsSource) Name() string { return "aws" }
+
+// Enabled reports whether the source is switched on
+func (awsSource) Enabled(opts Options) bool { return opts.SourceEnabled("aws", true) }
+
+// Timeout returns the time budget of the source
+func (awsSource) Timeout() time.Duration { return cloudTimeout }
+
+// Collect reads the profile from AWS_PROFILE and its region from ~/.aws/config
+func (awsSource) Collect(ctx context.Context, opts Options, out *ai.Context) error {
+	profile := opts.firstEnv("AWS_PROFILE", "AWS_DEFAULT_PROFILE")
+
+	configPath := opts.Getenv("AWS_CONFIG_FILE")
+	if configPath == "" {
+		configPath = homePath(".aws", "config")
+	}
+	config := readINI(configPath)
+
+	// Without a profile in the environment or a config file there is no AWS setup to report
+	if profile == "" && config == nil {
+		return nil
+	}
+	if profile == "" {
+		profile = "default"
+	}
+
+	section := "profile " + profile
+	if profile == "default" {
+		section = "default"
+	}
+
+	region := opts.firstEnv("AWS_REGION", "AWS_DEFAULT_REGION")
+	if region == "" {
+		region = config[section]["region"]
+	}
+
+	setCloud(out).AWS = &ai.AWSContext{Profile: profile, Region: region}
+	return nil
+}
+
+// Name returns the source name
+func (gcpSource) Name() string { return "gcp" }
+
+// Enabled reports whether the source is switched on
+func (gcpSource) Enabled(opts Options) bool { return opts.SourceEnabled("gcp", true) }
+
+// Timeout returns the time budget of the source
+func (gcpSource) Timeout() time.Duration { return cloudTimeout }
+
+// Collect reads the active configuration from ~/.config/gcloud
+func (gcpSource) Collect(ctx context.Context, opts Options, out *ai.Context) error {
+	configDir := opts.Getenv("CLOUDSDK_CONFIG")
+	if configDir == "" {
+		configDir = homePath(".config", "gcloud")
+	}
+
+	name := opts.Getenv("CLOUDSDK_ACTIVE_CONFIG_NAME")
+	if name == "" {
+		data, err := os.ReadFile(filepath.Join(configDir, "active_config"))
+		if err != nil {
+			return nil
+		}
+		name = strings.TrimSpace(string(data))
+	}
+	if name == "" {
+		name = "default"
+	}
+
+	config := readINI(filepath.Join(configDir, "configurations", "config_"+name))
+	gcp := &ai.GCPContext{
+		Configuration: name,
+		Project:       config["core"]["project"],
+		Account:       config["core"]["account"],
+		Region:        config["compute"]["region"],
+	}
+	if project := opts.Getenv("CLOUDSDK_CORE_PROJECT"); project != "" {
+		gcp.Project = project
+	}
+
+	setCloud(out).GCP = gcp
+	return nil
+}
+
+// Name returns the source name
+func (azureSource) Name() string { return "azure" }
+
+// Enabled reports whether the source is switched on
+func (azureSource) Enabled(opts Options) bool { return opts.SourceEnabled("azure", true) }
+
+// Timeout returns the time budget of the source
+func (azureSource) Timeout() time.Duration { return cloudTimeout }
+
+// Collect reads the default subscription from ~/.azure/azureProfile.json
+func (azureSource) Collect(ctx context.Context, opts Options, out *ai.Context) error {
+	configDir := opts.Getenv("AZURE_CONFIG_DIR")
+	if configDir == "" {
+		configDir = homePath(".azure")
+	}
+
+	data, err := os.ReadFile(filepath.Join(configDir, "azureProfile.json"))
+	if err != nil {
+		return nil
+	}
+
+	// The Azure CLI writes the profile with a byte order mark
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+
+	var profile struct {
+		Subscriptions []struct {
+			ID        string `json:"id"`
+			Name      string `json:"name"`
+			IsDefault bool   `json:"isDefault"`
+			User      struct {
+				Name string `json:"name"`
+			} `json:"user"`
+		} `json:"subscriptions"`
+	}
+	if err := json.Unmarshal(data, &profile); err != nil {
+		return err
+	}
+
+	for _, sub := range profile.Subscriptions {
+		if sub.IsDefault {
+			setCloud(out).Azure = &ai.AzureContext{
+				Subscription:   sub.Name,
+				SubscriptionID: sub.ID,
+				User:           sub.User.Name,
+			}
+			break
+		}
+	}
+	return nil
+}
+
+// setCloud returns out.Cloud, creating it if needed
+func setCloud(out *ai.Context) *ai.CloudContext {
+	if out.Cloud == nil {
+		out.Cloud = &ai.CloudContext{}
+	}
+	return out.Cloud
+}
+
+// homePath joins elem to the user's home directory
+func homePath(elem ...string) string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(append([]string{home}, elem...)...)
+}
+
+// readINI parses an INI file into sections of key/value pairs. It returns
+// nil if the file cannot be read.
+func readINI(path string) map[string]map[string]string {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil
+	}
+	defer file.Close()
+
+	sections := make(map[string]map[string]string)
+	section := ""
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
+			continue
+		}
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			section = strings.TrimSpace(line[1 : len(line)-1])
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+		if sections[section] == nil {
+			sections[section] = make(map[string]string)
+		}
+		sections[section][strings.TrimSpace(key)] = strings.TrimSpace(value)
+	}
+	return sections
+}
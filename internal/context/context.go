@@ -1,10 +1,11 @@
 package context
 
 import (
-	"bufio"
-	"fmt"
+	"context"
 	"os"
 	"os/exec"
+	"path/filepath"
+	"reflect"
 	"runtime"
 	"strings"
 	"time"
@@ -12,258 +13,272 @@ import (
 	"supertab/internal/ai"
 )
 
+// waitDelay bounds how long a killed command may keep its output pipes open,
+// e.g. when an interactive shell left a child process behind
+const waitDelay = 100 * time.Millisecond
+
+// partialGrace is how long a source that ran out of time may take to return
+// what it collected so far. Its commands are killed at the deadline and give
+// up their output pipes within waitDelay.
+const partialGrace = waitDelay + 50*time.Millisecond
+
 // Collector collects system context information
-type Collector struct{}
+type Collector struct {
+	// dir is the directory to describe; empty means the working directory
+	dir string
+
+	// sources switches registered sources on or off by name
+	sources map[string]bool
+
+	// input is the partial command being completed
+	input string
 
-// NewCollector creates a new context collector
+	// env is the environment of the shell the context is for; nil means
+	// the process's own
+	env map[string]string
+}
+
+// NewCollector creates a new context collector for the working directory
 func NewCollector() *Collector {
 	return &Collector{}
 }
 
-// Collect gathers current system context information
-func (c *Collector) Collect() ai.Context {
-	ctx := ai.Context{
+// NewCollectorForDir creates a context collector for dir, for callers such as
+// the daemon that serve requests from other working directories
+func NewCollectorForDir(dir string) *Collector {
+	return &Collector{dir: dir}
+}
+
+// WithSources switches sources on or off by name, overriding their defaults
+func (c *Collector) WithSources(sources map[string]bool) *Collector {
+	c.sources = sources
+	return c
+}
+
+// WithInput sets the partial command being completed, for sources that
+// look at what the user is typing
+func (c *Collector) WithInput(input string) *Collector {
+	c.input = input
+	return c
+}
+
+// WithEnv describes the shell with the environment variables env instead of
+// the process's own; see EnvVars
+func (c *Collector) WithEnv(env map[string]string) *Collector {
+	c.env = env
+	return c
+}
+
+// Timing records how long a source ran and why it gave up, if it did
+type Timing struct {
+	Name     string        `json:"name"`
+	Duration time.Duration `json:"duration"`
+	Error    string        `json:"error,omitempty"`
+}
+
+// Collect gathers current system context information. The enabled sources run
+// concurrently, each within its own timeout and within ctx. Sources that fail
+// or run out of time keep what they collected before giving up; only those
+// that do not return shortly after their deadline are left out.
+func (c *Collector) Collect(ctx context.Context) (ai.Context, []Timing) {
+	result := ai.Context{
 		DateTime: time.Now(),
 		Platform: runtime.GOOS,
 	}
+	env := Options{Env: c.env}
 
 	// Get current user
-	if user := os.Getenv("USER"); user != "" {
-		ctx.User = user
+	if user := env.Getenv("USER"); user != "" {
+		result.User = user
 	} else if user := os.Getenv("USERNAME"); user != "" {
-		ctx.User = user
+		result.User = user
 	}
 
 	// Get current directory
-	if pwd, err := os.Getwd(); err == nil {
-		ctx.Directory = pwd
+	if c.dir != "" {
+		result.Directory = c.dir
+	} else if pwd, err := os.Getwd(); err == nil {
+		result.Directory = pwd
 	}
 
 	// Get shell
-	if shell := os.Getenv("SHELL"); shell != "" {
-		ctx.Shell = shell
+	if shell := env.Getenv("SHELL"); shell != "" {
+		result.Shell = shell
 	}
 
 	// Get terminal
-	if term := os.Getenv("TERM"); term != "" {
-		ctx.Terminal = term
+	if term := env.Getenv("TERM"); term != "" {
+		result.Terminal = term
 	}
 
-	// Check if in git repository and get branch
-	c.collectGitInfo(&ctx)
-
-	// Collect system information
-	c.collectSystemInfo(&ctx)
-
-	// Collect shell aliases
-	c.collectAliases(&ctx)
-
-	// Collect Kubernetes context
-	c.collectK8sContext(&ctx)
-
-	return ctx
-}
-
-// collectGitInfo checks if current directory is a git repository and gets branch info
-func (c *Collector) collectGitInfo(ctx *ai.Context) {
-	// Check if .git directory exists
-	if _, err := os.Stat(".git"); err == nil {
-		ctx.IsGitRepo = true
+	opts := Options{
+		Dir:     result.Directory,
+		Shell:   result.Shell,
+		Input:   c.input,
+		Sources: c.sources,
+		Env:     c.env,
+	}
 
-		// Get current branch
-		if cmd := exec.Command("git", "branch", "--show-current"); cmd != nil {
-			if output, err := cmd.Output(); err == nil {
-				ctx.GitBranch = strings.TrimSpace(string(output))
-			}
+	var sources []ContextSource
+	for _, source := range Sources() {
+		if source.Enabled(opts) {
+			sources = append(sources, source)
 		}
 	}
+
+	timings := runSources(ctx, sources, opts, &result)
+	return result, timings
 }
 
-// collectSystemInfo gathers system-specific information
-func (c *Collector) collectSystemInfo(ctx *ai.Context) {
-	switch runtime.GOOS {
-	case "darwin":
-		// macOS system info
-		if cmd := exec.Command("sw_vers"); cmd != nil {
-			if output, err := cmd.Output(); err == nil {
-				lines := strings.Split(string(output), "\n")
-				var version []string
-				for _, line := range lines {
-					if strings.Contains(line, ":") {
-						parts := strings.SplitN(line, ":", 2)
-						if len(parts) == 2 {
-							version = append(version, strings.TrimSpace(parts[1]))
-						}
-					}
-				}
-				ctx.System = fmt.Sprintf("macOS %s", strings.Join(version, " "))
-			}
-		}
-	case "linux":
-		// Linux system info
-		if data, err := os.ReadFile("/etc/os-release"); err == nil {
-			lines := strings.Split(string(data), "\n")
-			for _, line := range lines {
-				if strings.HasPrefix(line, "PRETTY_NAME=") {
-					name := strings.Trim(strings.TrimPrefix(line, "PRETTY_NAME="), `"`)
-					ctx.System = name
-					break
-				}
-			}
-		}
-	case "windows":
-		// Windows system info
-		ctx.System = "Windows"
-	default:
-		ctx.System = runtime.GOOS
+// Refresh re-runs the sources that depend on the input on top of a context
+// collected earlier for the same directory, so callers that cache context,
+// such as the daemon, still get input-specific results
+func (c *Collector) Refresh(ctx context.Context, cached ai.Context) (ai.Context, []Timing) {
+	result := cached
+	result.DateTime = time.Now()
+
+	opts := Options{
+		Dir:     result.Directory,
+		Shell:   result.Shell,
+		Input:   c.input,
+		Sources: c.sources,
+		Env:     c.env,
 	}
-}
 
-// collectAliases gathers shell aliases from various sources
-func (c *Collector) collectAliases(ctx *ai.Context) {
-	ctx.Aliases = make(map[string]string)
-
-	// Method 1: Get aliases from current shell session (interactive mode)
-	if strings.Contains(ctx.Shell, "zsh") {
-		// For zsh, we need to source the configuration and then run alias
-		cmd := exec.Command(ctx.Shell, "-i", "-c", "alias")
-		cmd.Env = os.Environ()
-		if output, err := cmd.Output(); err == nil {
-			c.parseAliases(string(output), ctx.Aliases)
-		}
-	} else if strings.Contains(ctx.Shell, "bash") {
-		// For bash, similar approach
-		cmd := exec.Command(ctx.Shell, "-i", "-c", "alias")
-		cmd.Env = os.Environ()
-		if output, err := cmd.Output(); err == nil {
-			c.parseAliases(string(output), ctx.Aliases)
+	var sources []ContextSource
+	for _, source := range Sources() {
+		if dependent, ok := source.(InputDependent); ok && dependent.UsesInput() && source.Enabled(opts) {
+			sources = append(sources, source)
 		}
 	}
 
-	// Method 2: Try sourcing rc files and getting aliases
-	if len(ctx.Aliases) == 0 {
-		var rcCommand string
-		if strings.Contains(ctx.Shell, "zsh") {
-			rcCommand = "source ~/.zshrc 2>/dev/null; alias 2>/dev/null"
-		} else if strings.Contains(ctx.Shell, "bash") {
-			rcCommand = "source ~/.bashrc 2>/dev/null; alias 2>/dev/null"
-		}
+	timings := runSources(ctx, sources, opts, &result)
+	return result, timings
+}
 
-		if rcCommand != "" {
-			cmd := exec.Command("sh", "-c", rcCommand)
-			cmd.Env = os.Environ()
-			if output, err := cmd.Output(); err == nil {
-				c.parseAliases(string(output), ctx.Aliases)
-			}
-		}
+// Stamp identifies state that changes without the directory changing: a
+// checkout, commit or staging rewrites HEAD or the index of the git
+// repository, and switching contexts or namespaces rewrites the kubeconfig.
+// Callers that cache context, such as the daemon, collect it again when the
+// stamp changes.
+func (c *Collector) Stamp() string {
+	dir := c.dir
+	if dir == "" {
+		dir, _ = os.Getwd()
 	}
 
-	// Method 3: Read common alias files
-	aliasFiles := []string{
-		os.Getenv("HOME") + "/.aliases",
-		os.Getenv("HOME") + "/.bash_aliases",
-		os.Getenv("HOME") + "/.zsh_aliases",
+	var paths []string
+	if root, gitDir, _ := findGitRoot(dir); root != "" {
+		paths = append(paths, gitDir, filepath.Join(gitDir, "HEAD"), filepath.Join(gitDir, "index"))
 	}
+	paths = append(paths, kubeconfigPaths(Options{Env: c.env})...)
 
-	for _, file := range aliasFiles {
-		c.readAliasFile(file, ctx.Aliases)
+	var stamp strings.Builder
+	for _, path := range paths {
+		stamp.WriteString(path)
+		if info, err := os.Stat(path); err == nil {
+			stamp.WriteString("@" + info.ModTime().Format(time.RFC3339Nano))
+		}
+		stamp.WriteByte(';')
 	}
+	return stamp.String()
 }
 
-// parseAliases parses alias command output
-func (c *Collector) parseAliases(output string, aliases map[string]string) {
-	lines := strings.Split(output, "\n")
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
-		if line == "" {
-			continue
-		}
+// runSources runs sources concurrently and merges what they collect into result
+func runSources(ctx context.Context, sources []ContextSource, opts Options, result *ai.Context) []Timing {
+	type outcome struct {
+		index   int
+		context ai.Context
+		timing  Timing
+	}
+	outcomes := make(chan outcome, len(sources))
 
-		if strings.HasPrefix(line, "alias ") {
-			// Parse format: alias name='command'
-			parts := strings.SplitN(strings.TrimPrefix(line, "alias "), "=", 2)
-			if len(parts) == 2 {
-				name := strings.TrimSpace(parts[0])
-				value := strings.Trim(strings.TrimSpace(parts[1]), "'\"")
-				aliases[name] = value
+	for i, source := range sources {
+		go func(i int, source ContextSource) {
+			started := time.Now()
+			sourceCtx, cancel := context.WithTimeout(ctx, source.Timeout())
+			defer cancel()
+
+			type collected struct {
+				context ai.Context
+				err     error
 			}
-		} else if strings.Contains(line, "=") && !strings.HasPrefix(line, "-e") {
-			// Handle lines that start directly with alias definitions (zsh -i -c alias format)
-			// Exclude lines starting with -e (options)
-			parts := strings.SplitN(line, "=", 2)
-			if len(parts) == 2 {
-				name := strings.TrimSpace(parts[0])
-				value := strings.Trim(strings.TrimSpace(parts[1]), "'\"")
-				if name != "" && value != "" && !strings.Contains(name, " ") {
-					aliases[name] = value
+			done := make(chan collected, 1)
+			go func() {
+				var out ai.Context
+				err := source.Collect(sourceCtx, opts, &out)
+				done <- collected{context: out, err: err}
+			}()
+
+			o := outcome{index: i, timing: Timing{Name: source.Name()}}
+			select {
+			case res := <-done:
+				o.context = res.context
+				if res.err != nil {
+					o.timing.Error = res.err.Error()
+				}
+			case <-sourceCtx.Done():
+				o.timing.Error = sourceCtx.Err().Error()
+				// A source stops at its deadline with the fields it set so far
+				select {
+				case res := <-done:
+					o.context = res.context
+				case <-time.After(partialGrace):
 				}
 			}
-		}
-	}
-}
-
-// readAliasFile reads aliases from a file
-func (c *Collector) readAliasFile(filename string, aliases map[string]string) {
-	file, err := os.Open(filename)
-	if err != nil {
-		return
-	}
-	defer file.Close()
-
-	scanner := bufio.NewScanner(file)
-	for scanner.Scan() {
-		line := strings.TrimSpace(scanner.Text())
-		if strings.HasPrefix(line, "alias ") {
-			c.parseAliases(line, aliases)
-		}
+			o.timing.Duration = time.Since(started)
+			outcomes <- o
+		}(i, source)
 	}
-}
 
-// collectK8sContext gathers Kubernetes environment information
-func (c *Collector) collectK8sContext(ctx *ai.Context) {
-	k8sCtx := &ai.K8sContext{
-		IsAvailable: false,
+	// Merge in source order so the result does not depend on scheduling
+	parts := make([]ai.Context, len(sources))
+	timings := make([]Timing, len(sources))
+	for range sources {
+		o := <-outcomes
+		parts[o.index] = o.context
+		timings[o.index] = o.timing
 	}
-
-	// Check if kubectl is available
-	if _, err := exec.LookPath("kubectl"); err != nil {
-		ctx.K8sContext = k8sCtx
-		return
+	for _, part := range parts {
+		mergeContext(result, part)
 	}
 
-	// Check if kubectl can connect to a cluster
-	if cmd := exec.Command("kubectl", "config", "current-context"); cmd != nil {
-		if output, err := cmd.Output(); err == nil {
-			k8sCtx.IsAvailable = true
-			k8sCtx.CurrentContext = strings.TrimSpace(string(output))
-		}
-	}
+	return timings
+}
 
-	if !k8sCtx.IsAvailable {
-		ctx.K8sContext = k8sCtx
-		return
-	}
+// mergeContext copies the fields set in part into dst. Maps such as Extras
+// are merged key by key and structs such as K8sContext field by field, so
+// several sources can contribute to them.
+func mergeContext(dst *ai.Context, part ai.Context) {
+	mergeFields(reflect.ValueOf(dst).Elem(), reflect.ValueOf(part))
+}
 
-	// Get current namespace
-	if cmd := exec.Command("kubectl", "config", "view", "--minify", "--output", "jsonpath={..namespace}"); cmd != nil {
-		if output, err := cmd.Output(); err == nil {
-			namespace := strings.TrimSpace(string(output))
-			if namespace == "" {
-				namespace = "default"
-			}
-			k8sCtx.CurrentNamespace = namespace
+// mergeFields copies the non-zero fields of the struct src into dst
+func mergeFields(dst, src reflect.Value) {
+	for i := 0; i < src.NumField(); i++ {
+		field := src.Field(i)
+		if field.IsZero() {
+			continue
 		}
-	}
 
-	// Get cluster info (simplified)
-	if cmd := exec.Command("kubectl", "cluster-info", "--request-timeout=2s"); cmd != nil {
-		if output, err := cmd.Output(); err == nil {
-			lines := strings.Split(string(output), "\n")
-			if len(lines) > 0 {
-				k8sCtx.ClusterInfo = strings.TrimSpace(lines[0])
+		target := dst.Field(i)
+		switch {
+		case field.Kind() == reflect.Map && !target.IsNil():
+			iter := field.MapRange()
+			for iter.Next() {
+				target.SetMapIndex(iter.Key(), iter.Value())
 			}
+		case field.Kind() == reflect.Pointer && field.Elem().Kind() == reflect.Struct && !target.IsNil():
+			mergeFields(target.Elem(), field.Elem())
+		default:
+			target.Set(field)
 		}
 	}
+}
 
-	ctx.K8sContext = k8sCtx
+// command creates a command that is killed when ctx is done
+func command(ctx context.Context, name string, args ...string) *exec.Cmd {
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.WaitDelay = waitDelay
+	return cmd
 }
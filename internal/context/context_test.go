@@ -0,0 +1,171 @@
+package context
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"supertab/internal/ai"
+)
+
+// fakeSource runs collect as its Collect method
+type fakeSource struct {
+	name    string
+	timeout time.Duration
+	collect func(ctx context.Context, out *ai.Context) error
+}
+
+func (s fakeSource) Name() string              { return s.name }
+func (s fakeSource) Enabled(opts Options) bool { return true }
+func (s fakeSource) Timeout() time.Duration    { return s.timeout }
+func (s fakeSource) Collect(ctx context.Context, opts Options, out *ai.Context) error {
+	return s.collect(ctx, out)
+}
+
+func TestRunSources(t *testing.T) {
+	sources := []ContextSource{
+		fakeSource{"fast", time.Second, func(ctx context.Context, out *ai.Context) error {
+			out.SetExtra("fast", true)
+			return nil
+		}},
+		fakeSource{"slow", 50 * time.Millisecond, func(ctx context.Context, out *ai.Context) error {
+			// Cheap fields first, then a scan that outlives the deadline
+			out.IsGitRepo = true
+			out.GitBranch = "main"
+			<-ctx.Done()
+			return ctx.Err()
+		}},
+		fakeSource{"failing", time.Second, func(ctx context.Context, out *ai.Context) error {
+			out.SetExtra("failing", "partial")
+			return errors.New("boom")
+		}},
+		fakeSource{"stuck", 50 * time.Millisecond, func(ctx context.Context, out *ai.Context) error {
+			time.Sleep(time.Second)
+			out.SetExtra("stuck", true)
+			return nil
+		}},
+	}
+
+	var result ai.Context
+	started := time.Now()
+	timings := runSources(context.Background(), sources, Options{}, &result)
+	if elapsed := time.Since(started); elapsed > 500*time.Millisecond {
+		t.Errorf("runSources waited %s for a stuck source", elapsed)
+	}
+
+	if !result.IsGitRepo || result.GitBranch != "main" {
+		t.Errorf("fields set before the deadline were dropped: %+v", result)
+	}
+	if result.Extras["fast"] != true || result.Extras["failing"] != "partial" {
+		t.Errorf("Extras = %v", result.Extras)
+	}
+	if _, ok := result.Extras["stuck"]; ok {
+		t.Error("a source that did not return was merged")
+	}
+
+	wantErrors := map[string]bool{"fast": false, "slow": true, "failing": true, "stuck": true}
+	for i, timing := range timings {
+		if timing.Name != sources[i].Name() {
+			t.Errorf("timing %d is for %s, want %s", i, timing.Name, sources[i].Name())
+		}
+		if (timing.Error != "") != wantErrors[timing.Name] {
+			t.Errorf("timing %s error = %q", timing.Name, timing.Error)
+		}
+	}
+}
+
+func TestMergeContext(t *testing.T) {
+	dst := ai.Context{
+		User:    "me",
+		Aliases: map[string]string{"gs": "git status"},
+		Git:     &ai.GitContext{Root: "/src/app", Staged: 2},
+		Extras:  map[string]any{"a": 1},
+	}
+
+	mergeContext(&dst, ai.Context{
+		Directory: "/src/app",
+		Aliases:   map[string]string{"ll": "ls -la"},
+		Git:       &ai.GitContext{Untracked: 3},
+		Extras:    map[string]any{"b": 2},
+	})
+	mergeContext(&dst, ai.Context{
+		IsGitRepo:  true,
+		K8sContext: &ai.K8sContext{CurrentNamespace: "api"},
+	})
+	mergeContext(&dst, ai.Context{
+		K8sContext: &ai.K8sContext{Pods: []string{"web-1"}},
+	})
+
+	tests := []struct {
+		name string
+		ok   bool
+	}{
+		{"plain field kept", dst.User == "me"},
+		{"plain field set", dst.Directory == "/src/app"},
+		{"bool set", dst.IsGitRepo},
+		{"maps merged", len(dst.Aliases) == 2 && dst.Aliases["ll"] == "ls -la"},
+		{"extras merged", len(dst.Extras) == 2},
+		{"struct fields merged", dst.Git.Root == "/src/app" && dst.Git.Staged == 2 && dst.Git.Untracked == 3},
+		{"nil struct set then merged", dst.K8sContext != nil && dst.K8sContext.CurrentNamespace == "api" && len(dst.K8sContext.Pods) == 1},
+	}
+	for _, tt := range tests {
+		if !tt.ok {
+			t.Errorf("%s: %+v", tt.name, dst)
+		}
+	}
+}
+
+func TestMergeContextKeepsZeroFields(t *testing.T) {
+	dst := ai.Context{GitBranch: "main", Files: []ai.DirListing{{Dir: "."}}}
+	mergeContext(&dst, ai.Context{})
+
+	if dst.GitBranch != "main" || len(dst.Files) != 1 {
+		t.Errorf("zero fields overwrote the context: %+v", dst)
+	}
+}
+
+func TestSourceEnabled(t *testing.T) {
+	opts := Options{Sources: map[string]bool{"docker": false, "k8s_resources": true}}
+
+	tests := []struct {
+		name string
+		def  bool
+		want bool
+	}{
+		{"docker", true, false},
+		{"k8s_resources", false, true},
+		{"git", true, true},
+		{"aws", false, false},
+	}
+	for _, tt := range tests {
+		if got := opts.SourceEnabled(tt.name, tt.def); got != tt.want {
+			t.Errorf("SourceEnabled(%q, %v) = %v, want %v", tt.name, tt.def, got, tt.want)
+		}
+	}
+}
+
+func TestRegistry(t *testing.T) {
+	names := make(map[string]bool)
+	var previous string
+	for _, source := range Sources() {
+		name := source.Name()
+		if name <= previous {
+			t.Errorf("Sources() not sorted: %q after %q", name, previous)
+		}
+		names[name] = true
+		previous = name
+	}
+	for _, name := range []string{"aliases", "git", "docker", "project", "k8s", "files"} {
+		if !names[name] {
+			t.Errorf("source %q is not registered", name)
+		}
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("registering a source twice did not panic")
+		}
+	}()
+	Register(gitSource{})
+}
@@ -0,0 +1,202 @@
+package context
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"supertab/internal/ai"
+
+	"gopkg.in/yaml.v3"
+)
+
+// dockerSource finds Dockerfiles and compose services in the project and lists
+// running containers when the Docker daemon is reachable
+type dockerSource struct{}
+
+const (
+	// dockerPsTimeout bounds docker ps within the source budget
+	dockerPsTimeout = 800 * time.Millisecond
+
+	// maxContainers caps the running containers reported
+	maxContainers = 20
+)
+
+// composeFiles are the compose file names in the order docker compose looks for them
+var composeFiles = []string{"compose.yaml", "compose.yml", "docker-compose.yaml", "docker-compose.yml"}
+
+func init() {
+	Register(dockerSource{})
+}
+
+// Name returns the source name
+func (dockerSource) Name() string { return "docker" }
+
+// Enabled reports whether the source is switched on
+func (dockerSource) Enabled(opts Options) bool { return opts.SourceEnabled("docker", true) }
+
+// Timeout returns the time budget of the source
+func (dockerSource) Timeout() time.Duration { return 1500 * time.Millisecond }
+
+// Collect gathers the Docker context
+func (dockerSource) Collect(ctx context.Context, opts Options, out *ai.Context) error {
+	docker := &ai.DockerContext{}
+
+	// Look in the directory and its parents up to the repository root, so
+	// subdirectories of a compose project still see its services
+	for _, dir := range projectDirs(opts.Dir) {
+		if docker.Dockerfiles == nil {
+			for _, name := range findDockerfiles(dir) {
+				docker.Dockerfiles = append(docker.Dockerfiles, relativePath(opts.Dir, filepath.Join(dir, name)))
+			}
+		}
+		if docker.ComposeFile == "" {
+			for _, name := range composeFiles {
+				path := filepath.Join(dir, name)
+				if services, err := composeServices(path); err == nil {
+					docker.ComposeFile = relativePath(opts.Dir, path)
+					docker.ComposeServices = services
+					break
+				}
+			}
+		}
+	}
+
+	docker.CurrentContext = dockerContext(opts)
+
+	if dockerReachable(opts, docker.CurrentContext) {
+		psCtx, cancel := context.WithTimeout(ctx, dockerPsTimeout)
+		defer cancel()
+		cmd := opts.command(psCtx, "docker", "ps", "--format", "{{.Names}}")
+		if output, err := cmd.Output(); err == nil {
+			for _, name := range strings.Fields(string(output)) {
+				if len(docker.Containers) >= maxContainers {
+					break
+				}
+				docker.Containers = append(docker.Containers, name)
+			}
+		}
+	}
+
+	if docker.Dockerfiles != nil || docker.ComposeFile != "" || docker.Containers != nil {
+		out.Docker = docker
+	}
+	return nil
+}
+
+// projectDirs returns dir followed by its parents up to the git repository
+// root, or just dir outside a repository
+func projectDirs(dir string) []string {
+	root, _, _ := findGitRoot(dir)
+	dirs := []string{dir}
+	if root == "" {
+		return dirs
+	}
+	for dir != root {
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			break
+		}
+		dir = parent
+		dirs = append(dirs, dir)
+	}
+	return dirs
+}
+
+// relativePath returns path relative to dir when possible, as the user would type it
+func relativePath(dir, path string) string {
+	if rel, err := filepath.Rel(dir, path); err == nil {
+		return rel
+	}
+	return path
+}
+
+// findDockerfiles returns the Dockerfiles in dir, such as Dockerfile,
+// Dockerfile.dev and api.Dockerfile
+func findDockerfiles(dir string) []string {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+
+	var files []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() {
+			continue
+		}
+		if name == "Dockerfile" || strings.HasPrefix(name, "Dockerfile.") || strings.HasSuffix(name, ".Dockerfile") {
+			files = append(files, name)
+		}
+	}
+	return files
+}
+
+// composeServices returns the sorted service names of a compose file
+func composeServices(path string) ([]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var compose struct {
+		Services map[string]yaml.Node `yaml:"services"`
+	}
+	if err := yaml.Unmarshal(data, &compose); err != nil {
+		return nil, err
+	}
+
+	services := make([]string, 0, len(compose.Services))
+	for name := range compose.Services {
+		services = append(services, name)
+	}
+	sort.Strings(services)
+	return services, nil
+}
+
+// dockerContext returns the active Docker context from DOCKER_CONTEXT or the
+// Docker CLI config, without running the docker CLI
+func dockerContext(opts Options) string {
+	if name := opts.Getenv("DOCKER_CONTEXT"); name != "" {
+		return name
+	}
+
+	configDir := opts.Getenv("DOCKER_CONFIG")
+	if configDir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return ""
+		}
+		configDir = filepath.Join(home, ".docker")
+	}
+
+	data, err := os.ReadFile(filepath.Join(configDir, "config.json"))
+	if err != nil {
+		return ""
+	}
+
+	var config struct {
+		CurrentContext string `json:"currentContext"`
+	}
+	if err := json.Unmarshal(data, &config); err != nil {
+		return ""
+	}
+	return config.CurrentContext
+}
+
+// dockerReachable reports whether docker ps is worth running: the CLI is
+// installed and, for the default local daemon, its socket exists
+func dockerReachable(opts Options, currentContext string) bool {
+	if _, err := opts.lookPath("docker"); err != nil {
+		return false
+	}
+	if opts.Getenv("DOCKER_HOST") != "" || (currentContext != "" && currentContext != "default") {
+		return true
+	}
+	_, err := os.Stat("/var/run/docker.sock")
+	return err == nil
+}
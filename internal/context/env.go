@@ -0,0 +1,107 @@
+package context
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// EnvVars are the environment variables the sources read. A client of the
+// daemon forwards them, so context describes the client's shell rather than
+// the one the daemon was started from.
+var EnvVars = []string{
+	"PATH", "SHELL", "TERM", "USER",
+	"AWS_PROFILE", "AWS_DEFAULT_PROFILE", "AWS_REGION", "AWS_DEFAULT_REGION", "AWS_CONFIG_FILE",
+	"CLOUDSDK_CONFIG", "CLOUDSDK_ACTIVE_CONFIG_NAME", "CLOUDSDK_CORE_PROJECT",
+	"AZURE_CONFIG_DIR",
+	"DOCKER_CONTEXT", "DOCKER_CONFIG", "DOCKER_HOST",
+	"KUBECONFIG",
+	"TF_DATA_DIR", "TF_WORKSPACE",
+}
+
+// CurrentEnv returns the variables of EnvVars set in the process's environment
+func CurrentEnv() map[string]string {
+	env := make(map[string]string)
+	for _, name := range EnvVars {
+		if value, ok := os.LookupEnv(name); ok {
+			env[name] = value
+		}
+	}
+	return env
+}
+
+// EnvKey returns a string identifying env, for caching context per environment
+func EnvKey(env map[string]string) string {
+	names := make([]string, 0, len(env))
+	for name := range env {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var key strings.Builder
+	for _, name := range names {
+		key.WriteString(name + "=" + env[name] + "\x00")
+	}
+	return key.String()
+}
+
+// Getenv returns the value of the environment variable name in the shell the
+// context is collected for
+func (o Options) Getenv(name string) string {
+	if o.Env != nil {
+		return o.Env[name]
+	}
+	return os.Getenv(name)
+}
+
+// firstEnv returns the first non-empty environment variable of names
+func (o Options) firstEnv(names ...string) string {
+	for _, name := range names {
+		if value := o.Getenv(name); value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// lookPath finds a program on the $PATH of the shell the context is for
+func (o Options) lookPath(name string) (string, error) {
+	if o.Env == nil || strings.Contains(name, "/") {
+		return exec.LookPath(name)
+	}
+	for _, dir := range filepath.SplitList(o.Env["PATH"]) {
+		path := filepath.Join(dir, name)
+		if info, err := os.Stat(path); err == nil && !info.IsDir() && info.Mode()&0o111 != 0 {
+			return path, nil
+		}
+	}
+	return "", &exec.Error{Name: name, Err: exec.ErrNotFound}
+}
+
+// command creates a command that is killed when ctx is done and runs in the
+// environment of the shell the context is for
+func (o Options) command(ctx context.Context, name string, args ...string) *exec.Cmd {
+	if o.Env == nil {
+		return command(ctx, name, args...)
+	}
+
+	cmd := command(ctx, name, args...)
+	cmd.Path, cmd.Err = o.lookPath(name)
+
+	forwarded := make(map[string]bool, len(EnvVars))
+	for _, name := range EnvVars {
+		forwarded[name] = true
+	}
+	for _, variable := range os.Environ() {
+		if name, _, _ := strings.Cut(variable, "="); !forwarded[name] {
+			cmd.Env = append(cmd.Env, variable)
+		}
+	}
+	for name, value := range o.Env {
+		cmd.Env = append(cmd.Env, name+"="+value)
+	}
+	return cmd
+}
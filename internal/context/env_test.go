@@ -0,0 +1,76 @@
+package context
+
+import (
+	"context"
+	"errors"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestOptionsGetenv(t *testing.T) {
+	t.Setenv("AWS_PROFILE", "process")
+	t.Setenv("AWS_DEFAULT_PROFILE", "process-default")
+
+	tests := []struct {
+		name    string
+		env     map[string]string
+		want    string
+		wantAny string
+	}{
+		{"process environment", nil, "process", "process"},
+		{"shell environment", map[string]string{"AWS_PROFILE": "prod"}, "prod", "prod"},
+		{"unset in the shell", map[string]string{"AWS_DEFAULT_PROFILE": "dev"}, "", "dev"},
+		{"empty shell environment", map[string]string{}, "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := Options{Env: tt.env}
+			if got := opts.Getenv("AWS_PROFILE"); got != tt.want {
+				t.Errorf("Getenv() = %q, want %q", got, tt.want)
+			}
+			if got := opts.firstEnv("AWS_PROFILE", "AWS_DEFAULT_PROFILE"); got != tt.wantAny {
+				t.Errorf("firstEnv() = %q, want %q", got, tt.wantAny)
+			}
+		})
+	}
+}
+
+func TestEnvKey(t *testing.T) {
+	a := EnvKey(map[string]string{"AWS_PROFILE": "prod", "KUBECONFIG": "/k"})
+	if b := EnvKey(map[string]string{"KUBECONFIG": "/k", "AWS_PROFILE": "prod"}); a != b {
+		t.Errorf("EnvKey() depends on map order: %q != %q", a, b)
+	}
+	if b := EnvKey(map[string]string{"AWS_PROFILE": "dev", "KUBECONFIG": "/k"}); a == b {
+		t.Errorf("EnvKey() = %q for different values", a)
+	}
+}
+
+func TestOptionsCommand(t *testing.T) {
+	shellBin := binDir(t, "deploy")
+	t.Setenv("AWS_PROFILE", "process")
+	t.Setenv("SUG_TEST_KEEP", "kept")
+
+	opts := Options{Env: map[string]string{"PATH": shellBin + ":/bin:/usr/bin", "TF_WORKSPACE": "staging"}}
+	if path, err := opts.lookPath("deploy"); err != nil || path != filepath.Join(shellBin, "deploy") {
+		t.Errorf("lookPath() = %q, %v, want the program on the shell's PATH", path, err)
+	}
+	if _, err := opts.lookPath("missing"); !errors.Is(err, exec.ErrNotFound) {
+		t.Errorf("lookPath() error = %v, want exec.ErrNotFound", err)
+	}
+
+	out, err := opts.command(context.Background(), "sh", "-c", `echo "$AWS_PROFILE|$TF_WORKSPACE|$SUG_TEST_KEEP"`).Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Forwarded variables the shell did not set are unset, the others kept
+	if got := strings.TrimSpace(string(out)); got != "|staging|kept" {
+		t.Errorf("command environment = %q, want %q", got, "|staging|kept")
+	}
+
+	if err := opts.command(context.Background(), "missing").Run(); !errors.Is(err, exec.ErrNotFound) {
+		t.Errorf("Run() of a program not on the shell's PATH: error = %v, want exec.ErrNotFound", err)
+	}
+}
@@ -0,0 +1,198 @@
+package context
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"supertab/internal/ai"
+	"supertab/internal/cache"
+)
+
+// executablesSource reports which programs are installed. Sending every name
+// on $PATH would swamp the prompt, so it lists the notable tools that are
+// installed plus everything in directories outside the system ones, where
+// users and teams put their own tools.
+type executablesSource struct{}
+
+// maxExecutables caps the names reported
+const maxExecutables = 150
+
+// systemPathDirs hold the operating system's and package managers' programs
+var systemPathDirs = map[string]bool{
+	"/bin":                            true,
+	"/sbin":                           true,
+	"/usr/bin":                        true,
+	"/usr/sbin":                       true,
+	"/usr/local/sbin":                 true,
+	"/opt/homebrew/bin":               true,
+	"/opt/homebrew/sbin":              true,
+	"/home/linuxbrew/.linuxbrew/bin":  true,
+	"/home/linuxbrew/.linuxbrew/sbin": true,
+	"/snap/bin":                       true,
+}
+
+// notableTools are programs the model tends to suggest, reported wherever
+// they are installed
+var notableTools = []string{
+	"ag", "ansible", "argocd", "aws", "az", "bat", "bun", "cargo", "consul", "curl",
+	"delta", "deno", "direnv", "docker", "docker-compose", "eza", "exa", "fd", "flux", "fzf",
+	"gcloud", "gh", "glab", "go", "gradle", "helm", "helmfile", "htop", "http", "httpie",
+	"istioctl", "java", "jq", "just", "k9s", "kind", "kubectl", "kubectx", "kubens", "kustomize",
+	"lazygit", "make", "minikube", "mvn", "nc", "nerdctl", "node", "nomad", "npm", "nvim",
+	"packer", "pip", "pip3", "pnpm", "podman", "poetry", "psql", "pulumi", "python", "python3",
+	"redis-cli", "rg", "rsync", "rustc", "skaffold", "sops", "ssh", "stern", "task", "terraform",
+	"terragrunt", "tilt", "tmux", "tofu", "uv", "vault", "vim", "wget", "yarn", "yq",
+	"zoxide",
+}
+
+// versionedName matches programs installed once per version, such as
+// python3.12 or pip3.11, which the unversioned name already covers
+var versionedName = regexp.MustCompile(`[0-9]\.[0-9]`)
+
+// pathDir is the cached listing of a $PATH directory
+type pathDir struct {
+	ModTime time.Time `json:"mod_time"`
+	Names   []string  `json:"names"`
+}
+
+func init() {
+	Register(executablesSource{})
+}
+
+// Name returns the source name
+func (executablesSource) Name() string { return "executables" }
+
+// Enabled reports whether the source is switched on
+func (executablesSource) Enabled(opts Options) bool { return opts.SourceEnabled("executables", true) }
+
+// Timeout returns the time budget of the source. Listings are cached, so only
+// a directory that changed is read again.
+func (executablesSource) Timeout() time.Duration { return 500 * time.Millisecond }
+
+// Collect lists the installed programs
+func (executablesSource) Collect(ctx context.Context, opts Options, out *ai.Context) error {
+	dirs := pathDirs(opts)
+	listings := listPathDirs(ctx, dirs)
+
+	installed := make(map[string]bool)
+	var user []string
+	for _, dir := range dirs {
+		for _, name := range listings[dir].Names {
+			if !installed[name] && !systemPathDirs[dir] && !versionedName.MatchString(name) {
+				user = append(user, name)
+			}
+			installed[name] = true
+		}
+	}
+
+	var names []string
+	listed := make(map[string]bool)
+	for _, tool := range notableTools {
+		if installed[tool] {
+			names = append(names, tool)
+			listed[tool] = true
+		}
+	}
+	sort.Strings(user)
+	for _, name := range user {
+		if len(names) >= maxExecutables {
+			break
+		}
+		if !listed[name] {
+			names = append(names, name)
+		}
+	}
+
+	if len(names) > 0 {
+		out.Commands = &ai.CommandContext{Executables: names}
+	}
+	return ctx.Err()
+}
+
+// pathDirs returns the absolute directories on $PATH, without duplicates
+func pathDirs(opts Options) []string {
+	var dirs []string
+	seen := make(map[string]bool)
+	for _, dir := range filepath.SplitList(opts.Getenv("PATH")) {
+		if !filepath.IsAbs(dir) {
+			continue
+		}
+		dir = filepath.Clean(dir)
+		if !seen[dir] {
+			seen[dir] = true
+			dirs = append(dirs, dir)
+		}
+	}
+	return dirs
+}
+
+// listPathDirs returns the executables in each of dirs. Listings are cached
+// on disk and read again only when the modification time of a directory
+// changed, which happens whenever a program is installed or removed. Once ctx
+// is done, directories that would need reading are left out, but the
+// listings read until then are still cached.
+func listPathDirs(ctx context.Context, dirs []string) map[string]pathDir {
+	cachePath := filepath.Join(cache.DefaultDir(), "executables.json")
+
+	cached := make(map[string]pathDir)
+	if data, err := os.ReadFile(cachePath); err == nil {
+		json.Unmarshal(data, &cached)
+	}
+
+	listings := make(map[string]pathDir, len(dirs))
+	changed := false
+	for _, dir := range dirs {
+		info, err := os.Stat(dir)
+		if err != nil || !info.IsDir() {
+			continue
+		}
+		if listing, ok := cached[dir]; ok && listing.ModTime.Equal(info.ModTime()) {
+			listings[dir] = listing
+			continue
+		}
+		if ctx.Err() != nil {
+			// Out of time: the directories scanned so far are still saved,
+			// and a later run lists this one
+			continue
+		}
+		listings[dir] = pathDir{ModTime: info.ModTime(), Names: listExecutables(dir)}
+		changed = true
+	}
+
+	if changed || len(listings) != len(cached) {
+		if data, err := json.Marshal(listings); err == nil {
+			if os.MkdirAll(filepath.Dir(cachePath), 0o700) == nil {
+				cache.WriteFileAtomic(cachePath, data)
+			}
+		}
+	}
+	return listings
+}
+
+// listExecutables returns the names of the executable files in dir
+func listExecutables(dir string) []string {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+
+	var names []string
+	for _, entry := range entries {
+		if strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		// Follow symlinks, which is how most package managers install programs
+		info, err := os.Stat(filepath.Join(dir, entry.Name()))
+		if err != nil || info.IsDir() || info.Mode()&0o111 == 0 {
+			continue
+		}
+		names = append(names, entry.Name())
+	}
+	return names
+}
@@ -0,0 +1,72 @@
+package context
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"supertab/internal/ai"
+)
+
+// binDir creates a directory holding executables named names
+func binDir(t *testing.T, names ...string) string {
+	dir := t.TempDir()
+	for _, name := range names {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("#!/bin/sh\n"), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	os.WriteFile(filepath.Join(dir, "README"), []byte("not a program\n"), 0o644)
+	os.WriteFile(filepath.Join(dir, ".hidden"), []byte("#!/bin/sh\n"), 0o755)
+	return dir
+}
+
+func TestListPathDirs(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	tools := binDir(t, "deploy", "kubectl")
+	scripts := binDir(t, "backup")
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	tests := []struct {
+		name string
+		ctx  context.Context
+		dirs []string
+		want map[string][]string
+	}{
+		{"nothing cached, no time", cancelled, []string{tools}, map[string][]string{}},
+		{"scanned", context.Background(), []string{tools}, map[string][]string{tools: {"deploy", "kubectl"}}},
+		{"cached listing used without time", cancelled, []string{scripts, tools}, map[string][]string{tools: {"deploy", "kubectl"}}},
+		{"new directory scanned", context.Background(), []string{scripts, tools}, map[string][]string{tools: {"deploy", "kubectl"}, scripts: {"backup"}}},
+		{"missing directory skipped", context.Background(), []string{filepath.Join(tools, "missing"), scripts}, map[string][]string{scripts: {"backup"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := make(map[string][]string)
+			for dir, listing := range listPathDirs(tt.ctx, tt.dirs) {
+				got[dir] = listing.Names
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("listPathDirs() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExecutablesSourceCollect(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	t.Setenv("PATH", binDir(t, "kubectl", "deploy", "python3.12")+string(os.PathListSeparator)+binDir(t, "backup", "deploy"))
+
+	var out ai.Context
+	if err := (executablesSource{}).Collect(context.Background(), Options{}, &out); err != nil {
+		t.Fatal(err)
+	}
+	// Notable tools come first, then the rest sorted, without versioned names
+	want := []string{"kubectl", "backup", "deploy"}
+	if out.Commands == nil || !reflect.DeepEqual(out.Commands.Executables, want) {
+		t.Errorf("Executables = %+v, want %v", out.Commands, want)
+	}
+}
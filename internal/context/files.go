@@ -0,0 +1,246 @@
+package context
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"supertab/internal/ai"
+)
+
+// filesSource lists the working directory and the directory a partial path in
+// the input points at, so path arguments complete to names that exist.
+// Switch it off with context.sources.files to keep file names out of requests.
+type filesSource struct{}
+
+const (
+	// maxListingEntries caps the entries of one listing
+	maxListingEntries = 100
+
+	// maxListingBytes caps the total length of the names in one listing
+	maxListingBytes = 4096
+)
+
+func init() {
+	Register(filesSource{})
+}
+
+// Name returns the source name
+func (filesSource) Name() string { return "files" }
+
+// Enabled reports whether the source is switched on
+func (filesSource) Enabled(opts Options) bool { return opts.SourceEnabled("files", true) }
+
+// Timeout returns the time budget of the source
+func (filesSource) Timeout() time.Duration { return 500 * time.Millisecond }
+
+// UsesInput reports that the listing follows the partial path being typed
+func (filesSource) UsesInput() bool { return true }
+
+// Collect lists the working directory and, for input such as "vim src/ha",
+// the entries of src/ starting with "ha"
+func (filesSource) Collect(ctx context.Context, opts Options, out *ai.Context) error {
+	cwd, err := listDir(ctx, opts.Dir, ".", "", false)
+	if err != nil {
+		return err
+	}
+	out.Files = []ai.DirListing{cwd}
+
+	dir, prefix, ok := InputPath(opts.Input)
+	if !ok {
+		return nil
+	}
+	// A bare word refers to the working directory, which only needs listing
+	// again when the full listing was cut short
+	if dir == "" && !cwd.Truncated {
+		return nil
+	}
+
+	path := expandHome(dir)
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(opts.Dir, path)
+	}
+	display := dir
+	if display == "" {
+		display = "."
+	}
+
+	listing, err := listDir(ctx, path, display, prefix, strings.HasPrefix(prefix, "."))
+	if err == nil && len(listing.Entries) > 0 {
+		out.Files = append(out.Files, listing)
+	}
+	return nil
+}
+
+// InputPath splits the last word of a partial command into the directory it
+// points at and the name prefix typed so far: "vim src/ha" gives "src/" and
+// "ha", "cd inte" gives "" and "inte". Quotes and escapes are removed, so
+// `vim "my fi` gives "my fi". It reports false when the input ends in a
+// space, so no word is being typed.
+func InputPath(input string) (dir, prefix string, ok bool) {
+	word, _, ok := LastWord(input)
+	if !ok {
+		return "", "", false
+	}
+
+	if i := strings.LastIndex(word, "/"); i >= 0 {
+		return word[:i+1], word[i+1:], true
+	}
+	if word == "~" {
+		return "~/", "", true
+	}
+	return "", word, true
+}
+
+// LastWord returns the last word of a partial command the way the shell reads
+// it, without quotes and backslash escapes, and the quote character still
+// open at the end of the input, if any. It reports false when no word but the
+// command name is being typed.
+func LastWord(input string) (word string, quote byte, ok bool) {
+	var current strings.Builder
+	inWord := false
+	words := 0
+	for i := 0; i < len(input); i++ {
+		c := input[i]
+		switch {
+		case quote == '\'':
+			if c == '\'' {
+				quote = 0
+			} else {
+				current.WriteByte(c)
+			}
+		case quote == '"':
+			if c == '"' {
+				quote = 0
+			} else if c == '\\' && i+1 < len(input) && strings.IndexByte("\"\\$`", input[i+1]) >= 0 {
+				i++
+				current.WriteByte(input[i])
+			} else {
+				current.WriteByte(c)
+			}
+		case c == ' ' || c == '\t' || c == '\n':
+			if inWord {
+				words++
+				inWord = false
+				current.Reset()
+			}
+		case c == '\\':
+			inWord = true
+			if i+1 < len(input) {
+				i++
+				current.WriteByte(input[i])
+			}
+		case c == '\'' || c == '"':
+			inWord = true
+			quote = c
+		default:
+			inWord = true
+			current.WriteByte(c)
+		}
+	}
+
+	if !inWord || words == 0 {
+		return "", quote, false
+	}
+	return current.String(), quote, true
+}
+
+// expandHome replaces a leading ~ with the home directory
+func expandHome(path string) string {
+	if path == "~" || strings.HasPrefix(path, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			return filepath.Join(home, strings.TrimPrefix(path, "~"))
+		}
+	}
+	return path
+}
+
+// listDir lists the entries of path starting with prefix, leaving out .git,
+// hidden entries unless showHidden is set, and names ignored by git
+func listDir(ctx context.Context, path, display, prefix string, showHidden bool) (ai.DirListing, error) {
+	listing := ai.DirListing{Dir: display, Prefix: prefix}
+
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return listing, err
+	}
+
+	var names []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if name == ".git" || !strings.HasPrefix(name, prefix) {
+			continue
+		}
+		if strings.HasPrefix(name, ".") && !showHidden {
+			continue
+		}
+		if entry.IsDir() {
+			name += "/"
+		}
+		names = append(names, name)
+	}
+
+	ignored := gitIgnored(ctx, path, names)
+
+	// Directories first, as they are the usual target of cd and path completion
+	sort.SliceStable(names, func(i, j int) bool {
+		return strings.HasSuffix(names[i], "/") && !strings.HasSuffix(names[j], "/")
+	})
+
+	size := 0
+	for _, name := range names {
+		if ignored[name] {
+			continue
+		}
+		if len(listing.Entries) >= maxListingEntries || size+len(name) > maxListingBytes {
+			listing.Truncated = true
+			break
+		}
+		listing.Entries = append(listing.Entries, name)
+		size += len(name) + 1
+	}
+
+	return listing, nil
+}
+
+// gitIgnored returns the names in dir that git ignores. Outside a repository,
+// or without git, nothing is ignored.
+func gitIgnored(ctx context.Context, dir string, names []string) map[string]bool {
+	ignored := make(map[string]bool)
+	if len(names) == 0 {
+		return ignored
+	}
+	if root, _, _ := findGitRoot(dir); root == "" {
+		return ignored
+	}
+
+	var input bytes.Buffer
+	for _, name := range names {
+		input.WriteString(name)
+		input.WriteByte(0)
+	}
+
+	cmd := command(ctx, "git", "check-ignore", "--stdin", "-z")
+	cmd.Dir = dir
+	cmd.Stdin = &input
+	output, err := cmd.Output()
+
+	// check-ignore exits with 1 when no name is ignored
+	var exitErr *exec.ExitError
+	if err != nil && !(errors.As(err, &exitErr) && exitErr.ExitCode() == 1) {
+		return ignored
+	}
+
+	for _, name := range bytes.Split(output, []byte{0}) {
+		if len(name) > 0 {
+			ignored[string(name)] = true
+		}
+	}
+	return ignored
+}
@@ -0,0 +1,53 @@
+package context
+
+import "testing"
+
+func TestInputPath(t *testing.T) {
+	tests := []struct {
+		input      string
+		wantDir    string
+		wantPrefix string
+		wantOK     bool
+	}{
+		{"vim src/ha", "src/", "ha", true},
+		{"cd inte", "", "inte", true},
+		{"cd ~", "~/", "", true},
+		{`vim "my fi`, "", "my fi", true},
+		{`vim 'a b`, "", "a b", true},
+		{`vim my\ fi`, "", "my fi", true},
+		{`vim "a\"b`, "", `a"b`, true},
+		{`vim "a\nb`, "", `a\nb`, true}, // only some escapes work in double quotes
+		{`vim 'a\'`, "", `a\`, true},    // none work in single quotes
+		{`vim "src dir"/ma`, "src dir/", "ma", true},
+		{"vim ", "", "", false},
+		{`vim "a b" `, "", "", false},
+		{"vim", "", "", false},
+	}
+
+	for _, tt := range tests {
+		dir, prefix, ok := InputPath(tt.input)
+		if dir != tt.wantDir || prefix != tt.wantPrefix || ok != tt.wantOK {
+			t.Errorf("InputPath(%q) = %q, %q, %v, want %q, %q, %v", tt.input, dir, prefix, ok, tt.wantDir, tt.wantPrefix, tt.wantOK)
+		}
+	}
+}
+
+func TestLastWordQuote(t *testing.T) {
+	tests := []struct {
+		input string
+		want  byte
+	}{
+		{"vim my", 0},
+		{`vim "my`, '"'},
+		{`vim 'my`, '\''},
+		{`vim "my"`, 0},
+		{`vim "it's`, '"'},
+		{`vim 'say "hi`, '\''},
+	}
+
+	for _, tt := range tests {
+		if _, quote, _ := LastWord(tt.input); quote != tt.want {
+			t.Errorf("LastWord(%q) quote = %q, want %q", tt.input, quote, tt.want)
+		}
+	}
+}
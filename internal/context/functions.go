@@ -0,0 +1,123 @@
+package context
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+
+	"supertab/internal/ai"
+)
+
+// functionsSource collects user-defined shell functions, zsh global aliases
+// and fish abbreviations
+type functionsSource struct{}
+
+// maxFunctions caps the function names reported
+const maxFunctions = 50
+
+// ignoredFunctionPrefixes mark functions defined by completion systems,
+// frameworks and prompts rather than by the user
+var ignoredFunctionPrefixes = []string{"_", "+", "-", "(", "omz", "prompt_", "zle-", "p10k", "powerlevel", "fish_", "quote", "dequote"}
+
+func init() {
+	Register(functionsSource{})
+}
+
+// Name returns the source name
+func (functionsSource) Name() string { return "functions" }
+
+// Enabled reports whether the source is switched on
+func (functionsSource) Enabled(opts Options) bool { return opts.SourceEnabled("functions", true) }
+
+// Timeout returns the time budget of the source. Like aliases, this waits
+// for an interactive shell.
+func (functionsSource) Timeout() time.Duration { return 1500 * time.Millisecond }
+
+// Collect asks the user's shell for its functions
+func (functionsSource) Collect(ctx context.Context, opts Options, out *ai.Context) error {
+	output, err := shellDefinitions(ctx, opts)
+	if err != nil {
+		return err
+	}
+	commands := &ai.CommandContext{Functions: userFunctions(output.functions)}
+
+	switch {
+	case strings.Contains(opts.Shell, "zsh"):
+		globalAliases := make(map[string]string)
+		parseAliases(output.extras, globalAliases)
+		if len(globalAliases) > 0 {
+			commands.GlobalAliases = globalAliases
+		}
+	case strings.Contains(opts.Shell, "fish"):
+		if abbreviations := parseAbbreviations(output.extras); len(abbreviations) > 0 {
+			commands.Abbreviations = abbreviations
+		}
+	}
+
+	if len(commands.Functions) > 0 || commands.GlobalAliases != nil || commands.Abbreviations != nil {
+		out.Commands = commands
+	}
+	return nil
+}
+
+// userFunctions returns the sorted function names in output, one per line,
+// leaving out helper functions
+func userFunctions(output string) []string {
+	var names []string
+	seen := make(map[string]bool)
+	for _, line := range strings.Split(output, "\n") {
+		name := strings.TrimSpace(line)
+		if name == "" || seen[name] || strings.ContainsAny(name, " \t") || ignoredFunction(name) {
+			continue
+		}
+		seen[name] = true
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+	if len(names) > maxFunctions {
+		names = names[:maxFunctions]
+	}
+	return names
+}
+
+// ignoredFunction reports whether a function is a helper rather than a command
+func ignoredFunction(name string) bool {
+	for _, prefix := range ignoredFunctionPrefixes {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseAbbreviations parses the output of fish's `abbr --show`, whose lines
+// look like `abbr -a --position anywhere -- gco 'git checkout'`
+func parseAbbreviations(output string) map[string]string {
+	abbreviations := make(map[string]string)
+	for _, line := range strings.Split(output, "\n") {
+		if !strings.HasPrefix(line, "abbr ") {
+			continue
+		}
+		_, definition, ok := strings.Cut(line, " -- ")
+		if !ok {
+			continue
+		}
+		name, expansion, ok := strings.Cut(strings.TrimSpace(definition), " ")
+		if !ok {
+			continue
+		}
+		abbreviations[unquoteFish(name)] = unquoteFish(strings.TrimSpace(expansion))
+	}
+	return abbreviations
+}
+
+// unquoteFish removes the quoting fish adds to a word it prints
+func unquoteFish(word string) string {
+	if len(word) >= 2 && word[0] == '\'' && word[len(word)-1] == '\'' {
+		word = word[1 : len(word)-1]
+		return strings.NewReplacer(`\'`, `'`, `\\`, `\`).Replace(word)
+	}
+	return word
+}
@@ -0,0 +1,243 @@
+package context
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"supertab/internal/ai"
+)
+
+// gitSource describes the git repository containing the directory: branch,
+// working tree status, upstream, stashes and any operation in progress
+type gitSource struct{}
+
+func init() {
+	Register(gitSource{})
+}
+
+// Name returns the source name
+func (gitSource) Name() string { return "git" }
+
+// Enabled reports whether the source is switched on
+func (gitSource) Enabled(opts Options) bool { return opts.SourceEnabled("git", true) }
+
+// Timeout returns the time budget of the source
+func (gitSource) Timeout() time.Duration { return time.Second }
+
+// gitUntrackedBudget is the part of the budget a status scan listing
+// untracked files may take
+const gitUntrackedBudget = 600 * time.Millisecond
+
+// gitOperations maps files in the git directory to the operation they mark,
+// in the order git itself reports them
+var gitOperations = []struct {
+	file      string
+	operation string
+}{
+	{"rebase-merge", "rebase"},
+	{"rebase-apply", "rebase"},
+	{"MERGE_HEAD", "merge"},
+	{"CHERRY_PICK_HEAD", "cherry-pick"},
+	{"REVERT_HEAD", "revert"},
+	{"BISECT_LOG", "bisect"},
+}
+
+// Collect finds the repository root and gathers its state
+func (gitSource) Collect(ctx context.Context, opts Options, out *ai.Context) error {
+	root, gitDir, worktree := findGitRoot(opts.Dir)
+	if root == "" {
+		return nil
+	}
+
+	git := &ai.GitContext{
+		Root:     root,
+		Worktree: worktree,
+	}
+	out.IsGitRepo = true
+	out.Git = git
+
+	for _, op := range gitOperations {
+		if _, err := os.Stat(filepath.Join(gitDir, op.file)); err == nil {
+			git.Operation = op.operation
+			break
+		}
+	}
+
+	git.Stashes = countLines(filepath.Join(gitCommonDir(gitDir), "logs", "refs", "stash"))
+
+	// The branch and last commit are cheap and come first, so they survive a
+	// status scan that runs out of time in a large repository
+	cmd := opts.command(ctx, "git", "symbolic-ref", "--quiet", "--short", "HEAD")
+	cmd.Dir = opts.Dir
+	if output, err := cmd.Output(); err == nil {
+		out.GitBranch = strings.TrimSpace(string(output))
+	} else if ctx.Err() == nil {
+		git.Detached = true
+	}
+
+	cmd = opts.command(ctx, "git", "log", "-1", "--format=%s")
+	cmd.Dir = opts.Dir
+	if output, err := cmd.Output(); err == nil {
+		git.LastCommit = strings.TrimSpace(string(output))
+	}
+
+	// Upstream and file counts come from one status call. Listing untracked
+	// files is the slow part in large trees, so when that does not finish in
+	// its share of the budget the scan is repeated without them.
+	statusCtx, cancel := context.WithTimeout(ctx, gitUntrackedBudget)
+	output, err := gitStatus(statusCtx, opts, "normal")
+	cancel()
+	if err != nil && statusCtx.Err() != nil && ctx.Err() == nil {
+		output, err = gitStatus(ctx, opts, "no")
+	}
+	if err == nil {
+		parseGitStatus(output, git)
+	}
+
+	return nil
+}
+
+// gitStatus runs `git status --porcelain=v2 --branch` listing untracked files
+// as given by untracked ("normal" or "no")
+func gitStatus(ctx context.Context, opts Options, untracked string) ([]byte, error) {
+	cmd := opts.command(ctx, "git", "status", "--porcelain=v2", "--branch", "--untracked-files="+untracked)
+	cmd.Dir = opts.Dir
+	return cmd.Output()
+}
+
+// findGitRoot walks up from dir to the directory holding .git. It returns the
+// root, the git directory and whether the checkout is a linked worktree.
+func findGitRoot(dir string) (root, gitDir string, worktree bool) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", "", false
+	}
+
+	for {
+		dotGit := filepath.Join(dir, ".git")
+		if info, err := os.Stat(dotGit); err == nil {
+			if info.IsDir() {
+				return dir, dotGit, false
+			}
+			// Worktrees and submodules have a .git file pointing at the git directory
+			if target := readGitDirFile(dotGit); target != "" {
+				return dir, target, strings.Contains(filepath.ToSlash(target), "/worktrees/")
+			}
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", "", false
+		}
+		dir = parent
+	}
+}
+
+// GitRepo returns the root of the repository containing dir and its current
+// branch, read from the git directory without running git. Both are empty
+// outside a repository; branch is empty on a detached HEAD.
+func GitRepo(dir string) (root, branch string) {
+	root, gitDir, _ := findGitRoot(dir)
+	if root == "" {
+		return "", ""
+	}
+	data, err := os.ReadFile(filepath.Join(gitDir, "HEAD"))
+	if err != nil {
+		return root, ""
+	}
+	branch, ok := strings.CutPrefix(strings.TrimSpace(string(data)), "ref: refs/heads/")
+	if !ok {
+		return root, ""
+	}
+	return root, branch
+}
+
+// readGitDirFile resolves the "gitdir: <path>" line of a .git file
+func readGitDirFile(path string) string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	target, ok := strings.CutPrefix(strings.TrimSpace(string(data)), "gitdir:")
+	if !ok {
+		return ""
+	}
+	target = strings.TrimSpace(target)
+	if !filepath.IsAbs(target) {
+		target = filepath.Join(filepath.Dir(path), target)
+	}
+	return target
+}
+
+// gitCommonDir returns the directory shared by all worktrees of a repository
+func gitCommonDir(gitDir string) string {
+	data, err := os.ReadFile(filepath.Join(gitDir, "commondir"))
+	if err != nil {
+		return gitDir
+	}
+	common := strings.TrimSpace(string(data))
+	if !filepath.IsAbs(common) {
+		common = filepath.Join(gitDir, common)
+	}
+	return common
+}
+
+// countLines returns the number of lines in a file, or 0 if it cannot be read
+func countLines(path string) int {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0
+	}
+	return bytes.Count(data, []byte("\n"))
+}
+
+// parseGitStatus fills git from `git status --porcelain=v2 --branch` output
+func parseGitStatus(output []byte, git *ai.GitContext) {
+	scanner := bufio.NewScanner(bytes.NewReader(output))
+	for scanner.Scan() {
+		line := scanner.Text()
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+
+		switch fields[0] {
+		case "#":
+			if len(fields) < 3 {
+				continue
+			}
+			switch fields[1] {
+			case "branch.head":
+				git.Detached = fields[2] == "(detached)"
+			case "branch.upstream":
+				git.Upstream = fields[2]
+			case "branch.ab":
+				if len(fields) >= 4 {
+					git.Ahead, _ = strconv.Atoi(strings.TrimPrefix(fields[2], "+"))
+					git.Behind, _ = strconv.Atoi(strings.TrimPrefix(fields[3], "-"))
+				}
+			}
+		case "1", "2":
+			// Ordinary and renamed entries carry the staged and unstaged state as XY
+			if len(fields) < 2 || len(fields[1]) != 2 {
+				continue
+			}
+			if fields[1][0] != '.' {
+				git.Staged++
+			}
+			if fields[1][1] != '.' {
+				git.Unstaged++
+			}
+		case "u":
+			git.Conflicted++
+		case "?":
+			git.Untracked++
+		}
+	}
+}
@@ -0,0 +1,181 @@
+package context
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"supertab/internal/ai"
+)
+
+func TestParseGitStatus(t *testing.T) {
+	tests := []struct {
+		name   string
+		output string
+		want   ai.GitContext
+	}{
+		{
+			name: "clean with upstream",
+			output: `# branch.oid 1234567890abcdef
+# branch.head main
+# branch.upstream origin/main
+# branch.ab +2 -1
+`,
+			want: ai.GitContext{Upstream: "origin/main", Ahead: 2, Behind: 1},
+		},
+		{
+			name: "changes",
+			output: `# branch.oid 1234567890abcdef
+# branch.head feature/x
+1 M. N... 100644 100644 100644 abc abc src/a.go
+1 .M N... 100644 100644 100644 abc abc src/b.go
+1 MM N... 100644 100644 100644 abc abc src/c.go
+2 R. N... 100644 100644 100644 abc abc R100 new.go	old.go
+u UU N... 100644 100644 100644 100644 abc abc abc conflict.go
+? notes.txt
+? tmp/
+`,
+			want: ai.GitContext{Staged: 3, Unstaged: 2, Conflicted: 1, Untracked: 2},
+		},
+		{
+			name: "detached",
+			output: `# branch.oid 1234567890abcdef
+# branch.head (detached)
+`,
+			want: ai.GitContext{Detached: true},
+		},
+		{
+			name:   "initial commit",
+			output: "# branch.oid (initial)\n# branch.head main\n? README.md\n",
+			want:   ai.GitContext{Untracked: 1},
+		},
+		{
+			name:   "malformed",
+			output: "#\n# branch.ab\n1\n1 XYZ\n\n",
+			want:   ai.GitContext{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got ai.GitContext
+			parseGitStatus([]byte(tt.output), &got)
+			if got != tt.want {
+				t.Errorf("parseGitStatus() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+// gitRepo creates a repository with one commit on branch main
+func gitRepo(t *testing.T) string {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+
+	dir := t.TempDir()
+	git(t, dir, "init", "--quiet", "--initial-branch=main")
+	if err := os.WriteFile(filepath.Join(dir, "a.txt"), []byte("a\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	git(t, dir, "add", "a.txt")
+	git(t, dir, "-c", "user.name=t", "-c", "user.email=t@example.com", "commit", "--quiet", "-m", "first commit")
+	return dir
+}
+
+// git runs a git command in dir
+func git(t *testing.T, dir string, args ...string) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	if output, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("git %v: %v: %s", args, err, output)
+	}
+}
+
+func TestGitSourceCollect(t *testing.T) {
+	dir := gitRepo(t)
+	sub := filepath.Join(dir, "sub")
+	if err := os.Mkdir(sub, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	os.WriteFile(filepath.Join(dir, "a.txt"), []byte("changed\n"), 0o644)
+	os.WriteFile(filepath.Join(sub, "new.txt"), []byte("new\n"), 0o644)
+
+	var out ai.Context
+	if err := (gitSource{}).Collect(context.Background(), Options{Dir: sub}, &out); err != nil {
+		t.Fatal(err)
+	}
+
+	if !out.IsGitRepo || out.GitBranch != "main" || out.Git == nil {
+		t.Fatalf("Collect() = %+v", out)
+	}
+	want := ai.GitContext{Root: dir, Unstaged: 1, Untracked: 1, LastCommit: "first commit"}
+	if *out.Git != want {
+		t.Errorf("Git = %+v, want %+v", *out.Git, want)
+	}
+
+	git(t, dir, "checkout", "--quiet", "--detach")
+	out = ai.Context{}
+	(gitSource{}).Collect(context.Background(), Options{Dir: dir}, &out)
+	if out.GitBranch != "" || !out.Git.Detached {
+		t.Errorf("detached HEAD: branch %q, git %+v", out.GitBranch, out.Git)
+	}
+}
+
+func TestGitSourceCollectCancelled(t *testing.T) {
+	dir := gitRepo(t)
+
+	// Without time to run git, the repository and its root are still reported
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	var out ai.Context
+	(gitSource{}).Collect(ctx, Options{Dir: dir}, &out)
+
+	if !out.IsGitRepo || out.Git == nil || out.Git.Root != dir || out.Git.Detached {
+		t.Errorf("Collect() = %+v, git %+v", out, out.Git)
+	}
+}
+
+func TestGitRepo(t *testing.T) {
+	dir := gitRepo(t)
+
+	if root, branch := GitRepo(filepath.Join(dir, "missing", "..")); root != dir || branch != "main" {
+		t.Errorf("GitRepo() = %q, %q", root, branch)
+	}
+	if root, _ := GitRepo(t.TempDir()); root != "" {
+		t.Errorf("GitRepo() outside a repository = %q", root)
+	}
+}
+
+func TestCollectorStamp(t *testing.T) {
+	dir := gitRepo(t)
+	kubeconfig := filepath.Join(t.TempDir(), "config")
+	if err := os.WriteFile(kubeconfig, []byte("current-context: dev\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	collector := NewCollectorForDir(dir).WithEnv(map[string]string{"KUBECONFIG": kubeconfig})
+
+	stamp := collector.Stamp()
+	if again := collector.Stamp(); again != stamp {
+		t.Errorf("Stamp() = %q, then %q without changes", stamp, again)
+	}
+
+	// A checkout rewrites HEAD
+	git(t, dir, "checkout", "--quiet", "-b", "feature")
+	if again := collector.Stamp(); again == stamp {
+		t.Error("Stamp() unchanged after a checkout")
+	}
+
+	// Switching contexts rewrites the kubeconfig
+	stamp = collector.Stamp()
+	later := time.Now().Add(time.Minute)
+	if err := os.Chtimes(kubeconfig, later, later); err != nil {
+		t.Fatal(err)
+	}
+	if again := collector.Stamp(); again == stamp {
+		t.Error("Stamp() unchanged after the kubeconfig changed")
+	}
+}
@@ -0,0 +1,53 @@
+package context
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"supertab/internal/ai"
+)
+
+// k8sSource reports the current Kubernetes context and namespace
+type k8sSource struct{}
+
+func init() {
+	Register(k8sSource{})
+}
+
+// Name returns the source name
+func (k8sSource) Name() string { return "k8s" }
+
+// Enabled reports whether the source is switched on
+func (k8sSource) Enabled(opts Options) bool { return opts.SourceEnabled("k8s", true) }
+
+// Timeout returns the time budget of the source
+func (k8sSource) Timeout() time.Duration { return 500 * time.Millisecond }
+
+// Collect gathers Kubernetes environment information from the kubeconfig,
+// without running kubectl or contacting the cluster
+func (k8sSource) Collect(ctx context.Context, opts Options, out *ai.Context) error {
+	k8sCtx := &ai.K8sContext{
+		IsAvailable: false,
+	}
+	out.K8sContext = k8sCtx
+
+	// Check if kubectl is available
+	if _, err := opts.lookPath("kubectl"); err != nil {
+		return nil
+	}
+
+	current, ok := currentKubeContext(opts)
+	if !ok {
+		return nil
+	}
+
+	k8sCtx.IsAvailable = true
+	k8sCtx.CurrentContext = current.Name
+	k8sCtx.CurrentNamespace = current.Namespace
+	if current.Server != "" {
+		k8sCtx.ClusterInfo = fmt.Sprintf("cluster %s at %s", current.Cluster, current.Server)
+	}
+
+	return nil
+}
@@ -0,0 +1,210 @@
+package context
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"supertab/internal/ai"
+	"supertab/internal/cache"
+)
+
+// k8sResourcesSource lists pods, deployments and services in the current
+// namespace and flags failing pods. It contacts the cluster, so it is off
+// unless context.sources.k8s_resources is set.
+type k8sResourcesSource struct{}
+
+const (
+	// k8sResourcesTTL is how long listed resources are reused from the cache
+	k8sResourcesTTL = 30 * time.Second
+
+	// maxK8sResources caps the names reported per resource kind
+	maxK8sResources = 50
+)
+
+// failingReasons are container states that mark a pod as failing
+var failingReasons = map[string]bool{
+	"CrashLoopBackOff":           true,
+	"Error":                      true,
+	"ImagePullBackOff":           true,
+	"ErrImagePull":               true,
+	"CreateContainerConfigError": true,
+	"OOMKilled":                  true,
+}
+
+func init() {
+	Register(k8sResourcesSource{})
+}
+
+// Name returns the source name
+func (k8sResourcesSource) Name() string { return "k8s_resources" }
+
+// Enabled reports whether the source is switched on
+func (k8sResourcesSource) Enabled(opts Options) bool {
+	return opts.SourceEnabled("k8s_resources", false)
+}
+
+// Timeout returns the time budget of the source
+func (k8sResourcesSource) Timeout() time.Duration { return 1500 * time.Millisecond }
+
+// k8sResources is the cached result of listing a namespace
+type k8sResources struct {
+	Collected   time.Time     `json:"collected"`
+	Pods        []string      `json:"pods,omitempty"`
+	Deployments []string      `json:"deployments,omitempty"`
+	Services    []string      `json:"services,omitempty"`
+	FailingPods []ai.PodIssue `json:"failing_pods,omitempty"`
+}
+
+// Collect lists the resources of the current namespace with a single kubectl call
+func (k8sResourcesSource) Collect(ctx context.Context, opts Options, out *ai.Context) error {
+	if _, err := opts.lookPath("kubectl"); err != nil {
+		return nil
+	}
+
+	current, ok := currentKubeContext(opts)
+	if !ok {
+		return nil
+	}
+
+	cachePath := k8sResourcesCachePath(current)
+	resources, err := readK8sResources(cachePath)
+	if err != nil || time.Since(resources.Collected) > k8sResourcesTTL {
+		var listErr error
+		resources, listErr = listK8sResources(ctx, opts, current)
+		if listErr != nil {
+			// An unreachable cluster is remembered too, so every Tab does not
+			// wait for kubectl to give up again
+			resources = k8sResources{Collected: time.Now()}
+		}
+		if data, err := json.Marshal(resources); err == nil {
+			if os.MkdirAll(filepath.Dir(cachePath), 0o700) == nil {
+				cache.WriteFileAtomic(cachePath, data)
+			}
+		}
+		if listErr != nil {
+			return listErr
+		}
+	}
+
+	out.K8sContext = &ai.K8sContext{
+		Pods:        resources.Pods,
+		Deployments: resources.Deployments,
+		Services:    resources.Services,
+		FailingPods: resources.FailingPods,
+	}
+	return nil
+}
+
+// k8sResourcesCachePath returns the cache file for a context and namespace
+func k8sResourcesCachePath(current kubeContext) string {
+	sum := sha256.Sum256([]byte(current.Name + "\n" + current.Namespace))
+	return filepath.Join(cache.DefaultDir(), "k8s", hex.EncodeToString(sum[:8])+".json")
+}
+
+// readK8sResources reads cached resources
+func readK8sResources(path string) (k8sResources, error) {
+	var resources k8sResources
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return resources, err
+	}
+	err = json.Unmarshal(data, &resources)
+	return resources, err
+}
+
+// k8sObject holds the fields of a listed object that are reported
+type k8sObject struct {
+	Kind     string `json:"kind"`
+	Metadata struct {
+		Name string `json:"name"`
+	} `json:"metadata"`
+	Status struct {
+		Phase             string `json:"phase"`
+		ContainerStatuses []struct {
+			State struct {
+				Waiting *struct {
+					Reason string `json:"reason"`
+				} `json:"waiting"`
+				Terminated *struct {
+					Reason string `json:"reason"`
+				} `json:"terminated"`
+			} `json:"state"`
+		} `json:"containerStatuses"`
+	} `json:"status"`
+}
+
+// listK8sResources runs kubectl get for pods, deployments and services
+func listK8sResources(ctx context.Context, opts Options, current kubeContext) (k8sResources, error) {
+	cmd := opts.command(ctx, "kubectl", "get", "pods,deployments,services",
+		"--context", current.Name,
+		"--namespace", current.Namespace,
+		"--request-timeout=1s",
+		"--output", "json")
+	output, err := cmd.Output()
+	if err != nil {
+		return k8sResources{}, err
+	}
+
+	var list struct {
+		Items []k8sObject `json:"items"`
+	}
+	if err := json.Unmarshal(output, &list); err != nil {
+		return k8sResources{}, err
+	}
+
+	resources := k8sResources{Collected: time.Now()}
+	for _, item := range list.Items {
+		name := item.Metadata.Name
+		switch item.Kind {
+		case "Pod":
+			resources.Pods = append(resources.Pods, name)
+			if reason := podFailure(item); reason != "" {
+				resources.FailingPods = append(resources.FailingPods, ai.PodIssue{Name: name, Reason: reason})
+			}
+		case "Deployment":
+			resources.Deployments = append(resources.Deployments, name)
+		case "Service":
+			resources.Services = append(resources.Services, name)
+		}
+	}
+
+	resources.Pods = capNames(resources.Pods)
+	resources.Deployments = capNames(resources.Deployments)
+	resources.Services = capNames(resources.Services)
+	if len(resources.FailingPods) > maxK8sResources {
+		resources.FailingPods = resources.FailingPods[:maxK8sResources]
+	}
+	return resources, nil
+}
+
+// podFailure returns why a pod is failing, or "" if it is not
+func podFailure(pod k8sObject) string {
+	for _, status := range pod.Status.ContainerStatuses {
+		if waiting := status.State.Waiting; waiting != nil && failingReasons[waiting.Reason] {
+			return waiting.Reason
+		}
+		if terminated := status.State.Terminated; terminated != nil && failingReasons[terminated.Reason] {
+			return terminated.Reason
+		}
+	}
+	switch pod.Status.Phase {
+	case "Pending", "Failed", "Unknown":
+		return pod.Status.Phase
+	}
+	return ""
+}
+
+// capNames sorts names and keeps at most maxK8sResources of them
+func capNames(names []string) []string {
+	sort.Strings(names)
+	if len(names) > maxK8sResources {
+		names = names[:maxK8sResources]
+	}
+	return names
+}
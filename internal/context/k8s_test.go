@@ -0,0 +1,158 @@
+package context
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"supertab/internal/ai"
+)
+
+// writeKubeconfig writes a kubeconfig file holding config
+func writeKubeconfig(t *testing.T, config string) string {
+	path := filepath.Join(t.TempDir(), "config")
+	if err := os.WriteFile(path, []byte(config), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestK8sResourcesCachesFailures(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+
+	// A stand-in for kubectl that counts its runs and cannot reach the cluster
+	bin := t.TempDir()
+	calls := filepath.Join(bin, "calls")
+	script := "#!/bin/sh\necho run >> " + calls + "\necho 'Unable to connect to the server' >&2\nexit 1\n"
+	if err := os.WriteFile(filepath.Join(bin, "kubectl"), []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	kubeconfig := writeKubeconfig(t, "current-context: dev\n")
+	opts := Options{Env: map[string]string{"PATH": bin + ":/bin:/usr/bin", "KUBECONFIG": kubeconfig}}
+
+	var out ai.Context
+	if err := (k8sResourcesSource{}).Collect(context.Background(), opts, &out); err == nil {
+		t.Error("Collect() with an unreachable cluster: error = nil")
+	}
+	if err := (k8sResourcesSource{}).Collect(context.Background(), opts, &out); err != nil {
+		t.Errorf("Collect() with the failure cached: error = %v", err)
+	}
+
+	data, _ := os.ReadFile(calls)
+	if runs := strings.Count(string(data), "run"); runs != 1 {
+		t.Errorf("kubectl ran %d times, want 1", runs)
+	}
+}
+
+func TestCurrentKubeContext(t *testing.T) {
+	first := writeKubeconfig(t, `
+current-context: dev
+contexts:
+  - name: dev
+    context: {cluster: dev-cluster, namespace: web}
+clusters:
+  - name: dev-cluster
+    cluster: {server: https://dev.example.com}
+`)
+	second := writeKubeconfig(t, `
+current-context: prod
+contexts:
+  - name: dev
+    context: {cluster: other-cluster, namespace: other}
+  - name: prod
+    context: {cluster: prod-cluster}
+clusters:
+  - name: dev-cluster
+    cluster: {server: https://other.example.com}
+  - name: prod-cluster
+    cluster: {server: https://prod.example.com}
+`)
+	missing := filepath.Join(t.TempDir(), "missing")
+	broken := writeKubeconfig(t, "current-context: [\n")
+
+	tests := []struct {
+		name       string
+		kubeconfig string
+		want       kubeContext
+		wantOK     bool
+	}{
+		{
+			name:       "single file",
+			kubeconfig: first,
+			want:       kubeContext{Name: "dev", Cluster: "dev-cluster", Server: "https://dev.example.com", Namespace: "web"},
+			wantOK:     true,
+		},
+		{
+			name:       "first definition wins",
+			kubeconfig: strings.Join([]string{missing, broken, first, second}, string(os.PathListSeparator)),
+			want:       kubeContext{Name: "dev", Cluster: "dev-cluster", Server: "https://dev.example.com", Namespace: "web"},
+			wantOK:     true,
+		},
+		{
+			name:       "later files fill in",
+			kubeconfig: strings.Join([]string{writeKubeconfig(t, "current-context: prod\n"), second}, string(os.PathListSeparator)),
+			want:       kubeContext{Name: "prod", Cluster: "prod-cluster", Server: "https://prod.example.com", Namespace: "default"},
+			wantOK:     true,
+		},
+		{
+			name:       "namespace falls back to default",
+			kubeconfig: second,
+			want:       kubeContext{Name: "prod", Cluster: "prod-cluster", Server: "https://prod.example.com", Namespace: "default"},
+			wantOK:     true,
+		},
+		{
+			name:       "undefined context",
+			kubeconfig: writeKubeconfig(t, "current-context: gone\n"),
+			want:       kubeContext{Name: "gone", Namespace: "default"},
+			wantOK:     true,
+		},
+		{
+			name:       "no current context",
+			kubeconfig: strings.Join([]string{missing, broken}, string(os.PathListSeparator)),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := currentKubeContext(Options{Env: map[string]string{"KUBECONFIG": tt.kubeconfig}})
+			if got != tt.want || ok != tt.wantOK {
+				t.Errorf("currentKubeContext() = %+v, %v, want %+v, %v", got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestPodFailure(t *testing.T) {
+	tests := []struct {
+		name string
+		pod  string
+		want string
+	}{
+		{"running", `{"status": {"phase": "Running", "containerStatuses": [{"state": {"running": {}}}]}}`, ""},
+		{"succeeded", `{"status": {"phase": "Succeeded"}}`, ""},
+		{"crash loop", `{"status": {"phase": "Running", "containerStatuses": [{"state": {"waiting": {"reason": "CrashLoopBackOff"}}}]}}`, "CrashLoopBackOff"},
+		{"image pull", `{"status": {"phase": "Pending", "containerStatuses": [{"state": {"waiting": {"reason": "ImagePullBackOff"}}}]}}`, "ImagePullBackOff"},
+		{"out of memory", `{"status": {"phase": "Running", "containerStatuses": [{"state": {"running": {}}}, {"state": {"terminated": {"reason": "OOMKilled"}}}]}}`, "OOMKilled"},
+		{"starting container", `{"status": {"phase": "Running", "containerStatuses": [{"state": {"waiting": {"reason": "ContainerCreating"}}}]}}`, ""},
+		{"completed container", `{"status": {"phase": "Running", "containerStatuses": [{"state": {"terminated": {"reason": "Completed"}}}]}}`, ""},
+		{"pending", `{"status": {"phase": "Pending"}}`, "Pending"},
+		{"failed", `{"status": {"phase": "Failed"}}`, "Failed"},
+		{"unknown", `{"status": {"phase": "Unknown"}}`, "Unknown"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var pod k8sObject
+			if err := json.Unmarshal([]byte(tt.pod), &pod); err != nil {
+				t.Fatal(err)
+			}
+			if got := podFailure(pod); got != tt.want {
+				t.Errorf("podFailure() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
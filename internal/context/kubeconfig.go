@@ -0,0 +1,91 @@
+package context
+
+import (
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+)
+
+// kubeconfig holds the parts of a kubeconfig needed to describe the current context
+type kubeconfig struct {
+	CurrentContext string `yaml:"current-context"`
+	Contexts       []struct {
+		Name    string `yaml:"name"`
+		Context struct {
+			Cluster   string `yaml:"cluster"`
+			Namespace string `yaml:"namespace"`
+		} `yaml:"context"`
+	} `yaml:"contexts"`
+	Clusters []struct {
+		Name    string `yaml:"name"`
+		Cluster struct {
+			Server string `yaml:"server"`
+		} `yaml:"cluster"`
+	} `yaml:"clusters"`
+}
+
+// kubeContext is the resolved current context of a kubeconfig
+type kubeContext struct {
+	Name      string
+	Cluster   string
+	Server    string
+	Namespace string
+}
+
+// kubeconfigPaths returns the files listed in $KUBECONFIG, or ~/.kube/config
+func kubeconfigPaths(opts Options) []string {
+	if env := opts.Getenv("KUBECONFIG"); env != "" {
+		return filepath.SplitList(env)
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return nil
+	}
+	return []string{filepath.Join(home, ".kube", "config")}
+}
+
+// currentKubeContext reads the kubeconfig files directly, merging them the
+// way kubectl does: the first file to set a value wins
+func currentKubeContext(opts Options) (kubeContext, bool) {
+	var merged kubeconfig
+	for _, path := range kubeconfigPaths(opts) {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		var config kubeconfig
+		if err := yaml.Unmarshal(data, &config); err != nil {
+			continue
+		}
+		if merged.CurrentContext == "" {
+			merged.CurrentContext = config.CurrentContext
+		}
+		merged.Contexts = append(merged.Contexts, config.Contexts...)
+		merged.Clusters = append(merged.Clusters, config.Clusters...)
+	}
+
+	if merged.CurrentContext == "" {
+		return kubeContext{}, false
+	}
+
+	current := kubeContext{Name: merged.CurrentContext, Namespace: "default"}
+	for _, c := range merged.Contexts {
+		if c.Name != current.Name {
+			continue
+		}
+		current.Cluster = c.Context.Cluster
+		if c.Context.Namespace != "" {
+			current.Namespace = c.Context.Namespace
+		}
+		break
+	}
+	for _, c := range merged.Clusters {
+		if c.Name == current.Cluster {
+			current.Server = c.Cluster.Server
+			break
+		}
+	}
+
+	return current, true
+}
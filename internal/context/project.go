@@ -0,0 +1,328 @@
+package context
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"supertab/internal/ai"
+
+	"gopkg.in/yaml.v3"
+)
+
+// projectSource recognizes the project type from marker files and lists the
+// targets its task runners can run
+type projectSource struct{}
+
+const (
+	// maxTargets caps the targets reported per task runner
+	maxTargets = 50
+
+	// maxGoMainDepth bounds how deep the Go main package search descends
+	maxGoMainDepth = 4
+)
+
+// projectMarkers maps marker files to the project type they indicate.
+// Task runner files mark a project without implying a language.
+var projectMarkers = []struct {
+	file        string
+	projectType string
+}{
+	{"go.mod", "go"},
+	{"package.json", "node"},
+	{"Cargo.toml", "rust"},
+	{"pyproject.toml", "python"},
+	{"Makefile", ""},
+	{"makefile", ""},
+	{"GNUmakefile", ""},
+	{"Taskfile.yml", ""},
+	{"Taskfile.yaml", ""},
+	{"justfile", ""},
+	{"Justfile", ""},
+	{".justfile", ""},
+}
+
+func init() {
+	Register(projectSource{})
+}
+
+// Name returns the source name
+func (projectSource) Name() string { return "project" }
+
+// Enabled reports whether the source is switched on
+func (projectSource) Enabled(opts Options) bool { return opts.SourceEnabled("project", true) }
+
+// Timeout returns the time budget of the source
+func (projectSource) Timeout() time.Duration { return time.Second }
+
+// Collect finds the nearest project root and its targets
+func (projectSource) Collect(ctx context.Context, opts Options, out *ai.Context) error {
+	root := findProjectRoot(opts.Dir)
+	if root == "" {
+		return nil
+	}
+
+	project := &ai.ProjectContext{Root: root}
+	for _, marker := range projectMarkers {
+		if marker.projectType != "" && fileExists(filepath.Join(root, marker.file)) {
+			project.Types = append(project.Types, marker.projectType)
+		}
+	}
+
+	add := func(runner, file string, targets []string) {
+		if len(targets) == 0 {
+			return
+		}
+		if len(targets) > maxTargets {
+			targets = targets[:maxTargets]
+		}
+		project.Targets = append(project.Targets, ai.TaskTargets{
+			Runner:  runner,
+			File:    relativePath(opts.Dir, filepath.Join(root, file)),
+			Targets: targets,
+		})
+	}
+
+	if file := firstExisting(root, "GNUmakefile", "makefile", "Makefile"); file != "" {
+		add("make", file, makeTargets(filepath.Join(root, file)))
+	}
+	if fileExists(filepath.Join(root, "package.json")) {
+		add(nodeRunner(root), "package.json", npmScripts(filepath.Join(root, "package.json")))
+	}
+	if file := firstExisting(root, "justfile", "Justfile", ".justfile"); file != "" {
+		add("just", file, justRecipes(filepath.Join(root, file)))
+	}
+	if file := firstExisting(root, "Taskfile.yml", "Taskfile.yaml"); file != "" {
+		add("task", file, taskfileTasks(filepath.Join(root, file)))
+	}
+	if fileExists(filepath.Join(root, "go.mod")) {
+		add("go run", "go.mod", goMainPackages(ctx, root))
+	}
+
+	out.Project = project
+	return nil
+}
+
+// findProjectRoot returns the nearest directory from dir up to the repository
+// root that holds a project marker
+func findProjectRoot(dir string) string {
+	for _, candidate := range projectDirs(dir) {
+		for _, marker := range projectMarkers {
+			if fileExists(filepath.Join(candidate, marker.file)) {
+				return candidate
+			}
+		}
+	}
+	return ""
+}
+
+// fileExists reports whether path exists
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// firstExisting returns the first of names that exists in dir
+func firstExisting(dir string, names ...string) string {
+	for _, name := range names {
+		if fileExists(filepath.Join(dir, name)) {
+			return name
+		}
+	}
+	return ""
+}
+
+// makeTargetPattern matches rule lines such as "build test: deps", but not
+// variable assignments such as "CC := gcc" or "CC ::= gcc"
+var makeTargetPattern = regexp.MustCompile(`^([A-Za-z0-9_./-]+(?:\s+[A-Za-z0-9_./-]+)*)\s*::?(?:[^=:]|$)`)
+
+// makeTargets returns the explicit targets of a Makefile, skipping special
+// targets such as .PHONY and pattern rules
+func makeTargets(path string) []string {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil
+	}
+	defer file.Close()
+
+	seen := make(map[string]bool)
+	var targets []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		match := makeTargetPattern.FindStringSubmatch(scanner.Text())
+		if match == nil {
+			continue
+		}
+		for _, target := range strings.Fields(match[1]) {
+			if strings.HasPrefix(target, ".") || seen[target] {
+				continue
+			}
+			seen[target] = true
+			targets = append(targets, target)
+		}
+	}
+	return targets
+}
+
+// nodeRunner picks the package manager from the lock file in root
+func nodeRunner(root string) string {
+	switch {
+	case fileExists(filepath.Join(root, "pnpm-lock.yaml")):
+		return "pnpm run"
+	case fileExists(filepath.Join(root, "yarn.lock")):
+		return "yarn run"
+	case fileExists(filepath.Join(root, "bun.lockb")), fileExists(filepath.Join(root, "bun.lock")):
+		return "bun run"
+	}
+	return "npm run"
+}
+
+// npmScripts returns the sorted script names of a package.json
+func npmScripts(path string) []string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+
+	var pkg struct {
+		Scripts map[string]string `json:"scripts"`
+	}
+	if err := json.Unmarshal(data, &pkg); err != nil {
+		return nil
+	}
+
+	return sortedKeys(pkg.Scripts)
+}
+
+// justRecipePattern matches a recipe header such as "build target='x':" or "@test:"
+var justRecipePattern = regexp.MustCompile(`^@?([A-Za-z_][A-Za-z0-9_-]*)(?:\s+[^:]*)?:(?:[^=]|$)`)
+
+// justRecipes returns the public recipes of a justfile
+func justRecipes(path string) []string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+
+	var recipes []string
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		match := justRecipePattern.FindStringSubmatch(scanner.Text())
+		if match == nil {
+			continue
+		}
+		name := match[1]
+		// Settings, aliases and imports share the name: form but are not recipes
+		switch name {
+		case "set", "alias", "import", "mod", "export":
+			continue
+		}
+		if !strings.HasPrefix(name, "_") {
+			recipes = append(recipes, name)
+		}
+	}
+	return recipes
+}
+
+// taskfileTasks returns the sorted task names of a Taskfile
+func taskfileTasks(path string) []string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil
+	}
+
+	var taskfile struct {
+		Tasks map[string]yaml.Node `yaml:"tasks"`
+	}
+	if err := yaml.Unmarshal(data, &taskfile); err != nil {
+		return nil
+	}
+
+	return sortedKeys(taskfile.Tasks)
+}
+
+// goMainPackages returns the main packages of a Go module as ./path arguments
+// for go run, stopping early when ctx is done
+func goMainPackages(ctx context.Context, root string) []string {
+	var packages []string
+	filepath.WalkDir(root, func(path string, entry os.DirEntry, err error) error {
+		if err != nil || ctx.Err() != nil {
+			return filepath.SkipAll
+		}
+		if !entry.IsDir() {
+			return nil
+		}
+
+		rel, _ := filepath.Rel(root, path)
+		name := entry.Name()
+		if path != root && (strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_") || name == "vendor" || name == "testdata" || name == "node_modules") {
+			return filepath.SkipDir
+		}
+		if rel != "." && strings.Count(filepath.ToSlash(rel), "/") >= maxGoMainDepth {
+			return filepath.SkipDir
+		}
+
+		if isGoMainPackage(path) {
+			packages = append(packages, "./"+filepath.ToSlash(rel))
+		}
+		return nil
+	})
+
+	for i, pkg := range packages {
+		if pkg == "./." {
+			packages[i] = "."
+		}
+	}
+	return packages
+}
+
+// isGoMainPackage reports whether the non-test Go files in dir declare package main
+func isGoMainPackage(dir string) bool {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return false
+	}
+
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, ".go") || strings.HasSuffix(name, "_test.go") {
+			continue
+		}
+		return goPackageName(filepath.Join(dir, name)) == "main"
+	}
+	return false
+}
+
+// goPackageName returns the package clause of a Go file
+func goPackageName(path string) string {
+	file, err := os.Open(path)
+	if err != nil {
+		return ""
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if name, ok := strings.CutPrefix(strings.TrimSpace(scanner.Text()), "package "); ok {
+			return strings.TrimSpace(strings.SplitN(name, "//", 2)[0])
+		}
+	}
+	return ""
+}
+
+// sortedKeys returns the keys of m in order
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,78 @@
+package context
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestMakeTargets(t *testing.T) {
+	tests := []struct {
+		name     string
+		makefile string
+		want     []string
+	}{
+		{"rule", "build: main.go\n\tgo build\n", []string{"build"}},
+		{"several targets", "build test: deps\n", []string{"build", "test"}},
+		{"double-colon rule", "install:: build\n", []string{"install"}},
+		{"file targets", "bin/app a.out: main.c\n", []string{"bin/app", "a.out"}},
+		{"simple assignment", "CC := gcc\nLD ::= ld\nAR :::= ar\n", nil},
+		{"other assignments", "CFLAGS = -O2\nPREFIX ?= /usr\nLIBS += -lm\nREV != git rev-parse HEAD\n", nil},
+		{"exported assignment", "export PATH := $(PWD)/bin:$(PATH)\n", nil},
+		{"target-specific variable", "debug: CFLAGS = -g\ndebug: build\n", []string{"debug"}},
+		{"pattern rule", "%.o: %.c\n\t$(CC) -c $<\n", nil},
+		{"special targets", ".PHONY: build test\n.DEFAULT_GOAL := build\n", nil},
+		{"recipe lines", "build:\n\techo done: ok\n\t@printf 'a:b'\n", []string{"build"}},
+		{"variable targets", "$(BIN): main.go\n", nil},
+		{"duplicates", "build:\nbuild: more\n", []string{"build"}},
+		{"no rules", "# comment: here\n", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "Makefile")
+			if err := os.WriteFile(path, []byte(tt.makefile), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			if got := makeTargets(path); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("makeTargets() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestJustRecipes(t *testing.T) {
+	tests := []struct {
+		name     string
+		justfile string
+		want     []string
+	}{
+		{"recipe", "build:\n    go build\n", []string{"build"}},
+		{"dependencies", "test: build lint\n", []string{"test"}},
+		{"quiet recipe", "@deploy:\n", []string{"deploy"}},
+		{"parameters", "serve port='8080' host=\"localhost\":\n", []string{"serve"}},
+		{"variadic parameters", "run *args: build\ncheck +files:\n", []string{"run", "check"}},
+		{"default with a colon", "fetch url='http://example.com':\n", []string{"fetch"}},
+		{"private recipe", "_helper:\n", nil},
+		{"settings", "set shell := [\"bash\", \"-c\"]\nset dotenv-load\nset positional-arguments := true\n", nil},
+		{"aliases", "alias b := build\n", nil},
+		{"exports", "export RUST_LOG := \"debug\"\n", nil},
+		{"variables", "version := `git describe`\n", nil},
+		{"imports and modules", "import 'common.just'\nmod docker\n", nil},
+		{"recipe body", "build:\n    echo step: one\n", []string{"build"}},
+		{"comments", "# build: the binary\n", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "justfile")
+			if err := os.WriteFile(path, []byte(tt.justfile), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			if got := justRecipes(path); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("justRecipes() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,131 @@
+package context
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// shellSeparator separates the sections of the shell output
+const shellSeparator = "--sug--"
+
+// Shell scripts that print the aliases, the separator, the function names,
+// the separator and global aliases or abbreviations. fish functions shipped
+// with fish itself are skipped.
+const (
+	zshScript  = `alias; print -r -- ` + shellSeparator + `; print -rl -- ${(k)functions}; print -r -- ` + shellSeparator + `; alias -g`
+	bashScript = `alias; echo ` + shellSeparator + `; compgen -A function; echo ` + shellSeparator
+	fishScript = `echo ` + shellSeparator + `; for f in (functions --names); string match -q -- "$__fish_data_dir/*" (functions --details $f); or echo $f; end; echo ` + shellSeparator + `; abbr --show`
+)
+
+// rcFiles are the startup files, relative to $HOME, whose changes make the
+// shell define something else
+var rcFiles = []string{
+	".zshenv", ".zprofile", ".zshrc",
+	".profile", ".bash_profile", ".bashrc", ".bash_aliases",
+	".config/fish/config.fish",
+}
+
+// shellOutput is what an interactive shell prints for the scripts above
+type shellOutput struct {
+	aliases   string
+	functions string
+	extras    string
+}
+
+// shellRun is a run of the user's shell, shared by the sources that need it
+type shellRun struct {
+	stamp  string
+	done   chan struct{}
+	output shellOutput
+	err    error
+}
+
+var (
+	shellRunsMu sync.Mutex
+	shellRuns   = make(map[string]*shellRun)
+)
+
+// shellDefinitions returns the aliases, functions and global aliases or
+// abbreviations defined by the user's shell. Starting an interactive shell is
+// slow, so the aliases and functions sources share a single run, and its
+// output is reused until one of the rc files changes. A run that failed is
+// not reused.
+func shellDefinitions(ctx context.Context, opts Options) (shellOutput, error) {
+	shell := opts.Shell
+	var args []string
+	switch {
+	case strings.Contains(shell, "zsh"):
+		args = []string{"-i", "-c", zshScript}
+	case strings.Contains(shell, "bash"):
+		args = []string{"-i", "-c", bashScript}
+	case strings.Contains(shell, "fish"):
+		args = []string{"-c", fishScript}
+	default:
+		return shellOutput{}, nil
+	}
+
+	stamp := rcFilesStamp()
+	shellRunsMu.Lock()
+	run, ok := shellRuns[shell]
+	if !ok || run.stamp != stamp || run.failed() {
+		run = &shellRun{stamp: stamp, done: make(chan struct{})}
+		shellRuns[shell] = run
+		shellRunsMu.Unlock()
+
+		run.output, run.err = runShell(ctx, opts, args)
+		close(run.done)
+		return run.output, run.err
+	}
+	shellRunsMu.Unlock()
+
+	select {
+	case <-run.done:
+		return run.output, run.err
+	case <-ctx.Done():
+		return shellOutput{}, ctx.Err()
+	}
+}
+
+// failed reports whether the run finished with an error
+func (r *shellRun) failed() bool {
+	select {
+	case <-r.done:
+		return r.err != nil
+	default:
+		return false
+	}
+}
+
+// runShell runs the user's shell with args and splits its output into sections
+func runShell(ctx context.Context, opts Options, args []string) (shellOutput, error) {
+	cmd := opts.command(ctx, opts.Shell, args...)
+	output, err := cmd.Output()
+	if err != nil {
+		return shellOutput{}, err
+	}
+
+	sections := strings.SplitN(string(output), shellSeparator+"\n", 3)
+	for len(sections) < 3 {
+		sections = append(sections, "")
+	}
+	return shellOutput{aliases: sections[0], functions: sections[1], extras: sections[2]}, nil
+}
+
+// rcFilesStamp returns the home directory and the modification times of the
+// rc files, which change whenever the user edits them
+func rcFilesStamp() string {
+	home := os.Getenv("HOME")
+	var stamp strings.Builder
+	stamp.WriteString(home + ";")
+	for _, name := range rcFiles {
+		if info, err := os.Stat(filepath.Join(home, name)); err == nil {
+			stamp.WriteString(info.ModTime().Format(time.RFC3339Nano))
+		}
+		stamp.WriteByte(';')
+	}
+	return stamp.String()
+}
@@ -0,0 +1,74 @@
+package context
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"supertab/internal/ai"
+)
+
+func TestShellDefinitions(t *testing.T) {
+	bash, err := exec.LookPath("bash")
+	if err != nil {
+		t.Skip("bash not installed")
+	}
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	rc := filepath.Join(home, ".bashrc")
+	if err := os.WriteFile(rc, []byte("alias gs='git status'\nmkrel() { :; }\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	ctx := testContext(t)
+
+	var out ai.Context
+	if err := (aliasesSource{}).Collect(ctx, Options{Shell: bash}, &out); err != nil {
+		t.Fatal(err)
+	}
+	if out.Aliases["gs"] != "git status" {
+		t.Errorf("Aliases = %v", out.Aliases)
+	}
+	if err := (functionsSource{}).Collect(ctx, Options{Shell: bash}, &out); err != nil {
+		t.Fatal(err)
+	}
+	if out.Commands == nil || len(out.Commands.Functions) != 1 || out.Commands.Functions[0] != "mkrel" {
+		t.Errorf("Commands = %+v", out.Commands)
+	}
+
+	// The output is reused until the rc file changes
+	info, _ := os.Stat(rc)
+	os.WriteFile(rc, []byte("alias gs='git status'\nalias gd='git diff'\n"), 0o644)
+	os.Chtimes(rc, info.ModTime(), info.ModTime())
+	output, err := shellDefinitions(ctx, Options{Shell: bash})
+	if err != nil || output.functions != "mkrel\n" {
+		t.Errorf("unchanged rc file: functions %q, error %v", output.functions, err)
+	}
+
+	later := info.ModTime().Add(time.Second)
+	os.Chtimes(rc, later, later)
+	output, err = shellDefinitions(ctx, Options{Shell: bash})
+	if err != nil || output.functions != "" {
+		t.Errorf("changed rc file: functions %q, error %v", output.functions, err)
+	}
+	aliases := make(map[string]string)
+	parseAliases(output.aliases, aliases)
+	if aliases["gd"] != "git diff" {
+		t.Errorf("changed rc file: aliases %v", aliases)
+	}
+}
+
+func TestShellDefinitionsUnsupported(t *testing.T) {
+	output, err := shellDefinitions(context.Background(), Options{Shell: "/bin/tcsh"})
+	if err != nil || output != (shellOutput{}) {
+		t.Errorf("shellDefinitions(tcsh) = %+v, %v", output, err)
+	}
+}
+
+func testContext(t *testing.T) context.Context {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	t.Cleanup(cancel)
+	return ctx
+}
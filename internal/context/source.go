@@ -0,0 +1,99 @@
+package context
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"supertab/internal/ai"
+)
+
+// ContextSource is a pluggable piece of context collection. Sources register
+// themselves with Register, usually from an init function, and are run
+// concurrently by Collector.Collect.
+type ContextSource interface {
+	// Name identifies the source in config, timings and ai.Context.Extras
+	Name() string
+
+	// Enabled reports whether the source should run for opts
+	Enabled(opts Options) bool
+
+	// Timeout is the time budget of the source
+	Timeout() time.Duration
+
+	// Collect writes the fields the source owns to out. It should return
+	// promptly once ctx is done; the fields set by then are kept, so cheap
+	// fields are best set before slow ones.
+	Collect(ctx context.Context, opts Options, out *ai.Context) error
+}
+
+// InputDependent is implemented by sources whose result depends on
+// Options.Input rather than only on the directory. Collector.Refresh re-runs
+// just these sources.
+type InputDependent interface {
+	UsesInput() bool
+}
+
+// Options describes what a source collects context for
+type Options struct {
+	// Dir is the directory being described
+	Dir string
+
+	// Shell is the user's shell
+	Shell string
+
+	// Input is the partial command being completed, if any
+	Input string
+
+	// Sources switches individual sources on or off by name, overriding
+	// their defaults (context.sources in ~/.sug.yaml)
+	Sources map[string]bool
+
+	// Env holds the variables of EnvVars set in the shell the context is
+	// for; nil means the process's own environment
+	Env map[string]string
+}
+
+// SourceEnabled returns the configured switch for the named source, or def
+// when the config does not mention it
+func (o Options) SourceEnabled(name string, def bool) bool {
+	if enabled, ok := o.Sources[name]; ok {
+		return enabled
+	}
+	return def
+}
+
+var (
+	registryMu sync.RWMutex
+	registry   = make(map[string]ContextSource)
+)
+
+// Register makes a context source available to all collectors. It panics
+// if a source with the same name is already registered.
+func Register(source ContextSource) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	name := source.Name()
+	if _, dup := registry[name]; dup {
+		panic(fmt.Sprintf("context: source %q registered twice", name))
+	}
+	registry[name] = source
+}
+
+// Sources returns the registered sources sorted by name
+func Sources() []ContextSource {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	sources := make([]ContextSource, 0, len(registry))
+	for _, source := range registry {
+		sources = append(sources, source)
+	}
+	sort.Slice(sources, func(i, j int) bool {
+		return sources[i].Name() < sources[j].Name()
+	})
+	return sources
+}
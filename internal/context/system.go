@@ -0,0 +1,69 @@
+package context
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"runtime"
+	"strings"
+	"time"
+
+	"supertab/internal/ai"
+)
+
+// systemSource describes the operating system
+type systemSource struct{}
+
+func init() {
+	Register(systemSource{})
+}
+
+// Name returns the source name
+func (systemSource) Name() string { return "system" }
+
+// Enabled reports whether the source is switched on
+func (systemSource) Enabled(opts Options) bool { return opts.SourceEnabled("system", true) }
+
+// Timeout returns the time budget of the source
+func (systemSource) Timeout() time.Duration { return 500 * time.Millisecond }
+
+// Collect gathers system-specific information
+func (systemSource) Collect(ctx context.Context, opts Options, out *ai.Context) error {
+	switch runtime.GOOS {
+	case "darwin":
+		// macOS system info
+		if cmd := command(ctx, "sw_vers"); cmd != nil {
+			if output, err := cmd.Output(); err == nil {
+				lines := strings.Split(string(output), "\n")
+				var version []string
+				for _, line := range lines {
+					if strings.Contains(line, ":") {
+						parts := strings.SplitN(line, ":", 2)
+						if len(parts) == 2 {
+							version = append(version, strings.TrimSpace(parts[1]))
+						}
+					}
+				}
+				out.System = fmt.Sprintf("macOS %s", strings.Join(version, " "))
+			}
+		}
+	case "linux":
+		// Linux system info
+		if data, err := os.ReadFile("/etc/os-release"); err == nil {
+			lines := strings.Split(string(data), "\n")
+			for _, line := range lines {
+				if strings.HasPrefix(line, "PRETTY_NAME=") {
+					name := strings.Trim(strings.TrimPrefix(line, "PRETTY_NAME="), `"`)
+					out.System = name
+					break
+				}
+			}
+		}
+	case "windows":
+		// Windows system info
+		out.System = "Windows"
+	default:
+		out.System = runtime.GOOS
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+package context
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"supertab/internal/ai"
+)
+
+// terraformSource reports the Terraform workspace of the directory
+type terraformSource struct{}
+
+func init() {
+	Register(terraformSource{})
+}
+
+// Name returns the source name
+func (terraformSource) Name() string { return "terraform" }
+
+// Enabled reports whether the source is switched on
+func (terraformSource) Enabled(opts Options) bool { return opts.SourceEnabled("terraform", true) }
+
+// Timeout returns the time budget of the source
+func (terraformSource) Timeout() time.Duration { return cloudTimeout }
+
+// Collect reads the selected workspace the way terraform does: TF_WORKSPACE,
+// then the environment file in the data directory
+func (terraformSource) Collect(ctx context.Context, opts Options, out *ai.Context) error {
+	dataDir := opts.Getenv("TF_DATA_DIR")
+	if dataDir == "" {
+		dataDir = ".terraform"
+	}
+	if !filepath.IsAbs(dataDir) {
+		dataDir = filepath.Join(opts.Dir, dataDir)
+	}
+
+	_, err := os.Stat(dataDir)
+	initialized := err == nil
+
+	configFiles, _ := filepath.Glob(filepath.Join(opts.Dir, "*.tf"))
+	if !initialized && len(configFiles) == 0 {
+		return nil
+	}
+
+	workspace := opts.Getenv("TF_WORKSPACE")
+	if workspace == "" {
+		if data, err := os.ReadFile(filepath.Join(dataDir, "environment")); err == nil {
+			workspace = strings.TrimSpace(string(data))
+		}
+	}
+	if workspace == "" {
+		workspace = "default"
+	}
+
+	setCloud(out).Terraform = &ai.TerraformContext{
+		Workspace:   workspace,
+		Initialized: initialized,
+	}
+	return nil
+}
@@ -0,0 +1,141 @@
+package daemon
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"time"
+
+	"supertab/internal/ai"
+	"supertab/internal/history"
+)
+
+// dialTimeout keeps a wedged daemon from delaying the local fallback
+const dialTimeout = 200 * time.Millisecond
+
+// Client talks to a running daemon
+type Client struct {
+	socketPath  string
+	settings    string
+	env         map[string]string
+	history     string
+	historyFile string
+}
+
+// NewClient creates a client for the daemon listening on socketPath
+func NewClient(socketPath string) *Client {
+	return &Client{socketPath: socketPath}
+}
+
+// WithSettings sets the settings fingerprint sent with completions and
+// predictions; a daemon started with other settings refuses them
+func (c *Client) WithSettings(settings string) *Client {
+	c.settings = settings
+	return c
+}
+
+// WithEnv sets the environment sent with completions and predictions, which
+// the daemon collects context with; see context.EnvVars
+func (c *Client) WithEnv(env map[string]string) *Client {
+	c.env = env
+	return c
+}
+
+// WithHistory sets the history the daemon reads for completions and
+// predictions, by the name and path of its source
+func (c *Client) WithHistory(source history.HistorySource) *Client {
+	c.history, c.historyFile = source.Name(), source.Path()
+	return c
+}
+
+// Ping checks that a daemon is answering
+func (c *Client) Ping(ctx context.Context) error {
+	_, err := c.call(ctx, Request{Op: OpPing}, nil)
+	return err
+}
+
+// Shutdown asks the daemon to exit
+func (c *Client) Shutdown(ctx context.Context) error {
+	_, err := c.call(ctx, Request{Op: OpShutdown}, nil)
+	return err
+}
+
+// Complete asks the daemon to complete input typed in dir. With onDelta set,
+// the completion is streamed.
+func (c *Client) Complete(ctx context.Context, input, dir string, onDelta ai.DeltaFunc) (*ai.Response, error) {
+	return c.call(ctx, Request{
+		Op:          OpComplete,
+		Input:       input,
+		Dir:         dir,
+		Stream:      onDelta != nil,
+		Settings:    c.settings,
+		Env:         c.env,
+		History:     c.history,
+		HistoryFile: c.historyFile,
+	}, onDelta)
+}
+
+// Predict asks the daemon to predict the next command in dir
+func (c *Client) Predict(ctx context.Context, dir string, historyLimit int) (*ai.Response, error) {
+	return c.call(ctx, Request{
+		Op:           OpPredict,
+		Dir:          dir,
+		HistoryLimit: historyLimit,
+		Settings:     c.settings,
+		Env:          c.env,
+		History:      c.history,
+		HistoryFile:  c.historyFile,
+	}, nil)
+}
+
+// call sends req and reads messages until the final one. Failing to reach the
+// daemon, or a daemon refusing the client's settings, returns an error
+// wrapping ErrUnavailable.
+func (c *Client) call(ctx context.Context, req Request, onDelta ai.DeltaFunc) (*ai.Response, error) {
+	dialer := net.Dialer{Timeout: dialTimeout}
+	conn, err := dialer.DialContext(ctx, "unix", c.socketPath)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrUnavailable, err)
+	}
+	defer conn.Close()
+
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+		req.Timeout = time.Until(deadline)
+	}
+
+	if err := json.NewEncoder(conn).Encode(req); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrUnavailable, err)
+	}
+
+	dec := json.NewDecoder(bufio.NewReader(conn))
+	for {
+		var msg Message
+		if err := dec.Decode(&msg); err != nil {
+			if ctx.Err() != nil {
+				return nil, fmt.Errorf("%w: %w", ai.ErrTimeout, ctx.Err())
+			}
+			return nil, fmt.Errorf("failed to read daemon response: %w", err)
+		}
+
+		if msg.Delta != "" && onDelta != nil {
+			onDelta(msg.Delta)
+		}
+		if !msg.Done {
+			continue
+		}
+
+		if msg.Kind == kindSettingsChanged {
+			return nil, fmt.Errorf("%w: %w", ErrUnavailable, ErrSettingsChanged)
+		}
+		if msg.Error != "" {
+			return nil, &RemoteError{Kind: msg.Kind, Message: msg.Error, RetryAfter: msg.RetryAfter}
+		}
+		if msg.Response == nil {
+			return nil, fmt.Errorf("%w: daemon sent no response", ai.ErrInvalidResponse)
+		}
+		return msg.Response, nil
+	}
+}
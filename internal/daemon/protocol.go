@@ -0,0 +1,96 @@
+package daemon
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"supertab/internal/ai"
+)
+
+// Operations understood by the daemon
+const (
+	OpComplete = "complete"
+	OpPredict  = "predict"
+	OpPing     = "ping"
+	OpShutdown = "shutdown"
+)
+
+// Request is a call from a thin client. Each connection carries one request,
+// encoded as a single line of JSON.
+type Request struct {
+	Op           string        `json:"op"`
+	Input        string        `json:"input,omitempty"`
+	Dir          string        `json:"dir,omitempty"`
+	HistoryLimit int           `json:"history_limit,omitempty"`
+	Stream       bool          `json:"stream,omitempty"`
+	Timeout      time.Duration `json:"timeout,omitempty"`
+
+	// Settings fingerprints the provider and configuration the client would
+	// use on its own; see Options.Settings
+	Settings string `json:"settings,omitempty"`
+
+	// Env holds the client's values of context.EnvVars, so context describes
+	// the client's shell rather than the one the daemon was started from
+	Env map[string]string `json:"env,omitempty"`
+
+	// History and HistoryFile name the history the client reads and its
+	// path; empty means the daemon's own
+	History     string `json:"history,omitempty"`
+	HistoryFile string `json:"history_file,omitempty"`
+}
+
+// Message is a line of JSON sent back by the daemon. A streaming completion
+// sends Delta messages followed by a final message with Done set.
+type Message struct {
+	Delta      string        `json:"delta,omitempty"`
+	Done       bool          `json:"done,omitempty"`
+	Response   *ai.Response  `json:"response,omitempty"`
+	Error      string        `json:"error,omitempty"`
+	Kind       string        `json:"kind,omitempty"`
+	RetryAfter time.Duration `json:"retry_after,omitempty"`
+}
+
+// ErrUnavailable is returned by the client when no daemon answers on the
+// socket, or the one answering runs with other settings
+var ErrUnavailable = errors.New("daemon unavailable")
+
+// ErrSettingsChanged is returned by a daemon whose settings differ from the
+// client's, e.g. after a config change or with --provider
+var ErrSettingsChanged = errors.New("daemon runs with other settings; restart it to apply them")
+
+// kindSettingsChanged reports ErrSettingsChanged in a Message
+const kindSettingsChanged = "settings_changed"
+
+// RemoteError is an error reported by the daemon. It keeps the error kind
+// so exit codes match those of a local run.
+type RemoteError struct {
+	Kind       string
+	Message    string
+	RetryAfter time.Duration
+}
+
+func (e *RemoteError) Error() string {
+	return e.Message
+}
+
+// Unwrap returns the ai error for the reported kind, if known
+func (e *RemoteError) Unwrap() error {
+	return ai.KindError(e.Kind)
+}
+
+// RetryDelay returns the delay the provider asked for before retrying
+func (e *RemoteError) RetryDelay() time.Duration {
+	return e.RetryAfter
+}
+
+// DefaultSocketPath returns $XDG_RUNTIME_DIR/sug/daemon.sock, falling back
+// to a per-user directory under the system temp directory
+func DefaultSocketPath() string {
+	if dir := os.Getenv("XDG_RUNTIME_DIR"); dir != "" {
+		return filepath.Join(dir, "sug", "daemon.sock")
+	}
+	return filepath.Join(os.TempDir(), "sug-"+strconv.Itoa(os.Getuid()), "daemon.sock")
+}
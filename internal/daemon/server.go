@@ -0,0 +1,365 @@
+package daemon
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"supertab/internal/ai"
+	contextpkg "supertab/internal/context"
+	"supertab/internal/history"
+)
+
+const (
+	// DefaultContextTTL is how long collected context is reused for a directory
+	DefaultContextTTL = 30 * time.Second
+
+	// defaultRequestTimeout applies when a client sends no timeout
+	defaultRequestTimeout = 30 * time.Second
+
+	// defaultReadTimeout bounds how long a client may take to send its request
+	defaultReadTimeout = 5 * time.Second
+
+	// AliasHistoryLimit is how many recent commands rank the aliases sent
+	// with a completion
+	AliasHistoryLimit = 100
+)
+
+// Options configures a Server
+type Options struct {
+	// Complete and Predict answer the two request kinds; they may be the same client
+	Complete ai.Client
+	Predict  ai.Client
+
+	// ContextTTL bounds how long collected context is reused per directory and
+	// environment, unless the git repository or kubeconfig changes
+	ContextTTL time.Duration
+
+	// ReadTimeout bounds how long a client may take to send its request, so
+	// a connection that never does is closed
+	ReadTimeout time.Duration
+
+	// Sources switches context sources on or off by name
+	Sources map[string]bool
+
+	// History is where command history is read from; nil means the user's shell
+	History history.HistorySource
+
+	// Settings fingerprints the configuration the server was started with.
+	// Requests carrying other settings are refused, so the client answers
+	// them itself instead of getting answers from a stale configuration.
+	Settings string
+
+	Debug bool
+}
+
+// Server answers complete and predict requests over a Unix socket, keeping
+// provider connections, collected context and parsed history warm between calls
+type Server struct {
+	opts Options
+
+	mu       sync.Mutex
+	contexts map[string]cachedContext
+	history  map[string]historyIndex
+
+	done     chan struct{}
+	doneOnce sync.Once
+}
+
+// cachedContext is context collected for a directory and environment, with
+// the stamp of the state it was collected in; see contextpkg.Collector.Stamp
+type cachedContext struct {
+	context   ai.Context
+	stamp     string
+	collected time.Time
+}
+
+// historyIndex caches parsed history until the history file or the store of
+// recorded results changes. There is one per history read by clients.
+type historyIndex struct {
+	file    fileVersion
+	store   fileVersion
+	limit   int
+	entries []ai.HistoryEntry
+}
+
+// fileVersion identifies the contents of a file by its size and modification time
+type fileVersion struct {
+	modTime time.Time
+	size    int64
+}
+
+// versionOf returns the version of the file at path; a missing file has the zero version
+func versionOf(path string) fileVersion {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fileVersion{}
+	}
+	return fileVersion{modTime: info.ModTime(), size: info.Size()}
+}
+
+// NewServer creates a daemon server
+func NewServer(opts Options) *Server {
+	if opts.ContextTTL <= 0 {
+		opts.ContextTTL = DefaultContextTTL
+	}
+	if opts.ReadTimeout <= 0 {
+		opts.ReadTimeout = defaultReadTimeout
+	}
+	return &Server{
+		opts:     opts,
+		contexts: make(map[string]cachedContext),
+		history:  make(map[string]historyIndex),
+		done:     make(chan struct{}),
+	}
+}
+
+// Listen creates the Unix socket at path, replacing a stale socket left behind
+// by a daemon that did not shut down cleanly
+func Listen(path string) (net.Listener, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create socket directory: %w", err)
+	}
+
+	if _, err := os.Stat(path); err == nil {
+		if NewClient(path).Ping(context.Background()) == nil {
+			return nil, fmt.Errorf("daemon already running on %s", path)
+		}
+		os.Remove(path)
+	}
+
+	ln, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %s: %w", path, err)
+	}
+	if err := os.Chmod(path, 0o600); err != nil {
+		ln.Close()
+		return nil, fmt.Errorf("failed to restrict socket permissions: %w", err)
+	}
+	return ln, nil
+}
+
+// Serve accepts connections until the listener is closed or a shutdown request arrives
+func (s *Server) Serve(ln net.Listener) error {
+	go func() {
+		<-s.done
+		ln.Close()
+	}()
+
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			select {
+			case <-s.done:
+				return nil
+			default:
+			}
+			if errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			return err
+		}
+		go s.handle(conn)
+	}
+}
+
+// Shutdown stops Serve
+func (s *Server) Shutdown() {
+	s.doneOnce.Do(func() { close(s.done) })
+}
+
+// handle serves a single request on conn
+func (s *Server) handle(conn net.Conn) {
+	defer conn.Close()
+
+	var req Request
+	conn.SetReadDeadline(time.Now().Add(s.opts.ReadTimeout))
+	if err := json.NewDecoder(bufio.NewReader(conn)).Decode(&req); err != nil {
+		s.logf("invalid request: %v", err)
+		return
+	}
+	conn.SetReadDeadline(time.Time{})
+
+	timeout := req.Timeout
+	if timeout <= 0 {
+		timeout = defaultRequestTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	enc := json.NewEncoder(conn)
+	send := func(msg Message) {
+		if err := enc.Encode(msg); err != nil {
+			s.logf("failed to write response: %v", err)
+			cancel()
+		}
+	}
+
+	started := time.Now()
+	resp, err := s.dispatch(ctx, req, func(delta string) { send(Message{Delta: delta}) })
+	if err != nil {
+		s.logf("%s %q failed after %s: %v", req.Op, req.Input, time.Since(started).Round(time.Millisecond), err)
+		kind := ai.ErrorKind(err)
+		if errors.Is(err, ErrSettingsChanged) {
+			kind = kindSettingsChanged
+		}
+		send(Message{
+			Done:       true,
+			Error:      err.Error(),
+			Kind:       kind,
+			RetryAfter: ai.RetryAfter(err),
+		})
+		return
+	}
+
+	s.logf("%s %q answered in %s", req.Op, req.Input, time.Since(started).Round(time.Millisecond))
+	send(Message{Done: true, Response: resp})
+}
+
+// dispatch runs a request and returns its response
+func (s *Server) dispatch(ctx context.Context, req Request, onDelta ai.DeltaFunc) (*ai.Response, error) {
+	switch req.Op {
+	case OpPing:
+		return &ai.Response{}, nil
+
+	case OpShutdown:
+		s.Shutdown()
+		return &ai.Response{}, nil
+
+	case OpComplete, OpPredict:
+		if req.Settings != s.opts.Settings {
+			return nil, ErrSettingsChanged
+		}
+	}
+
+	switch req.Op {
+	case OpComplete:
+		completion := ai.CompletionRequest{
+			Input:   req.Input,
+			Context: s.collect(ctx, req),
+		}
+		if recentHistory, err := s.recentHistory(req, AliasHistoryLimit); err == nil {
+			completion.History = recentHistory
+		}
+		if req.Stream {
+			return s.opts.Complete.Stream(ctx, completion, onDelta)
+		}
+		return s.opts.Complete.Complete(ctx, completion)
+
+	case OpPredict:
+		recentHistory, err := s.recentHistory(req, req.HistoryLimit)
+		if err != nil {
+			s.logf("failed to get history: %v", err)
+			recentHistory = []ai.HistoryEntry{}
+		}
+		return s.opts.Predict.Predict(ctx, ai.PredictionRequest{
+			History: recentHistory,
+			Context: s.collect(ctx, req),
+		})
+	}
+
+	return nil, fmt.Errorf("unknown operation: %q", req.Op)
+}
+
+// collect returns the context for the request's directory and environment,
+// reusing a recent collection unless the git repository or kubeconfig changed
+// since. Sources that depend on the input are refreshed for every request.
+func (s *Server) collect(ctx context.Context, req Request) ai.Context {
+	collector := contextpkg.NewCollectorForDir(req.Dir).WithSources(s.opts.Sources).WithInput(req.Input)
+	if req.Env != nil {
+		collector.WithEnv(req.Env)
+	}
+	key := req.Dir + "\x00" + contextpkg.EnvKey(req.Env)
+	stamp := collector.Stamp()
+
+	s.mu.Lock()
+	cached, ok := s.contexts[key]
+	s.mu.Unlock()
+
+	if ok && cached.stamp == stamp && time.Since(cached.collected) < s.opts.ContextTTL {
+		info, timings := collector.Refresh(ctx, cached.context)
+		s.logTimings(timings)
+		return info
+	}
+
+	info, timings := collector.Collect(ctx)
+	s.logTimings(timings)
+
+	s.mu.Lock()
+	s.contexts[key] = cachedContext{context: info, stamp: stamp, collected: time.Now()}
+	s.mu.Unlock()
+
+	return info
+}
+
+// logTimings reports sources that gave up
+func (s *Server) logTimings(timings []contextpkg.Timing) {
+	for _, timing := range timings {
+		if timing.Error != "" {
+			s.logf("context %s gave up after %s: %s", timing.Name, timing.Duration.Round(time.Millisecond), timing.Error)
+		}
+	}
+}
+
+// recentHistory returns the latest entries of the history the request names,
+// parsing the history file again only when it or the recorded results changed
+func (s *Server) recentHistory(req Request, limit int) ([]ai.HistoryEntry, error) {
+	parser := history.NewParser()
+	if req.History != "" {
+		source, err := history.SourceAt(req.History, req.HistoryFile)
+		if err != nil {
+			return nil, err
+		}
+		parser = history.NewParserFor(source)
+	} else if s.opts.History != nil {
+		parser = history.NewParserFor(s.opts.History)
+	}
+
+	if _, err := os.Stat(parser.HistoryFile()); err != nil {
+		return nil, err
+	}
+	file := versionOf(parser.HistoryFile())
+	store := versionOf(parser.StoreFile())
+
+	s.mu.Lock()
+	index := s.history[parser.HistoryFile()]
+	s.mu.Unlock()
+
+	// A parse for a larger limit also answers smaller ones
+	if index.entries != nil && index.limit >= limit && index.file == file && index.store == store {
+		return lastEntries(index.entries, limit), nil
+	}
+
+	entries, err := parser.GetRecentHistory(limit)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	s.history[parser.HistoryFile()] = historyIndex{file: file, store: store, limit: limit, entries: entries}
+	s.mu.Unlock()
+
+	return entries, nil
+}
+
+// lastEntries returns the last limit entries
+func lastEntries(entries []ai.HistoryEntry, limit int) []ai.HistoryEntry {
+	if len(entries) > limit {
+		return entries[len(entries)-limit:]
+	}
+	return entries
+}
+
+// logf writes a debug line to stderr
+func (s *Server) logf(format string, args ...any) {
+	if s.opts.Debug {
+		fmt.Fprintf(os.Stderr, "Debug: daemon: "+format+"\n", args...)
+	}
+}
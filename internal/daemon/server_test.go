@@ -0,0 +1,303 @@
+package daemon
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"supertab/internal/ai"
+	contextpkg "supertab/internal/context"
+	"supertab/internal/history"
+)
+
+// scriptedClient answers completions with a fixed response or error and
+// streams the response in words
+type scriptedClient struct {
+	resp     *ai.Response
+	err      error
+	requests []ai.CompletionRequest
+	predicts []ai.PredictionRequest
+}
+
+func (c *scriptedClient) Complete(ctx context.Context, req ai.CompletionRequest) (*ai.Response, error) {
+	c.requests = append(c.requests, req)
+	return c.resp, c.err
+}
+
+func (c *scriptedClient) Predict(ctx context.Context, req ai.PredictionRequest) (*ai.Response, error) {
+	c.predicts = append(c.predicts, req)
+	return c.resp, c.err
+}
+
+func (c *scriptedClient) Stream(ctx context.Context, req ai.CompletionRequest, onDelta ai.DeltaFunc) (*ai.Response, error) {
+	c.requests = append(c.requests, req)
+	if c.err != nil {
+		return nil, c.err
+	}
+	for _, word := range strings.SplitAfter("+"+c.resp.Content, " ") {
+		onDelta(word)
+	}
+	return c.resp, nil
+}
+
+// startServer serves opts on a socket in a temporary directory, with every
+// context source switched off but those opts.Sources switches on
+func startServer(t *testing.T, opts Options) string {
+	sources := make(map[string]bool)
+	for _, source := range contextpkg.Sources() {
+		sources[source.Name()] = opts.Sources[source.Name()]
+	}
+	opts.Sources = sources
+
+	// No commands are recorded, so history comes from the file below
+	t.Setenv("XDG_DATA_HOME", t.TempDir())
+
+	// The last command is the one asking, which the parser leaves out
+	historyFile := filepath.Join(t.TempDir(), "history")
+	if err := os.WriteFile(historyFile, []byte("git status\nmake build\nmake test\nsug predict\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	opts.History = history.NewBashSource(historyFile)
+
+	// Unix socket paths are short, so the socket does not go in t.TempDir
+	dir, err := os.MkdirTemp("", "sug")
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "d.sock")
+	ln, err := Listen(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server := NewServer(opts)
+	done := make(chan error, 1)
+	go func() { done <- server.Serve(ln) }()
+	t.Cleanup(func() {
+		server.Shutdown()
+		<-done
+		os.RemoveAll(dir)
+	})
+	return path
+}
+
+func testContext(t *testing.T) context.Context {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	t.Cleanup(cancel)
+	return ctx
+}
+
+func TestDaemonComplete(t *testing.T) {
+	backend := &scriptedClient{resp: &ai.Response{Type: ai.TypeCompletion, Content: "atus --short"}}
+	path := startServer(t, Options{Complete: backend, Predict: backend, Settings: "s1"})
+	client := NewClient(path).WithSettings("s1")
+	ctx := testContext(t)
+
+	if err := client.Ping(ctx); err != nil {
+		t.Fatalf("Ping() error = %v", err)
+	}
+
+	resp, err := client.Complete(ctx, "git st", "/src/app", nil)
+	if err != nil {
+		t.Fatalf("Complete() error = %v", err)
+	}
+	if resp.Type != ai.TypeCompletion || resp.Content != "atus --short" {
+		t.Errorf("Complete() = %+v", resp)
+	}
+	if req := backend.requests[0]; req.Input != "git st" || req.Context.Directory != "/src/app" || len(req.History) != 3 {
+		t.Errorf("request = %+v", req)
+	}
+
+	var streamed strings.Builder
+	resp, err = client.Complete(ctx, "git st", "/src/app", func(delta string) { streamed.WriteString(delta) })
+	if err != nil {
+		t.Fatalf("streamed Complete() error = %v", err)
+	}
+	if streamed.String() != "+atus --short" || resp.Content != "atus --short" {
+		t.Errorf("streamed %q, response %+v", streamed.String(), resp)
+	}
+}
+
+func TestDaemonPredict(t *testing.T) {
+	backend := &scriptedClient{resp: &ai.Response{Type: ai.TypePrediction, Content: "make test"}}
+	path := startServer(t, Options{Complete: backend, Predict: backend})
+
+	resp, err := NewClient(path).Predict(testContext(t), "/src/app", 2)
+	if err != nil {
+		t.Fatalf("Predict() error = %v", err)
+	}
+	if resp.Content != "make test" {
+		t.Errorf("Predict() = %+v", resp)
+	}
+	if history := backend.predicts[0].History; len(history) != 2 || history[1].Command != "make test" {
+		t.Errorf("history = %+v, want the last 2 commands", history)
+	}
+}
+
+func TestDaemonClientEnv(t *testing.T) {
+	backend := &scriptedClient{resp: &ai.Response{Type: ai.TypeCompletion, Content: "x"}}
+	path := startServer(t, Options{Complete: backend, Predict: backend, Sources: map[string]bool{"aws": true}})
+	ctx := testContext(t)
+	t.Setenv("AWS_PROFILE", "daemon")
+
+	historyFile := filepath.Join(t.TempDir(), "history")
+	if err := os.WriteFile(historyFile, []byte("terraform plan\nsug complete\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	// Shells in the same directory with other profiles get their own context
+	for _, profile := range []string{"prod", "dev", "prod"} {
+		env := map[string]string{"AWS_PROFILE": profile, "AWS_CONFIG_FILE": filepath.Join(t.TempDir(), "config")}
+		client := NewClient(path).WithEnv(env).WithHistory(history.NewBashSource(historyFile))
+		if _, err := client.Complete(ctx, "aws s3 ls", "/src/app", nil); err != nil {
+			t.Fatalf("Complete() error = %v", err)
+		}
+
+		req := backend.requests[len(backend.requests)-1]
+		if cloud := req.Context.Cloud; cloud == nil || cloud.AWS == nil || cloud.AWS.Profile != profile {
+			t.Errorf("cloud context with AWS_PROFILE=%s = %+v", profile, cloud)
+		}
+		if len(req.History) != 1 || req.History[0].Command != "terraform plan" {
+			t.Errorf("history = %+v, want the client's history file", req.History)
+		}
+	}
+}
+
+func TestDaemonContextAfterCheckout(t *testing.T) {
+	backend := &scriptedClient{resp: &ai.Response{Type: ai.TypeCompletion, Content: "x"}}
+	path := startServer(t, Options{Complete: backend, Predict: backend, Sources: map[string]bool{"git": true}, ContextTTL: time.Hour})
+	ctx := testContext(t)
+
+	dir := t.TempDir()
+	for _, args := range [][]string{
+		{"init", "--quiet", "--initial-branch", "main"},
+		{"-c", "user.name=t", "-c", "user.email=t@t", "commit", "--quiet", "--allow-empty", "-m", "first"},
+	} {
+		if out, err := exec.Command("git", append([]string{"-C", dir}, args...)...).CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+
+	// The cached context is collected again once the branch changes
+	for _, branch := range []string{"main", "feature"} {
+		if branch != "main" {
+			if out, err := exec.Command("git", "-C", dir, "checkout", "--quiet", "-b", branch).CombinedOutput(); err != nil {
+				t.Fatalf("git checkout: %v\n%s", err, out)
+			}
+		}
+		if _, err := NewClient(path).Complete(ctx, "git st", dir, nil); err != nil {
+			t.Fatalf("Complete() error = %v", err)
+		}
+		if got := backend.requests[len(backend.requests)-1].Context.GitBranch; got != branch {
+			t.Errorf("branch = %q, want %q", got, branch)
+		}
+	}
+}
+
+func TestDaemonErrors(t *testing.T) {
+	rateLimited := &ai.APIError{Provider: ai.ProviderOpenAI, StatusCode: http.StatusTooManyRequests, RetryAfter: 1500 * time.Millisecond}
+
+	tests := []struct {
+		name           string
+		err            error
+		wantKind       string
+		wantRetryAfter time.Duration
+	}{
+		{"rate limited", rateLimited, "rate_limited", 1500 * time.Millisecond},
+		{"auth", &ai.APIError{StatusCode: http.StatusUnauthorized}, "auth", 0},
+		{"unclassified", errors.New("boom"), "unknown", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			backend := &scriptedClient{err: tt.err}
+			path := startServer(t, Options{Complete: backend, Predict: backend})
+
+			_, err := NewClient(path).Complete(testContext(t), "ls", "/", nil)
+			var remote *RemoteError
+			if !errors.As(err, &remote) {
+				t.Fatalf("Complete() error = %v, want *RemoteError", err)
+			}
+			if kind := ai.ErrorKind(err); kind != tt.wantKind {
+				t.Errorf("ErrorKind() = %q, want %q", kind, tt.wantKind)
+			}
+			if got := ai.RetryAfter(err); got != tt.wantRetryAfter {
+				t.Errorf("RetryAfter() = %s, want %s", got, tt.wantRetryAfter)
+			}
+		})
+	}
+}
+
+func TestDaemonSettings(t *testing.T) {
+	backend := &scriptedClient{resp: &ai.Response{Type: ai.TypeCompletion, Content: "x"}}
+	path := startServer(t, Options{Complete: backend, Predict: backend, Settings: "openai/gpt-4o-mini"})
+	ctx := testContext(t)
+
+	client := NewClient(path).WithSettings("ollama/llama3.1")
+	for name, call := range map[string]func() error{
+		"complete": func() error { _, err := client.Complete(ctx, "ls", "/", nil); return err },
+		"predict":  func() error { _, err := client.Predict(ctx, "/", 10); return err },
+	} {
+		err := call()
+		if !errors.Is(err, ErrUnavailable) || !errors.Is(err, ErrSettingsChanged) {
+			t.Errorf("%s with other settings: error = %v, want ErrUnavailable", name, err)
+		}
+	}
+	if len(backend.requests)+len(backend.predicts) != 0 {
+		t.Error("a request with other settings reached the provider")
+	}
+
+	// Ping and shutdown do not depend on the settings
+	if err := client.Ping(ctx); err != nil {
+		t.Errorf("Ping() error = %v", err)
+	}
+}
+
+func TestDaemonUnavailable(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "missing.sock")
+	if _, err := NewClient(path).Complete(testContext(t), "ls", "/", nil); !errors.Is(err, ErrUnavailable) {
+		t.Errorf("Complete() error = %v, want ErrUnavailable", err)
+	}
+}
+
+func TestDaemonSilentClient(t *testing.T) {
+	path := startServer(t, Options{Complete: &scriptedClient{}, ReadTimeout: 50 * time.Millisecond})
+
+	conn, err := net.Dial("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	// A client that never sends its request is hung up on
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	if _, err := conn.Read(make([]byte, 1)); err != io.EOF {
+		t.Errorf("Read() error = %v, want io.EOF", err)
+	}
+}
+
+func TestDaemonShutdown(t *testing.T) {
+	backend := &scriptedClient{}
+	path := startServer(t, Options{Complete: backend, Predict: backend})
+	ctx := testContext(t)
+
+	if err := NewClient(path).Shutdown(ctx); err != nil {
+		t.Fatalf("Shutdown() error = %v", err)
+	}
+	// Serve returns, so the socket stops answering
+	deadline := time.Now().Add(2 * time.Second)
+	for NewClient(path).Ping(ctx) == nil {
+		if time.Now().After(deadline) {
+			t.Fatal("daemon still answering after shutdown")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
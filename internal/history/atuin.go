@@ -0,0 +1,89 @@
+package history
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"supertab/internal/ai"
+)
+
+// atuinSource reads the SQLite database of Atuin, which records the
+// directory, exit code, duration, session and host of every command
+type atuinSource struct {
+	path string
+}
+
+// NewAtuinSource creates a source reading the Atuin database at path
+func NewAtuinSource(path string) HistorySource {
+	return atuinSource{path: path}
+}
+
+// atuinDatabasePath returns $ATUIN_DB_PATH, falling back to Atuin's default
+// database location
+func atuinDatabasePath() string {
+	if path := os.Getenv("ATUIN_DB_PATH"); path != "" {
+		return path
+	}
+	return filepath.Join(dataHome(), "atuin", "history.db")
+}
+
+// Name returns the source name
+func (atuinSource) Name() string { return "atuin" }
+
+// Path returns the database file
+func (s atuinSource) Path() string { return s.path }
+
+// Read returns the last limit entries of the history
+func (s atuinSource) Read(limit int) ([]ai.HistoryEntry, error) {
+	records, err := s.Records(limit)
+	if err != nil {
+		return nil, err
+	}
+	return recordEntries(records), nil
+}
+
+// atuinRow is a row of Atuin's history table. Times are in nanoseconds and
+// -1 marks an unknown duration or exit code.
+type atuinRow struct {
+	Command   string `json:"command"`
+	Timestamp int64  `json:"timestamp"`
+	Duration  int64  `json:"duration"`
+	Exit      int    `json:"exit"`
+	Cwd       string `json:"cwd"`
+	Session   string `json:"session"`
+	Hostname  string `json:"hostname"` // "<host>:<user>"
+}
+
+// Records returns the last limit commands with their metadata, oldest first
+func (s atuinSource) Records(limit int) ([]Record, error) {
+	query := fmt.Sprintf("SELECT command, timestamp, duration, exit, cwd, session, hostname FROM history WHERE deleted_at IS NULL ORDER BY timestamp DESC LIMIT %d", limit)
+
+	var rows []atuinRow
+	if err := querySQLite(s.path, query, &rows); err != nil {
+		return nil, err
+	}
+
+	records := make([]Record, 0, len(rows))
+	for _, row := range rows {
+		start := time.Unix(0, row.Timestamp)
+		record := Record{
+			Command:  row.Command,
+			Dir:      row.Cwd,
+			Session:  row.Session,
+			Start:    start,
+			End:      start,
+			ExitCode: max(row.Exit, 0),
+		}
+		record.Hostname, _, _ = strings.Cut(row.Hostname, ":")
+		if row.Duration > 0 {
+			record.End = start.Add(time.Duration(row.Duration))
+		}
+		records = append(records, record)
+	}
+	slices.Reverse(records)
+	return records, nil
+}
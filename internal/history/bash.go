@@ -0,0 +1,96 @@
+package history
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"supertab/internal/ai"
+)
+
+// bashSource reads a history file holding one command per line, as bash
+// writes it. With HISTTIMEFORMAT set, bash precedes each command with a
+// `#<epoch>` line, and a multi-line command (saved with lithist) spans the
+// lines up to the next one.
+type bashSource struct {
+	path string
+}
+
+// NewBashSource creates a source reading the bash history file at path
+func NewBashSource(path string) HistorySource {
+	return bashSource{path: path}
+}
+
+// Name returns the source name
+func (bashSource) Name() string { return "bash" }
+
+// Path returns the history file
+func (s bashSource) Path() string { return s.path }
+
+// Read returns the last limit entries of the history file
+func (s bashSource) Read(limit int) ([]ai.HistoryEntry, error) {
+	file, err := os.Open(s.path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	entries, err := decodeBashHistory(file)
+	if err != nil {
+		return nil, err
+	}
+	return lastEntries(entries, limit), nil
+}
+
+// decodeBashHistory decodes a bash history file. Entries without a
+// timestamp line have a zero Timestamp and are a line each, since nothing
+// marks where a multi-line command ends.
+func decodeBashHistory(r io.Reader) ([]ai.HistoryEntry, error) {
+	var entries []ai.HistoryEntry
+	var timestamp time.Time
+	grouping := false // whether lines join the last entry until the next timestamp
+
+	// A Reader rather than a Scanner, which gives up on lines over 64KB
+	reader := bufio.NewReader(r)
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+		line = strings.TrimRight(line, "\r\n")
+
+		if seconds, ok := bashTimestamp(strings.TrimSpace(line)); ok {
+			timestamp = time.Unix(seconds, 0)
+			grouping = false
+		} else if grouping {
+			last := &entries[len(entries)-1]
+			last.Command += "\n" + line
+		} else if command := strings.TrimSpace(line); command != "" {
+			entries = append(entries, ai.HistoryEntry{Command: command, Timestamp: timestamp})
+			grouping = !timestamp.IsZero()
+			timestamp = time.Time{}
+		}
+
+		if err == io.EOF {
+			break
+		}
+	}
+
+	for i := range entries {
+		entries[i].Command = strings.TrimSpace(entries[i].Command)
+	}
+	return entries, nil
+}
+
+// bashTimestamp parses a `#<epoch>` timestamp line
+func bashTimestamp(line string) (int64, bool) {
+	digits, ok := strings.CutPrefix(line, "#")
+	if !ok || digits == "" {
+		return 0, false
+	}
+	seconds, err := strconv.ParseInt(digits, 10, 64)
+	return seconds, err == nil && seconds > 0
+}
@@ -0,0 +1,76 @@
+package history
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"supertab/internal/ai"
+)
+
+func TestDecodeBashHistory(t *testing.T) {
+	tests := []struct {
+		name    string
+		history string
+		want    []ai.HistoryEntry
+	}{
+		{
+			name:    "plain",
+			history: "ls\n\ngit status  \n",
+			want:    []ai.HistoryEntry{{Command: "ls"}, {Command: "git status"}},
+		},
+		{
+			name:    "timestamps",
+			history: "#1700000000\nmake build\n#1700000060\nmake test\n",
+			want: []ai.HistoryEntry{
+				{Command: "make build", Timestamp: time.Unix(1700000000, 0)},
+				{Command: "make test", Timestamp: time.Unix(1700000060, 0)},
+			},
+		},
+		{
+			name:    "multi-line with timestamps",
+			history: "#1700000000\nfor f in *; do\n  echo $f\n\ndone\n#1700000001\npwd\n",
+			want: []ai.HistoryEntry{
+				{Command: "for f in *; do\n  echo $f\n\ndone", Timestamp: time.Unix(1700000000, 0)},
+				{Command: "pwd", Timestamp: time.Unix(1700000001, 0)},
+			},
+		},
+		{
+			name:    "timestamps turned on later",
+			history: "ls\ncd /tmp\n#1700000000\ncat <<EOF\nhi\nEOF\n",
+			want: []ai.HistoryEntry{
+				{Command: "ls"},
+				{Command: "cd /tmp"},
+				{Command: "cat <<EOF\nhi\nEOF", Timestamp: time.Unix(1700000000, 0)},
+			},
+		},
+		{
+			name:    "comments are commands",
+			history: "# a note\n#\n#0\n",
+			want:    []ai.HistoryEntry{{Command: "# a note"}, {Command: "#"}, {Command: "#0"}},
+		},
+		{
+			name:    "CRLF without trailing newline",
+			history: "#1700000000\r\nls\r\npwd",
+			want:    []ai.HistoryEntry{{Command: "ls\npwd", Timestamp: time.Unix(1700000000, 0)}},
+		},
+		{
+			name:    "timestamp without command",
+			history: "#1700000000\n#1700000001\n\nls\n",
+			want:    []ai.HistoryEntry{{Command: "ls", Timestamp: time.Unix(1700000001, 0)}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := decodeBashHistory(strings.NewReader(tt.history))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("decodeBashHistory() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,130 @@
+package history
+
+import (
+	"bufio"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"supertab/internal/ai"
+)
+
+// fishSource reads fish's history file, a YAML-like list with a `- cmd:`
+// item per command followed by its `when:` time and the `paths:` it referred to
+type fishSource struct {
+	path string
+}
+
+// NewFishSource creates a source reading the fish history file at path
+func NewFishSource(path string) HistorySource {
+	return fishSource{path: path}
+}
+
+// fishHistoryPath returns the history file of the fish session named by
+// $fish_history, "fish" unless set
+func fishHistoryPath() string {
+	session := os.Getenv("fish_history")
+	if session == "" {
+		session = "fish"
+	}
+	return filepath.Join(dataHome(), "fish", session+"_history")
+}
+
+// Name returns the source name
+func (fishSource) Name() string { return "fish" }
+
+// Path returns the history file
+func (s fishSource) Path() string { return s.path }
+
+// Read returns the last limit entries of the history file
+func (s fishSource) Read(limit int) ([]ai.HistoryEntry, error) {
+	file, err := os.Open(s.path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	entries, err := decodeFishHistory(file)
+	if err != nil {
+		return nil, err
+	}
+	return lastEntries(entries, limit), nil
+}
+
+// decodeFishHistory decodes a fish history file. fish does not write a
+// general YAML document, so the few line shapes it writes are parsed directly.
+func decodeFishHistory(r io.Reader) ([]ai.HistoryEntry, error) {
+	var entries []ai.HistoryEntry
+	var current *ai.HistoryEntry
+	inPaths := false
+
+	reader := bufio.NewReader(r)
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+		line = strings.TrimRight(line, "\r\n")
+
+		switch {
+		case strings.HasPrefix(line, "- cmd: "):
+			entries = append(entries, ai.HistoryEntry{Command: unescapeFish(strings.TrimPrefix(line, "- cmd: "))})
+			current = &entries[len(entries)-1]
+			inPaths = false
+		case current == nil:
+		case strings.HasPrefix(line, "  when: "):
+			if seconds, err := strconv.ParseInt(strings.TrimSpace(strings.TrimPrefix(line, "  when: ")), 10, 64); err == nil {
+				current.Timestamp = time.Unix(seconds, 0)
+			}
+			inPaths = false
+		case strings.HasPrefix(line, "  paths:"):
+			inPaths = true
+		case inPaths && strings.HasPrefix(line, "    - "):
+			current.Paths = append(current.Paths, unescapeFish(strings.TrimPrefix(line, "    - ")))
+		default:
+			inPaths = false
+		}
+
+		if err == io.EOF {
+			break
+		}
+	}
+
+	// Drop entries fish wrote without a command
+	valid := entries[:0]
+	for _, entry := range entries {
+		if strings.TrimSpace(entry.Command) != "" {
+			valid = append(valid, entry)
+		}
+	}
+	return valid, nil
+}
+
+// unescapeFish reverses the escaping fish applies to history values, which
+// writes backslashes as `\\` and newlines as `\n`
+func unescapeFish(value string) string {
+	if !strings.Contains(value, `\`) {
+		return value
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(value); i++ {
+		if value[i] == '\\' && i+1 < len(value) {
+			switch value[i+1] {
+			case '\\':
+				b.WriteByte('\\')
+				i++
+				continue
+			case 'n':
+				b.WriteByte('\n')
+				i++
+				continue
+			}
+		}
+		b.WriteByte(value[i])
+	}
+	return b.String()
+}
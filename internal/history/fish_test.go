@@ -0,0 +1,78 @@
+package history
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"supertab/internal/ai"
+)
+
+func TestDecodeFishHistory(t *testing.T) {
+	tests := []struct {
+		name    string
+		history string
+		want    []ai.HistoryEntry
+	}{
+		{
+			name:    "commands",
+			history: "- cmd: ls\n  when: 1700000000\n- cmd: git status\n  when: 1700000060\n",
+			want: []ai.HistoryEntry{
+				{Command: "ls", Timestamp: time.Unix(1700000000, 0)},
+				{Command: "git status", Timestamp: time.Unix(1700000060, 0)},
+			},
+		},
+		{
+			name:    "paths",
+			history: "- cmd: vim notes.md\n  when: 1700000000\n  paths:\n    - notes.md\n    - dir\\\\file\n- cmd: pwd\n",
+			want: []ai.HistoryEntry{
+				{Command: "vim notes.md", Timestamp: time.Unix(1700000000, 0), Paths: []string{"notes.md", `dir\file`}},
+				{Command: "pwd"},
+			},
+		},
+		{
+			name:    "escaped newline and backslash",
+			history: "- cmd: echo a\\\\b\\nc\n  when: 1700000000\n",
+			want:    []ai.HistoryEntry{{Command: "echo a\\b\nc", Timestamp: time.Unix(1700000000, 0)}},
+		},
+		{
+			name:    "unknown and malformed lines",
+			history: "  when: 1\ngarbage\n- cmd: ls\n  when: soon\n  other: x\n    - not a path\n- cmd: \n",
+			want:    []ai.HistoryEntry{{Command: "ls"}},
+		},
+		{
+			name:    "CRLF without trailing newline",
+			history: "- cmd: ls\r\n  when: 1700000000",
+			want:    []ai.HistoryEntry{{Command: "ls", Timestamp: time.Unix(1700000000, 0)}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := decodeFishHistory(strings.NewReader(tt.history))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("decodeFishHistory() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUnescapeFish(t *testing.T) {
+	tests := map[string]string{
+		`plain`:     "plain",
+		`a\\b`:      `a\b`,
+		`a\nb`:      "a\nb",
+		`a\tb`:      `a\tb`,
+		`trailing\`: `trailing\`,
+		`\\n`:       `\n`,
+	}
+	for value, want := range tests {
+		if got := unescapeFish(value); got != want {
+			t.Errorf("unescapeFish(%q) = %q, want %q", value, got, want)
+		}
+	}
+}
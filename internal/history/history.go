@@ -1,73 +1,47 @@
 package history
 
 import (
-	"bufio"
 	"os"
-	"path/filepath"
-	"strconv"
-	"strings"
 	"time"
 
 	"supertab/internal/ai"
 )
 
 // Parser handles shell history parsing
-type Parser struct{}
+type Parser struct {
+	source HistorySource
+	store  *Store
+}
 
-// NewParser creates a new history parser
+// NewParser creates a history parser for the user's shell that adds the
+// results recorded by the shell hooks to the entries of its history
 func NewParser() *Parser {
-	return &Parser{}
+	return NewParserFor(DefaultSource())
 }
 
-// GetRecentHistory retrieves recent command history entries with outputs
-func (p *Parser) GetRecentHistory(limit int) ([]ai.HistoryEntry, error) {
-	shell := os.Getenv("SHELL")
-
-	var historyFile string
-	switch {
-	case strings.Contains(shell, "zsh"):
-		historyFile = filepath.Join(os.Getenv("HOME"), ".zsh_history")
-	case strings.Contains(shell, "bash"):
-		historyFile = filepath.Join(os.Getenv("HOME"), ".bash_history")
-	default:
-		historyFile = filepath.Join(os.Getenv("HOME"), ".history")
-	}
-
-	entries, err := p.parseHistoryFile(historyFile, limit)
-	if err != nil {
-		return nil, err
-	}
-
-	// Try to get command outputs from enhanced history or cache
-	p.enrichWithOutputs(entries)
-
-	return entries, nil
+// NewParserFor creates a history parser reading source
+func NewParserFor(source HistorySource) *Parser {
+	return &Parser{source: source, store: NewStore(DefaultStorePath(), 0, 0)}
 }
 
-// parseHistoryFile parses the shell history file and returns recent entries
-func (p *Parser) parseHistoryFile(filename string, limit int) ([]ai.HistoryEntry, error) {
-	file, err := os.Open(filename)
+// GetRecentHistory retrieves recent command history entries with their exit
+// codes, durations and outputs where `sug record` recorded them. They are
+// served from the end of the store of recorded commands, and the shell's
+// history is parsed only when nothing was recorded since it last changed.
+func (p *Parser) GetRecentHistory(limit int) ([]ai.HistoryEntry, error) {
+	// Records cover at most the commands of the history, and usually fewer
+	// since shells that do not run the hooks record nothing
+	records, err := p.store.Recent(2 * limit)
 	if err != nil {
 		return nil, err
 	}
-	defer file.Close()
-
-	var entries []ai.HistoryEntry
-	scanner := bufio.NewScanner(file)
-
-	for scanner.Scan() {
-		line := strings.TrimSpace(scanner.Text())
-		if line == "" {
-			continue
-		}
-
-		entry := p.parseHistoryLine(line)
-		if entry.Command != "" {
-			entries = append(entries, entry)
-		}
+	if len(records) > 0 && !p.storeOutdated() {
+		return lastEntries(recordEntries(records), limit), nil
 	}
 
-	if err := scanner.Err(); err != nil {
+	// Ask for one more entry since the last one is left out below
+	entries, err := p.source.Read(limit + 1)
+	if err != nil {
 		return nil, err
 	}
 
@@ -75,87 +49,67 @@ func (p *Parser) parseHistoryFile(filename string, limit int) ([]ai.HistoryEntry
 	if len(entries) <= 1 {
 		return []ai.HistoryEntry{}, nil
 	}
+	entries = lastEntries(entries[:len(entries)-1], limit)
+	enrichWithRecords(entries, records)
 
-	// Remove the last entry (current command)
-	entries = entries[:len(entries)-1]
-
-	// Return the last 'limit' entries from the remaining history
-	start := len(entries) - limit
-	if start < 0 {
-		start = 0
-	}
-
-	return entries[start:], nil
+	return entries, nil
 }
 
-// parseHistoryLine parses a single history line into a HistoryEntry
-func (p *Parser) parseHistoryLine(line string) ai.HistoryEntry {
-	entry := ai.HistoryEntry{
-		Timestamp: time.Now(), // Default timestamp
-		ExitCode:  0,          // Default exit code
-	}
-
-	// Handle zsh extended history format: : <timestamp>:<duration>;<command>
-	if strings.HasPrefix(line, ": ") {
-		parts := strings.SplitN(line, ";", 2)
-		if len(parts) == 2 {
-			// Parse timestamp from format ": 1234567890:0;"
-			timestampPart := strings.TrimPrefix(parts[0], ": ")
-			if colonIndex := strings.Index(timestampPart, ":"); colonIndex != -1 {
-				timestampStr := timestampPart[:colonIndex]
-				if timestamp, err := strconv.ParseInt(timestampStr, 10, 64); err == nil {
-					entry.Timestamp = time.Unix(timestamp, 0)
-				}
-			}
-			entry.Command = parts[1]
-		} else {
-			entry.Command = line
-		}
-	} else {
-		entry.Command = line
+// storeOutdated reports whether the shell's history changed after the last
+// command was recorded, as it does once the hooks are switched off or when
+// the store only holds imported history
+func (p *Parser) storeOutdated() bool {
+	history, err := os.Stat(p.source.Path())
+	if err != nil {
+		return false
 	}
+	store, err := os.Stat(p.store.Path())
+	return err != nil || history.ModTime().After(store.ModTime())
+}
 
-	// Clean up the command
-	entry.Command = strings.TrimSpace(entry.Command)
-
-	return entry
+// HistoryFile returns the file the history is read from
+func (p *Parser) HistoryFile() string {
+	return p.source.Path()
 }
 
-// enrichWithOutputs attempts to enrich history entries with command outputs
-func (p *Parser) enrichWithOutputs(entries []ai.HistoryEntry) {
-	// TODO: Implement real command output retrieval from terminal history
-	// This is a dummy implementation for now. To get real command outputs, we would need:
-	// 1. Enhanced shell configuration to log outputs (e.g., script, tmux logging)
-	// 2. Integration with terminal emulators that support history with outputs
-	// 3. Custom shell hooks (precmd/preexec) to capture command results
+// StoreFile returns the file of recorded command results
+func (p *Parser) StoreFile() string {
+	return p.store.Path()
+}
 
-	for i := range entries {
+// enrichWithRecords fills in the results of entries from records of the same
+// command. Both are matched from the most recent backwards, so a command run
+// several times gets the results of each run.
+func enrichWithRecords(entries []ai.HistoryEntry, records []Record) {
+	next := len(records) - 1
+	for i := len(entries) - 1; i >= 0 && next >= 0; i-- {
 		entry := &entries[i]
 
-		// For now, we'll leave outputs empty or provide dummy data for common commands
-		entry.Output = ""
-		entry.ErrorOutput = ""
-		entry.ExitCode = 0
-		entry.Duration = ""
+		match := -1
+		for j := next; j >= 0; j-- {
+			if records[j].Command == entry.Command {
+				match = j
+				break
+			}
+		}
+		if match < 0 {
+			continue
+		}
 
-		// Provide some context for common commands without executing them
-		switch {
-		case strings.HasPrefix(entry.Command, "ls"):
-			entry.Output = "[command output not available]"
-		case strings.HasPrefix(entry.Command, "git status"):
-			entry.Output = "[git status output not available]"
-		case strings.HasPrefix(entry.Command, "kubectl"):
-			entry.Output = "[kubectl output not available]"
+		record := records[match]
+		// Imported records may lack what the history itself knows
+		if !record.Start.IsZero() {
+			entry.Timestamp = record.Start
 		}
+		if record.Dir != "" {
+			entry.Directory = record.Dir
+		}
+		entry.ExitCode = record.ExitCode
+		if duration := record.Duration(); duration > 0 {
+			entry.Duration = duration.Round(time.Millisecond).String()
+		}
+		entry.Output = record.Output
+		entry.ErrorOutput = record.ErrorOutput
+		next = match - 1
 	}
 }
-
-// Note: The following functions are placeholders for future implementation
-// of real command output retrieval from terminal history
-
-// TODO: Future enhancement - implement real command output retrieval
-// Possible approaches:
-// 1. Shell integration with logging (e.g., zsh precmd/preexec hooks)
-// 2. Terminal emulator integration (tmux, screen logging)
-// 3. System-level command auditing (auditd on Linux)
-// 4. Custom shell wrapper that logs all command I/O
@@ -0,0 +1,98 @@
+package history
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"supertab/internal/ai"
+)
+
+func TestGetRecentHistory(t *testing.T) {
+	start := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+	records := []Record{
+		{Command: "make build", Dir: "/src/app", Start: start, End: start.Add(2 * time.Second), ExitCode: 2, ErrorOutput: "undefined: x"},
+		{Command: "make test", Dir: "/src/app", Start: start.Add(time.Minute), End: start.Add(time.Minute)},
+	}
+	fromStore := []ai.HistoryEntry{
+		{Command: "make build", Directory: "/src/app", Timestamp: start, ExitCode: 2, Duration: "2s", ErrorOutput: "undefined: x"},
+		{Command: "make test", Directory: "/src/app", Timestamp: start.Add(time.Minute)},
+	}
+
+	tests := []struct {
+		name    string
+		records []Record
+		history time.Time // when the history file last changed, relative to the store
+		want    []ai.HistoryEntry
+	}{
+		{
+			name:    "nothing recorded",
+			history: start,
+			want:    []ai.HistoryEntry{{Command: "git status"}, {Command: "make build"}},
+		},
+		{
+			name:    "recorded since the history changed",
+			records: records,
+			history: start.Add(-time.Hour),
+			want:    fromStore,
+		},
+		{
+			name:    "history changed since",
+			records: records[:1],
+			history: start.Add(time.Hour),
+			want: []ai.HistoryEntry{
+				{Command: "git status"},
+				{Command: "make build", Directory: "/src/app", Timestamp: start, ExitCode: 2, Duration: "2s", ErrorOutput: "undefined: x"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			historyFile := filepath.Join(dir, "bash_history")
+			if err := os.WriteFile(historyFile, []byte("git status\nmake build\nsug predict\n"), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			store := NewStore(filepath.Join(dir, "history.jsonl"), 0, 0)
+			if _, err := store.Import(tt.records); err != nil {
+				t.Fatal(err)
+			}
+			os.Chtimes(store.Path(), start, start)
+			os.Chtimes(historyFile, tt.history, tt.history)
+
+			parser := &Parser{source: NewBashSource(historyFile), store: store}
+			got, err := parser.GetRecentHistory(2)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GetRecentHistory() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEnrichWithRecords(t *testing.T) {
+	start := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+	entries := []ai.HistoryEntry{{Command: "make"}, {Command: "ls", Timestamp: start}, {Command: "make"}, {Command: "pwd"}}
+	records := []Record{
+		{Command: "make", Start: start, End: start.Add(1500 * time.Millisecond), ExitCode: 2},
+		{Command: "ls"}, // imported, without times
+		{Command: "make", Start: start.Add(time.Minute), End: start.Add(time.Minute)},
+	}
+
+	enrichWithRecords(entries, records)
+
+	want := []ai.HistoryEntry{
+		{Command: "make", Timestamp: start, ExitCode: 2, Duration: "1.5s"},
+		{Command: "ls", Timestamp: start},
+		{Command: "make", Timestamp: start.Add(time.Minute)},
+		{Command: "pwd"},
+	}
+	if !reflect.DeepEqual(entries, want) {
+		t.Errorf("enrichWithRecords() = %+v, want %+v", entries, want)
+	}
+}
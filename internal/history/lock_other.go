@@ -0,0 +1,11 @@
+//go:build !unix
+
+package history
+
+import "os"
+
+// lockFile does nothing where advisory locks are not available; the store
+// relies on single appends there
+func lockFile(file *os.File) error {
+	return nil
+}
@@ -0,0 +1,86 @@
+package history
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"time"
+
+	"supertab/internal/ai"
+)
+
+// mcflySource reads the SQLite database of McFly, which records the
+// directory, exit code and session of every command
+type mcflySource struct {
+	path string
+}
+
+// NewMcflySource creates a source reading the McFly database at path
+func NewMcflySource(path string) HistorySource {
+	return mcflySource{path: path}
+}
+
+// mcflyDatabasePath returns the McFly database, in the legacy ~/.mcfly
+// directory when that exists and in the platform's data directory otherwise
+func mcflyDatabasePath() string {
+	legacy := filepath.Join(os.Getenv("HOME"), ".mcfly", "history.db")
+	if _, err := os.Stat(legacy); err == nil {
+		return legacy
+	}
+	if runtime.GOOS == "darwin" {
+		return filepath.Join(os.Getenv("HOME"), "Library", "Application Support", "McFly", "history.db")
+	}
+	return filepath.Join(dataHome(), "mcfly", "history.db")
+}
+
+// Name returns the source name
+func (mcflySource) Name() string { return "mcfly" }
+
+// Path returns the database file
+func (s mcflySource) Path() string { return s.path }
+
+// Read returns the last limit entries of the history
+func (s mcflySource) Read(limit int) ([]ai.HistoryEntry, error) {
+	records, err := s.Records(limit)
+	if err != nil {
+		return nil, err
+	}
+	return recordEntries(records), nil
+}
+
+// mcflyRow is a row of McFly's commands table; when_run is in seconds
+type mcflyRow struct {
+	Cmd       string `json:"cmd"`
+	WhenRun   int64  `json:"when_run"`
+	ExitCode  int    `json:"exit_code"`
+	Dir       string `json:"dir"`
+	SessionID string `json:"session_id"`
+}
+
+// Records returns the last limit commands with their metadata, oldest first.
+// McFly does not record durations.
+func (s mcflySource) Records(limit int) ([]Record, error) {
+	query := fmt.Sprintf("SELECT cmd, when_run, exit_code, dir, session_id FROM commands ORDER BY id DESC LIMIT %d", limit)
+
+	var rows []mcflyRow
+	if err := querySQLite(s.path, query, &rows); err != nil {
+		return nil, err
+	}
+
+	records := make([]Record, 0, len(rows))
+	for _, row := range rows {
+		start := time.Unix(row.WhenRun, 0)
+		records = append(records, Record{
+			Command:  row.Cmd,
+			Dir:      row.Dir,
+			Session:  row.SessionID,
+			Start:    start,
+			End:      start,
+			ExitCode: row.ExitCode,
+		})
+	}
+	slices.Reverse(records)
+	return records, nil
+}
@@ -0,0 +1,126 @@
+package history
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"supertab/internal/ai"
+)
+
+// nushellNewline is how nushell's plain-text history writes a newline inside
+// a command
+const nushellNewline = "<\\n>"
+
+// nushellSource reads nushell's history, kept either as plain text
+// (history.txt) or in SQLite (history.sqlite3) depending on its
+// history.file_format setting
+type nushellSource struct {
+	path string
+}
+
+// NewNushellSource creates a source reading the nushell history at path. A
+// path ending in .sqlite3 is read as a database.
+func NewNushellSource(path string) HistorySource {
+	return nushellSource{path: path}
+}
+
+// nushellHistoryPath returns the history nushell keeps in its config
+// directory, preferring the SQLite one when both exist
+func nushellHistoryPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		dir = filepath.Join(os.Getenv("HOME"), ".config")
+	}
+	database := filepath.Join(dir, "nushell", "history.sqlite3")
+	if _, err := os.Stat(database); err == nil {
+		return database
+	}
+	return filepath.Join(dir, "nushell", "history.txt")
+}
+
+// Name returns the source name
+func (nushellSource) Name() string { return "nushell" }
+
+// Path returns the history file
+func (s nushellSource) Path() string { return s.path }
+
+// Read returns the last limit entries of the history
+func (s nushellSource) Read(limit int) ([]ai.HistoryEntry, error) {
+	if strings.HasSuffix(s.path, ".sqlite3") {
+		return s.readDatabase(limit)
+	}
+
+	file, err := os.Open(s.path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	entries, err := decodeNushellHistory(file)
+	if err != nil {
+		return nil, err
+	}
+	return lastEntries(entries, limit), nil
+}
+
+// decodeNushellHistory decodes nushell's plain-text history, a command per
+// line
+func decodeNushellHistory(r io.Reader) ([]ai.HistoryEntry, error) {
+	var entries []ai.HistoryEntry
+	reader := bufio.NewReader(r)
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+		if command := strings.TrimSpace(strings.ReplaceAll(line, nushellNewline, "\n")); command != "" {
+			entries = append(entries, ai.HistoryEntry{Command: command})
+		}
+		if err == io.EOF {
+			return entries, nil
+		}
+	}
+}
+
+// nushellRow is a row of the history table of nushell's database
+type nushellRow struct {
+	CommandLine    string `json:"command_line"`
+	StartTimestamp int64  `json:"start_timestamp"` // milliseconds since the epoch
+	Cwd            string `json:"cwd"`
+	DurationMs     int64  `json:"duration_ms"`
+	ExitStatus     int    `json:"exit_status"`
+}
+
+// readDatabase reads the last limit entries of nushell's SQLite history
+func (s nushellSource) readDatabase(limit int) ([]ai.HistoryEntry, error) {
+	query := fmt.Sprintf("SELECT command_line, start_timestamp, cwd, duration_ms, exit_status FROM history ORDER BY id DESC LIMIT %d", limit)
+
+	var rows []nushellRow
+	if err := querySQLite(s.path, query, &rows); err != nil {
+		return nil, err
+	}
+
+	entries := make([]ai.HistoryEntry, 0, len(rows))
+	for _, row := range rows {
+		entry := ai.HistoryEntry{
+			Command:   row.CommandLine,
+			Directory: row.Cwd,
+			ExitCode:  row.ExitStatus,
+		}
+		if row.StartTimestamp > 0 {
+			entry.Timestamp = time.UnixMilli(row.StartTimestamp)
+		}
+		if row.DurationMs > 0 {
+			entry.Duration = (time.Duration(row.DurationMs) * time.Millisecond).String()
+		}
+		entries = append(entries, entry)
+	}
+	slices.Reverse(entries)
+	return entries, nil
+}
@@ -0,0 +1,61 @@
+package history
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"supertab/internal/ai"
+)
+
+func TestDecodeNushellHistory(t *testing.T) {
+	tests := []struct {
+		name    string
+		history string
+		want    []ai.HistoryEntry
+	}{
+		{"commands", "ls\ngit status\n", []ai.HistoryEntry{{Command: "ls"}, {Command: "git status"}}},
+		{"multi-line", "def greet [] {<\\n>  'hi'<\\n>}\npwd\n", []ai.HistoryEntry{{Command: "def greet [] {\n  'hi'\n}"}, {Command: "pwd"}}},
+		{"blank lines", "\n  \nls", []ai.HistoryEntry{{Command: "ls"}}},
+		{"empty", "", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := decodeNushellHistory(strings.NewReader(tt.history))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("decodeNushellHistory() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNushellSourceDatabase(t *testing.T) {
+	// A stand-in for sqlite3 printing rows newest first, as the query asks
+	bin := t.TempDir()
+	script := "#!/bin/sh\necho '" +
+		`[{"command_line":"cargo test","start_timestamp":1700000060500,"cwd":"/src/app","duration_ms":1500,"exit_status":101},` +
+		`{"command_line":"cd /src/app","start_timestamp":0,"cwd":"/","duration_ms":0,"exit_status":0}]` + "'\n"
+	if err := os.WriteFile(filepath.Join(bin, "sqlite3"), []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", bin)
+
+	got, err := NewNushellSource(filepath.Join(t.TempDir(), "history.sqlite3")).Read(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []ai.HistoryEntry{
+		{Command: "cd /src/app", Directory: "/"},
+		{Command: "cargo test", Directory: "/src/app", Timestamp: time.UnixMilli(1700000060500), Duration: "1.5s", ExitCode: 101},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Read() = %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,153 @@
+package history
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"supertab/internal/ai"
+)
+
+// HistorySource reads the command history kept by a shell or history tool
+type HistorySource interface {
+	// Name identifies the source, e.g. "zsh" or "fish"
+	Name() string
+
+	// Path returns the file the history is read from, which callers may
+	// watch for changes
+	Path() string
+
+	// Read returns the last limit entries, oldest first
+	Read(limit int) ([]ai.HistoryEntry, error)
+}
+
+// RecordSource is implemented by history sources that keep the metadata of
+// a Record for each command, such as its directory, session and host
+type RecordSource interface {
+	HistorySource
+
+	// Records returns the last limit commands, oldest first
+	Records(limit int) ([]Record, error)
+}
+
+// SourceNames lists the names SourceByName accepts besides "auto"
+var SourceNames = []string{"zsh", "bash", "fish", "nushell", "atuin", "mcfly"}
+
+// SourceByName returns the named history source at its default location.
+// "auto" and "" select AutoSource.
+func SourceByName(name string) (HistorySource, error) {
+	switch name {
+	case "", "auto":
+		return AutoSource(), nil
+	case "zsh", "bash", "fish", "nushell":
+		return SourceForShell(name), nil
+	case "atuin":
+		return NewAtuinSource(atuinDatabasePath()), nil
+	case "mcfly":
+		return NewMcflySource(mcflyDatabasePath()), nil
+	}
+	return nil, fmt.Errorf("unknown history source %q (want auto, %s)", name, strings.Join(SourceNames, ", "))
+}
+
+// SourceAt returns the named history source reading path, so a source can be
+// recreated from its Name and Path in another process, such as the daemon
+func SourceAt(name, path string) (HistorySource, error) {
+	switch name {
+	case "zsh":
+		return NewZshSource(path), nil
+	case "bash":
+		return NewBashSource(path), nil
+	case "fish":
+		return NewFishSource(path), nil
+	case "nushell":
+		return NewNushellSource(path), nil
+	case "atuin":
+		return NewAtuinSource(path), nil
+	case "mcfly":
+		return NewMcflySource(path), nil
+	}
+	return nil, fmt.Errorf("unknown history source %q (want %s)", name, strings.Join(SourceNames, ", "))
+}
+
+// AutoSource returns the Atuin or McFly database when one exists and the
+// sqlite3 command can read it, since they know the directory and exit code of
+// every command, and the history of the user's shell otherwise
+func AutoSource() HistorySource {
+	if _, err := exec.LookPath("sqlite3"); err == nil {
+		for _, source := range []HistorySource{NewAtuinSource(atuinDatabasePath()), NewMcflySource(mcflyDatabasePath())} {
+			if _, err := os.Stat(source.Path()); err == nil {
+				return source
+			}
+		}
+	}
+	return DefaultSource()
+}
+
+// DefaultSource returns the history source of the user's shell
+func DefaultSource() HistorySource {
+	return SourceForShell(os.Getenv("SHELL"))
+}
+
+// SourceForShell returns the history source of shell, a shell name or path.
+// zsh and bash honor $HISTFILE when it is exported.
+func SourceForShell(shell string) HistorySource {
+	switch name := filepath.Base(shell); {
+	case strings.Contains(name, "zsh"):
+		return NewZshSource(histFile(".zsh_history"))
+	case strings.Contains(name, "bash"):
+		return NewBashSource(histFile(".bash_history"))
+	case strings.Contains(name, "fish"):
+		return NewFishSource(fishHistoryPath())
+	case name == "nu" || name == "nushell":
+		return NewNushellSource(nushellHistoryPath())
+	default:
+		return NewBashSource(filepath.Join(os.Getenv("HOME"), ".history"))
+	}
+}
+
+// histFile returns $HISTFILE, falling back to name in the home directory
+func histFile(name string) string {
+	if path := os.Getenv("HISTFILE"); path != "" {
+		return path
+	}
+	return filepath.Join(os.Getenv("HOME"), name)
+}
+
+// dataHome returns $XDG_DATA_HOME, falling back to ~/.local/share
+func dataHome() string {
+	if dir := os.Getenv("XDG_DATA_HOME"); dir != "" {
+		return dir
+	}
+	return filepath.Join(os.Getenv("HOME"), ".local", "share")
+}
+
+// recordEntries converts records into history entries
+func recordEntries(records []Record) []ai.HistoryEntry {
+	entries := make([]ai.HistoryEntry, 0, len(records))
+	for _, record := range records {
+		entry := ai.HistoryEntry{
+			Command:     record.Command,
+			Directory:   record.Dir,
+			Timestamp:   record.Start,
+			ExitCode:    record.ExitCode,
+			Output:      record.Output,
+			ErrorOutput: record.ErrorOutput,
+		}
+		if duration := record.Duration(); duration > 0 {
+			entry.Duration = duration.Round(time.Millisecond).String()
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+// lastEntries returns the last limit entries
+func lastEntries(entries []ai.HistoryEntry, limit int) []ai.HistoryEntry {
+	if limit >= 0 && len(entries) > limit {
+		return entries[len(entries)-limit:]
+	}
+	return entries
+}
@@ -0,0 +1,38 @@
+package history
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"time"
+)
+
+// sqliteTimeout bounds a query of a history database
+const sqliteTimeout = 2 * time.Second
+
+// querySQLite runs query against the database at path with the sqlite3
+// command-line tool and decodes the rows into rows. The database is opened
+// read-only so the shell or tool owning it is never disturbed.
+func querySQLite(path, query string, rows any) error {
+	if _, err := exec.LookPath("sqlite3"); err != nil {
+		return fmt.Errorf("reading %s needs the sqlite3 command: %w", path, err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), sqliteTimeout)
+	defer cancel()
+
+	output, err := exec.CommandContext(ctx, "sqlite3", "-readonly", "-json", path, query).Output()
+	if err != nil {
+		return fmt.Errorf("failed to query %s: %w", path, err)
+	}
+	// sqlite3 prints nothing rather than an empty array when no rows match
+	if len(bytes.TrimSpace(output)) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(output, rows); err != nil {
+		return fmt.Errorf("failed to decode rows of %s: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,477 @@
+package history
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"supertab/internal/cache"
+)
+
+const (
+	// MaxOutputBytes bounds the output kept per stream of a recorded command
+	MaxOutputBytes = 2048
+
+	// DefaultMaxRecords is how many records a store keeps
+	DefaultMaxRecords = 50000
+
+	// DefaultMaxAge is how long a store keeps a record
+	DefaultMaxAge = 365 * 24 * time.Hour
+)
+
+// trimInterval is how much a store grows between trims. Trimming reads the
+// whole store, so Append does it only when the store crosses a multiple of
+// this size.
+var trimInterval int64 = 1 << 20
+
+// Record is a command recorded by the shell hooks
+type Record struct {
+	Command     string    `json:"command"`
+	Dir         string    `json:"dir,omitempty"`
+	Repo        string    `json:"repo,omitempty"`   // root of the git repository around Dir
+	Branch      string    `json:"branch,omitempty"` // git branch checked out when the command ran
+	Hostname    string    `json:"hostname,omitempty"`
+	Session     string    `json:"session,omitempty"` // identifies the shell the command ran in
+	Start       time.Time `json:"start"`
+	End         time.Time `json:"end"`
+	ExitCode    int       `json:"exit_code"`
+	Suggestion  string    `json:"suggestion,omitempty"`   // the completion or prediction shown for the command line, if any
+	Output      string    `json:"output,omitempty"`       // tail of stdout, when output capture is on
+	ErrorOutput string    `json:"error_output,omitempty"` // tail of stderr, when output capture is on
+}
+
+// Duration returns how long the command ran
+func (r Record) Duration() time.Duration {
+	if r.End.Before(r.Start) {
+		return 0
+	}
+	return r.End.Sub(r.Start)
+}
+
+// Store is an append-only JSONL file of recorded commands. It keeps at most
+// maxRecords records, none of which ended more than maxAge ago.
+type Store struct {
+	path       string
+	maxRecords int
+	maxAge     time.Duration
+}
+
+// DefaultStorePath returns $XDG_DATA_HOME/sug/history.jsonl, falling back to
+// ~/.local/share/sug/history.jsonl
+func DefaultStorePath() string {
+	return filepath.Join(dataHome(), "sug", "history.jsonl")
+}
+
+// NewStore creates a store backed by the file at path. Zero maxRecords or
+// maxAge select the defaults.
+func NewStore(path string, maxRecords int, maxAge time.Duration) *Store {
+	if maxRecords <= 0 {
+		maxRecords = DefaultMaxRecords
+	}
+	if maxAge <= 0 {
+		maxAge = DefaultMaxAge
+	}
+	return &Store{path: path, maxRecords: maxRecords, maxAge: maxAge}
+}
+
+// Path returns the file backing the store
+func (s *Store) Path() string {
+	return s.path
+}
+
+// lock takes the store's lock, which Append, trim and Import hold while they
+// write, so records appended by concurrent shells are not lost to a rewrite.
+// Readers do not need it since rewrites replace the file atomically.
+func (s *Store) lock() (unlock func(), err error) {
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create history directory: %w", err)
+	}
+	file, err := os.OpenFile(s.path+".lock", os.O_RDWR|os.O_CREATE, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock history store: %w", err)
+	}
+	if err := lockFile(file); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to lock history store: %w", err)
+	}
+	// Closing the file releases the lock
+	return func() { file.Close() }, nil
+}
+
+// Append adds a record to the store. Every trimInterval bytes, the store is
+// trimmed to its limits.
+func (s *Store) Append(record Record) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("failed to encode record: %w", err)
+	}
+
+	unlock, err := s.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	file, err := os.OpenFile(s.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to open history store: %w", err)
+	}
+	data = append(data, '\n')
+	_, err = file.Write(data)
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("failed to write history store: %w", err)
+	}
+	info, err := file.Stat()
+	file.Close()
+
+	if err == nil && info.Size()/trimInterval != (info.Size()-int64(len(data)))/trimInterval {
+		return s.trim(time.Now())
+	}
+	return nil
+}
+
+// trim rewrites the store without the records beyond its limits. The caller
+// holds the store's lock.
+func (s *Store) trim(now time.Time) error {
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		return fmt.Errorf("failed to read history store: %w", err)
+	}
+
+	var records []Record
+	for _, line := range bytes.Split(data, []byte("\n")) {
+		var record Record
+		if json.Unmarshal(line, &record) == nil && record.Command != "" {
+			records = append(records, record)
+		}
+	}
+	kept := s.retain(records, now)
+	if len(kept) == len(records) {
+		return nil
+	}
+
+	trimmed, err := encodeRecords(kept)
+	if err != nil {
+		return err
+	}
+	if err := cache.WriteFileAtomic(s.path, trimmed); err != nil {
+		return fmt.Errorf("failed to write history store: %w", err)
+	}
+	return nil
+}
+
+// retain returns the records within the store's limits, keeping their order
+func (s *Store) retain(records []Record, now time.Time) []Record {
+	var kept []Record
+	for _, i := range s.retained(records, now) {
+		kept = append(kept, records[i])
+	}
+	return kept
+}
+
+// retained returns the positions of the records within the store's limits:
+// of those that ended within maxAge of now, or at an unknown time, the last
+// maxRecords
+func (s *Store) retained(records []Record, now time.Time) []int {
+	cutoff := now.Add(-s.maxAge)
+	var kept []int
+	for i, record := range records {
+		if record.End.IsZero() || !record.End.Before(cutoff) {
+			kept = append(kept, i)
+		}
+	}
+	if len(kept) > s.maxRecords {
+		kept = kept[len(kept)-s.maxRecords:]
+	}
+	return kept
+}
+
+// encodeRecords returns records as JSON lines
+func encodeRecords(records []Record) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, record := range records {
+		if err := enc.Encode(record); err != nil {
+			return nil, fmt.Errorf("failed to encode record: %w", err)
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+// Import merges records into the store and returns how many new ones it
+// kept. A record is already present when a record of the same command started
+// at the same time. Records without a start time, as imported from shell
+// histories without timestamps, are told apart by how often the command
+// occurred before, so repeats are kept and importing again adds nothing. The
+// store is rewritten in the order commands ended, which queries rely on, and
+// trimmed to its limits.
+func (s *Store) Import(records []Record) (int, error) {
+	unlock, err := s.lock()
+	if err != nil {
+		return 0, err
+	}
+	defer unlock()
+
+	existing, err := s.Query(Query{})
+	if err != nil {
+		return 0, err
+	}
+
+	seen := make(map[importKey]bool, len(existing))
+	for _, k := range importKeys(existing) {
+		seen[k] = true
+	}
+
+	merged := existing
+	for i, k := range importKeys(records) {
+		if records[i].Command == "" || seen[k] {
+			continue
+		}
+		seen[k] = true
+		merged = append(merged, records[i])
+	}
+	if len(merged) == len(existing) {
+		return 0, nil
+	}
+
+	// Sort positions rather than records to tell which new ones trimming keeps
+	order := make([]int, len(merged))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool { return merged[order[i]].End.Before(merged[order[j]].End) })
+	sorted := make([]Record, len(merged))
+	for i, j := range order {
+		sorted[i] = merged[j]
+	}
+
+	kept := 0
+	merged = merged[:0:0]
+	for _, i := range s.retained(sorted, time.Now()) {
+		if order[i] >= len(existing) {
+			kept++
+		}
+		merged = append(merged, sorted[i])
+	}
+
+	data, err := encodeRecords(merged)
+	if err != nil {
+		return 0, err
+	}
+	if err := cache.WriteFileAtomic(s.path, data); err != nil {
+		return 0, fmt.Errorf("failed to write history store: %w", err)
+	}
+	return kept, nil
+}
+
+// importKey identifies a record for Import
+type importKey struct {
+	command string
+	start   int64
+	repeat  int // earlier records of the command without a start time
+}
+
+// importKeys returns the key of each of records
+func importKeys(records []Record) []importKey {
+	keys := make([]importKey, len(records))
+	repeats := make(map[string]int)
+	for i, record := range records {
+		keys[i] = importKey{command: record.Command, start: record.Start.UnixNano()}
+		if record.Start.IsZero() {
+			keys[i].repeat = repeats[record.Command]
+			repeats[record.Command]++
+		}
+	}
+	return keys
+}
+
+// Query selects records from a store. Zero fields match every record.
+type Query struct {
+	Dir     string    // commands run in Dir
+	Repo    string    // commands run anywhere in the repository rooted at Repo
+	Session string    // commands run in the shell session
+	Since   time.Time // commands started at or after Since
+	Until   time.Time // commands started before Until
+	Prefix  string    // commands starting with Prefix
+	Limit   int       // the most recent Limit matches; 0 means all
+}
+
+// reorderSlack bounds how much earlier than the record before it a record
+// may have ended. Records are appended when commands end, by a background
+// process per command, so they are almost but not exactly in order.
+const reorderSlack = time.Minute
+
+// Match reports whether record is selected by q, ignoring Limit
+func (q Query) Match(record Record) bool {
+	switch {
+	case q.Dir != "" && record.Dir != q.Dir:
+		return false
+	case q.Repo != "" && record.Repo != q.Repo:
+		return false
+	case q.Session != "" && record.Session != q.Session:
+		return false
+	case !q.Since.IsZero() && record.Start.Before(q.Since):
+		return false
+	case !q.Until.IsZero() && !record.Start.Before(q.Until):
+		return false
+	case q.Prefix != "" && !strings.HasPrefix(record.Command, q.Prefix):
+		return false
+	}
+	return true
+}
+
+// Query returns the records selected by q, oldest first. The store is read
+// from its end and only as far back as q needs, so recent lookups stay fast
+// as the store grows. A missing store holds no records.
+func (s *Store) Query(q Query) ([]Record, error) {
+	file, err := os.Open(s.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var records []Record
+	err = readLinesBackward(file, func(line []byte) bool {
+		var record Record
+		// Skip lines a crashed writer left incomplete
+		if json.Unmarshal(line, &record) != nil || record.Command == "" {
+			return true
+		}
+		if !q.Since.IsZero() && record.End.Before(q.Since.Add(-reorderSlack)) {
+			return false
+		}
+		if q.Match(record) {
+			records = append(records, record)
+		}
+		return q.Limit <= 0 || len(records) < q.Limit
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	slices.Reverse(records)
+	return records, nil
+}
+
+// Recent returns the last limit records, oldest first
+func (s *Store) Recent(limit int) ([]Record, error) {
+	return s.Query(Query{Limit: limit})
+}
+
+// InDir returns the last limit commands run in dir, oldest first
+func (s *Store) InDir(dir string, limit int) ([]Record, error) {
+	return s.Query(Query{Dir: dir, Limit: limit})
+}
+
+// InSession returns the last limit commands of a shell session, oldest first
+func (s *Store) InSession(session string, limit int) ([]Record, error) {
+	return s.Query(Query{Session: session, Limit: limit})
+}
+
+// Between returns the commands started in [since, until), oldest first
+func (s *Store) Between(since, until time.Time) ([]Record, error) {
+	return s.Query(Query{Since: since, Until: until})
+}
+
+// WithPrefix returns the last limit commands starting with prefix, oldest first
+func (s *Store) WithPrefix(prefix string, limit int) ([]Record, error) {
+	return s.Query(Query{Prefix: prefix, Limit: limit})
+}
+
+// backwardChunk is how much of a file readLinesBackward reads at a time
+const backwardChunk = 64 << 10
+
+// readLinesBackward calls fn with each non-empty line of file, last line
+// first, until fn returns false
+func readLinesBackward(file *os.File, fn func(line []byte) bool) error {
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+
+	offset := info.Size()
+	var rest []byte // start of the line that continues into the chunk read before
+	for offset > 0 {
+		size := int64(backwardChunk)
+		if offset < size {
+			size = offset
+		}
+		offset -= size
+
+		chunk := make([]byte, size, size+int64(len(rest)))
+		if _, err := file.ReadAt(chunk, offset); err != nil && err != io.EOF {
+			return err
+		}
+		chunk = append(chunk, rest...)
+
+		// Every line but the first is complete; the first may continue in the
+		// chunk before unless this is the start of the file
+		for {
+			i := bytes.LastIndexByte(chunk, '\n')
+			if i < 0 {
+				break
+			}
+			if line := chunk[i+1:]; len(line) > 0 && !fn(line) {
+				return nil
+			}
+			chunk = chunk[:i]
+		}
+		rest = chunk
+	}
+
+	if len(rest) > 0 {
+		fn(rest)
+	}
+	return nil
+}
+
+// ansiEscape matches terminal escape sequences such as colors
+var ansiEscape = regexp.MustCompile(`\x1b(\[[0-9;?]*[ -/]*[@-~]|\][^\x07]*\x07|[@-Z\\-_])`)
+
+// OutputTail returns the last MaxOutputBytes of the output in the file at
+// path, starting at a line boundary and without terminal escape sequences
+func OutputTail(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return "", err
+	}
+
+	// Read a little more than needed since escape sequences are dropped
+	offset := info.Size() - 4*MaxOutputBytes
+	if offset < 0 {
+		offset = 0
+	}
+	data, err := io.ReadAll(io.NewSectionReader(file, offset, info.Size()-offset))
+	if err != nil {
+		return "", err
+	}
+
+	output := ansiEscape.ReplaceAllString(string(data), "")
+	output = strings.ReplaceAll(output, "\r\n", "\n")
+	if len(output) > MaxOutputBytes {
+		output = output[len(output)-MaxOutputBytes:]
+		if i := strings.IndexByte(output, '\n'); i >= 0 {
+			output = output[i+1:]
+		}
+	}
+	return strings.TrimSpace(strings.ToValidUTF8(output, "")), nil
+}
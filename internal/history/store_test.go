@@ -0,0 +1,221 @@
+package history
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// commands returns the commands of records
+func commands(records []Record) []string {
+	var commands []string
+	for _, record := range records {
+		commands = append(commands, record.Command)
+	}
+	return commands
+}
+
+func TestStoreRetain(t *testing.T) {
+	now := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+	records := []Record{
+		{Command: "old", End: now.Add(-48 * time.Hour)},
+		{Command: "untimed"},
+		{Command: "hour", End: now.Add(-time.Hour)},
+		{Command: "minute", End: now.Add(-time.Minute)},
+	}
+
+	tests := []struct {
+		name       string
+		maxRecords int
+		maxAge     time.Duration
+		want       []string
+	}{
+		{"within limits", 10, 72 * time.Hour, []string{"old", "untimed", "hour", "minute"}},
+		{"too old", 10, 24 * time.Hour, []string{"untimed", "hour", "minute"}},
+		{"too many", 2, 72 * time.Hour, []string{"hour", "minute"}},
+		{"both", 1, 30 * time.Minute, []string{"minute"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := NewStore("", tt.maxRecords, tt.maxAge)
+			if got := commands(store.retain(records, now)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("retain() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewStoreDefaults(t *testing.T) {
+	store := NewStore("history.jsonl", 0, 0)
+	if store.maxRecords != DefaultMaxRecords || store.maxAge != DefaultMaxAge {
+		t.Errorf("NewStore() limits = %d, %s", store.maxRecords, store.maxAge)
+	}
+}
+
+func TestStoreAppendTrims(t *testing.T) {
+	defer func(interval int64) { trimInterval = interval }(trimInterval)
+	trimInterval = 1 // every append crosses a multiple
+
+	path := filepath.Join(t.TempDir(), "history.jsonl")
+	store := NewStore(path, 3, time.Hour)
+	now := time.Now()
+
+	// An expired record and a line a crashed writer left behind go first
+	if err := store.Append(Record{Command: "expired", End: now.Add(-2 * time.Hour)}); err != nil {
+		t.Fatal(err)
+	}
+	file, _ := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0o600)
+	file.WriteString("{\"command\":\"cut\n")
+	file.Close()
+
+	for i := 0; i < 10; i++ {
+		if err := store.Append(Record{Command: "cmd " + strings.Repeat("x", i), End: now}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	records, err := store.Recent(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"cmd xxxxxxx", "cmd xxxxxxxx", "cmd xxxxxxxxx"}; !reflect.DeepEqual(commands(records), want) {
+		t.Errorf("store = %v, want %v", commands(records), want)
+	}
+}
+
+func TestStoreConcurrentAppends(t *testing.T) {
+	defer func(interval int64) { trimInterval = interval }(trimInterval)
+	trimInterval = 1 // every append trims, racing the others
+
+	path := filepath.Join(t.TempDir(), "history.jsonl")
+	now := time.Now()
+	const shells, commandsPerShell = 8, 20
+
+	// Each shell records through a store of its own, as sug record does
+	var wg sync.WaitGroup
+	errs := make(chan error, 2*shells*commandsPerShell)
+	for i := 0; i < shells; i++ {
+		wg.Add(1)
+		go func(shell int) {
+			defer wg.Done()
+			store := NewStore(path, 1000, time.Hour)
+			for j := 0; j < commandsPerShell; j++ {
+				// An expired record makes the next trim rewrite the store
+				errs <- store.Append(Record{Command: "expired", End: now.Add(-2 * time.Hour)})
+				errs <- store.Append(Record{Command: fmt.Sprintf("cmd %d %d", shell, j), End: now})
+			}
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	records, err := NewStore(path, 1000, time.Hour).Recent(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != shells*commandsPerShell {
+		t.Errorf("store holds %d records, want %d", len(records), shells*commandsPerShell)
+	}
+}
+
+func TestStoreImport(t *testing.T) {
+	now := time.Now()
+	at := func(minutes int) time.Time { return now.Add(time.Duration(minutes) * time.Minute) }
+	timed := func(command string, minutes int) Record {
+		return Record{Command: command, Start: at(minutes), End: at(minutes)}
+	}
+	untimed := func(commands ...string) []Record {
+		var records []Record
+		for _, command := range commands {
+			records = append(records, Record{Command: command})
+		}
+		return records
+	}
+
+	tests := []struct {
+		name       string
+		existing   []Record
+		imported   []Record
+		maxRecords int
+		wantAdded  int
+		want       []string
+	}{
+		{
+			name:      "new records in the order they ended",
+			existing:  []Record{timed("make", -10)},
+			imported:  []Record{timed("ls", -5), timed("git pull", -30), {Command: ""}},
+			wantAdded: 2,
+			want:      []string{"git pull", "make", "ls"},
+		},
+		{
+			name:      "timed repeats collapse",
+			existing:  []Record{timed("make", -10)},
+			imported:  []Record{timed("make", -10), timed("ls", -5), timed("ls", -5)},
+			wantAdded: 1,
+			want:      []string{"make", "ls"},
+		},
+		{
+			name:      "untimed repeats are kept",
+			imported:  untimed("ls", "cd /tmp", "ls", "ls"),
+			wantAdded: 4,
+			want:      []string{"ls", "cd /tmp", "ls", "ls"},
+		},
+		{
+			name:      "importing untimed history again adds nothing",
+			existing:  untimed("ls", "cd /tmp", "ls"),
+			imported:  untimed("ls", "cd /tmp", "ls"),
+			wantAdded: 0,
+			want:      []string{"ls", "cd /tmp", "ls"},
+		},
+		{
+			name:      "a longer untimed history adds its extra repeats",
+			existing:  untimed("ls", "pwd"),
+			imported:  untimed("ls", "pwd", "ls"),
+			wantAdded: 1,
+			want:      []string{"ls", "pwd", "ls"},
+		},
+		{
+			name:       "trimming counts only the new records kept",
+			existing:   []Record{timed("make", -10)},
+			imported:   append(untimed("ls", "ls"), timed("go test", -3)),
+			maxRecords: 2,
+			wantAdded:  1,
+			want:       []string{"make", "go test"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := NewStore(filepath.Join(t.TempDir(), "history.jsonl"), tt.maxRecords, 0)
+			for _, record := range tt.existing {
+				if err := store.Append(record); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			added, err := store.Import(tt.imported)
+			if err != nil {
+				t.Fatal(err)
+			}
+			records, _ := store.Recent(0)
+			if added != tt.wantAdded || !reflect.DeepEqual(commands(records), tt.want) {
+				t.Errorf("Import() = %d, store %v; want %d, %v", added, commands(records), tt.wantAdded, tt.want)
+			}
+
+			if added, err := store.Import(tt.imported); added != 0 || err != nil {
+				t.Errorf("importing again = %d, %v", added, err)
+			}
+		})
+	}
+}
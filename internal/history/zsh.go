@@ -0,0 +1,121 @@
+package history
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"supertab/internal/ai"
+)
+
+// zshMeta is the byte zsh writes before a byte it escaped in the history
+// file; the escaped byte follows XORed with 32
+const zshMeta = 0x83
+
+// zshSource reads a zsh history file
+type zshSource struct {
+	path string
+}
+
+// NewZshSource creates a source reading the zsh history file at path
+func NewZshSource(path string) HistorySource {
+	return zshSource{path: path}
+}
+
+// Name returns the source name
+func (zshSource) Name() string { return "zsh" }
+
+// Path returns the history file
+func (s zshSource) Path() string { return s.path }
+
+// Read returns the last limit entries of the history file
+func (s zshSource) Read(limit int) ([]ai.HistoryEntry, error) {
+	file, err := os.Open(s.path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	entries, err := decodeZshHistory(file)
+	if err != nil {
+		return nil, err
+	}
+	return lastEntries(entries, limit), nil
+}
+
+// decodeZshHistory decodes a zsh history file, in the extended format
+// (`: <start>:<elapsed>;<command>`) or the plain one. Commands spanning
+// several lines, which zsh writes with a backslash before each newline, come
+// back as one entry.
+func decodeZshHistory(r io.Reader) ([]ai.HistoryEntry, error) {
+	var entries []ai.HistoryEntry
+	reader := bufio.NewReader(r)
+
+	var pending []byte // the lines of a command continued with a backslash
+	for {
+		line, err := reader.ReadBytes('\n')
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+		line = bytes.TrimSuffix(bytes.TrimSuffix(line, []byte("\n")), []byte("\r"))
+
+		if len(line) > 0 || len(pending) > 0 {
+			if bytes.HasSuffix(line, []byte(`\`)) && err == nil {
+				pending = append(pending, line[:len(line)-1]...)
+				pending = append(pending, '\n')
+				continue
+			}
+			pending = append(pending, line...)
+			if entry, ok := parseZshEntry(unmetafy(pending)); ok {
+				entries = append(entries, entry)
+			}
+			pending = pending[:0]
+		}
+
+		if err == io.EOF {
+			return entries, nil
+		}
+	}
+}
+
+// parseZshEntry parses a complete history entry
+func parseZshEntry(data []byte) (ai.HistoryEntry, bool) {
+	text := string(data)
+	var entry ai.HistoryEntry
+
+	if header, command, ok := strings.Cut(text, ";"); ok && strings.HasPrefix(header, ": ") {
+		start, elapsed, _ := strings.Cut(strings.TrimPrefix(header, ": "), ":")
+		if seconds, err := strconv.ParseInt(strings.TrimSpace(start), 10, 64); err == nil {
+			entry.Timestamp = time.Unix(seconds, 0)
+			text = command
+			if seconds, err := strconv.ParseInt(strings.TrimSpace(elapsed), 10, 64); err == nil && seconds > 0 {
+				entry.Duration = (time.Duration(seconds) * time.Second).String()
+			}
+		}
+	}
+
+	entry.Command = strings.TrimSpace(text)
+	return entry, entry.Command != ""
+}
+
+// unmetafy reverses zsh's escaping of bytes in the history file, which
+// otherwise garbles non-ASCII commands
+func unmetafy(data []byte) []byte {
+	if bytes.IndexByte(data, zshMeta) < 0 {
+		return data
+	}
+	out := make([]byte, 0, len(data))
+	for i := 0; i < len(data); i++ {
+		if data[i] == zshMeta && i+1 < len(data) {
+			i++
+			out = append(out, data[i]^32)
+			continue
+		}
+		out = append(out, data[i])
+	}
+	return out
+}
@@ -0,0 +1,117 @@
+package history
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"supertab/internal/ai"
+)
+
+func TestDecodeZshHistory(t *testing.T) {
+	tests := []struct {
+		name    string
+		history string
+		want    []ai.HistoryEntry
+	}{
+		{
+			name:    "plain",
+			history: "ls\ngit status\n",
+			want:    []ai.HistoryEntry{{Command: "ls"}, {Command: "git status"}},
+		},
+		{
+			name:    "extended",
+			history: ": 1700000000:0;make build\n: 1700000060:12;make test\n",
+			want: []ai.HistoryEntry{
+				{Command: "make build", Timestamp: time.Unix(1700000000, 0)},
+				{Command: "make test", Timestamp: time.Unix(1700000060, 0), Duration: "12s"},
+			},
+		},
+		{
+			name:    "multi-line",
+			history: ": 1700000000:0;for f in *; do\\\n  echo $f\\\ndone\n: 1700000001:0;pwd\n",
+			want: []ai.HistoryEntry{
+				{Command: "for f in *; do\n  echo $f\ndone", Timestamp: time.Unix(1700000000, 0)},
+				{Command: "pwd", Timestamp: time.Unix(1700000001, 0)},
+			},
+		},
+		{
+			name:    "metafied",
+			history: ": 1700000000:0;echo \xd1\x83\xb1\n",
+			want:    []ai.HistoryEntry{{Command: "echo ё", Timestamp: time.Unix(1700000000, 0)}},
+		},
+		{
+			name:    "no trailing newline",
+			history: "ls\ncd /tmp",
+			want:    []ai.HistoryEntry{{Command: "ls"}, {Command: "cd /tmp"}},
+		},
+		{
+			name:    "backslash at the end of the file",
+			history: "echo a\\",
+			want:    []ai.HistoryEntry{{Command: "echo a\\"}},
+		},
+		{
+			name:    "blank lines and CRLF",
+			history: "\r\nls\r\n\n: 1700000000:0;\n",
+			want:    []ai.HistoryEntry{{Command: "ls"}},
+		},
+		{
+			name:    "empty",
+			history: "",
+			want:    nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := decodeZshHistory(strings.NewReader(tt.history))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("decodeZshHistory() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseZshEntry(t *testing.T) {
+	tests := []struct {
+		entry  string
+		want   ai.HistoryEntry
+		wantOK bool
+	}{
+		{": 1700000000:5;git push", ai.HistoryEntry{Command: "git push", Timestamp: time.Unix(1700000000, 0), Duration: "5s"}, true},
+		{": 1700000000:x;git push", ai.HistoryEntry{Command: "git push", Timestamp: time.Unix(1700000000, 0)}, true},
+		{": notatime:0;git push", ai.HistoryEntry{Command: ": notatime:0;git push"}, true},
+		{"echo a; echo b", ai.HistoryEntry{Command: "echo a; echo b"}, true},
+		{": 1700000000:0;   ", ai.HistoryEntry{}, false},
+	}
+
+	for _, tt := range tests {
+		got, ok := parseZshEntry([]byte(tt.entry))
+		if ok != tt.wantOK || (ok && !reflect.DeepEqual(got, tt.want)) {
+			t.Errorf("parseZshEntry(%q) = %+v, %v, want %+v, %v", tt.entry, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
+func TestUnmetafy(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want string
+	}{
+		{"ascii", "git status", "git status"},
+		{"escaped byte", "\xd1\x83\xb1", "\xd1\x91"},
+		{"escaped meta", "\x83\xa3", "\x83"},
+		{"meta at the end", "a\x83", "a\x83"},
+	}
+
+	for _, tt := range tests {
+		if got := string(unmetafy([]byte(tt.data))); got != tt.want {
+			t.Errorf("unmetafy(%s) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
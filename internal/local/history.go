@@ -0,0 +1,29 @@
+package local
+
+import "strings"
+
+// completeHistory completes input to the one distinct history command that
+// extends it
+func (c *Completer) completeHistory(input string) (string, bool) {
+	// A bare command name matches too much history to be a confident answer
+	if len(strings.Fields(input)) < 2 {
+		return "", false
+	}
+
+	var match string
+	for i := len(c.History) - 1; i >= 0; i-- {
+		command := c.History[i]
+		if command == input || !strings.HasPrefix(command, input) || strings.Contains(command, "\n") {
+			continue
+		}
+		if match != "" && match != command {
+			return "", false
+		}
+		match = command
+	}
+
+	if match == "" {
+		return "", false
+	}
+	return match[len(input):], true
+}
@@ -0,0 +1,52 @@
+// Package local completes the unambiguous cases of a partial command without
+// calling a provider: filesystem paths, subcommands of common tools,
+// and commands from the shell history.
+package local
+
+import (
+	"strings"
+
+	"supertab/internal/ai"
+)
+
+// Completer resolves completions locally
+type Completer struct {
+	// Dir is the directory paths are resolved against
+	Dir string
+
+	// History holds recent commands, oldest first
+	History []string
+}
+
+// Result is a local completion and the stage that produced it
+type Result struct {
+	Response ai.Response
+	Stage    string // path, spec or history
+}
+
+// Complete returns a completion when one of the local stages has exactly one
+// answer for input, and false when the provider should be asked instead
+func (c *Completer) Complete(input string) (Result, bool) {
+	if strings.TrimSpace(input) == "" {
+		return Result{}, false
+	}
+
+	stages := []struct {
+		name     string
+		complete func(string) (string, bool)
+	}{
+		{"path", c.completePath},
+		{"spec", c.completeSpec},
+		{"history", c.completeHistory},
+	}
+
+	for _, stage := range stages {
+		if rest, ok := stage.complete(input); ok && rest != "" {
+			return Result{
+				Response: ai.Response{Type: ai.TypeCompletion, Content: rest},
+				Stage:    stage.name,
+			}, true
+		}
+	}
+	return Result{}, false
+}
@@ -0,0 +1,126 @@
+package local
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	contextpkg "supertab/internal/context"
+)
+
+// pathCommands take file arguments, so a bare word after them is a path.
+// After other commands only words containing a slash are treated as paths.
+var pathCommands = map[string]bool{
+	"cd": true, "pushd": true, "ls": true, "ll": true, "la": true, "tree": true,
+	"cat": true, "bat": true, "less": true, "more": true, "head": true, "tail": true,
+	"vi": true, "vim": true, "nvim": true, "nano": true, "emacs": true, "code": true, "open": true,
+	"rm": true, "rmdir": true, "mkdir": true, "cp": true, "mv": true, "touch": true, "ln": true,
+	"chmod": true, "chown": true, "stat": true, "file": true, "du": true,
+	"source": true, ".": true,
+}
+
+// dirCommands only take directories
+var dirCommands = map[string]bool{"cd": true, "pushd": true, "rmdir": true}
+
+// completePath completes the partial path at the end of input when exactly
+// one entry matches
+func (c *Completer) completePath(input string) (string, bool) {
+	dir, prefix, ok := contextpkg.InputPath(input)
+	if !ok {
+		return "", false
+	}
+
+	command := strings.Fields(input)[0]
+	if dir == "" && !pathCommands[command] {
+		return "", false
+	}
+	if prefix == "" {
+		// Completing the contents of a directory is a choice, not a completion
+		return "", false
+	}
+
+	path := dir
+	if path == "~/" || strings.HasPrefix(path, "~/") {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", false
+		}
+		path = filepath.Join(home, strings.TrimPrefix(path, "~"))
+	} else if !filepath.IsAbs(path) {
+		path = filepath.Join(c.Dir, path)
+	}
+
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return "", false
+	}
+
+	var match string
+	matches := 0
+	for _, entry := range entries {
+		name := entry.Name()
+		if !strings.HasPrefix(name, prefix) {
+			continue
+		}
+		isDir := entry.IsDir()
+		if entry.Type()&os.ModeSymlink != 0 {
+			if info, err := os.Stat(filepath.Join(path, name)); err == nil {
+				isDir = info.IsDir()
+			}
+		}
+		if dirCommands[command] && !isDir {
+			continue
+		}
+
+		matches++
+		match = name
+		if isDir {
+			match += "/"
+		}
+	}
+
+	if matches != 1 || match == prefix {
+		return "", false
+	}
+
+	// Inside quotes the rest of the name goes in as typed, and the quotes
+	// are closed once the path names a file
+	_, quote, _ := contextpkg.LastWord(input)
+	rest := match[len(prefix):]
+	switch quote {
+	case '\'':
+		rest = strings.ReplaceAll(rest, "'", `'\''`)
+	case '"':
+		rest = escapeDoubleQuoted(rest)
+	default:
+		return escapePath(rest), true
+	}
+	if !strings.HasSuffix(rest, "/") {
+		rest += string(quote)
+	}
+	return rest, true
+}
+
+// escapeDoubleQuoted escapes the characters the shell interprets inside double quotes
+func escapeDoubleQuoted(name string) string {
+	var b strings.Builder
+	for _, r := range name {
+		if strings.ContainsRune("\"\\$`", r) {
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// escapePath escapes characters the shell would otherwise interpret
+func escapePath(name string) string {
+	var b strings.Builder
+	for _, r := range name {
+		if strings.ContainsRune(" \t'\"\\$`!&;|<>()[]{}*?#~", r) {
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
@@ -0,0 +1,47 @@
+package local
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCompletePath(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"my file", `say "hi".txt`, "main.go"} {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Mkdir(filepath.Join(dir, "my dir"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	c := &Completer{Dir: dir}
+
+	tests := []struct {
+		input  string
+		want   string
+		wantOK bool
+	}{
+		{"vim ma", "in.go", true},
+		{"vim my", "", false}, // my file, my dir
+		{`vim my\ fi`, "le", true},
+		{`vim "my fi`, `le"`, true},
+		{`vim 'my fi`, "le'", true},
+		{`vim "my d`, "ir/", true}, // the quote stays open for the rest of the path
+		{`vim my\ d`, "ir/", true},
+		{`cd "my`, " dir/", true}, // only directories
+		{`vim sa`, `y\ \"hi\".txt`, true},
+		{`vim "sa`, `y \"hi\".txt"`, true},
+		{`vim 'sa`, `y "hi".txt'`, true},
+		{"vim my\\ file", "", false}, // already complete
+		{"git ma", "", false},        // not a path command
+	}
+
+	for _, tt := range tests {
+		got, ok := c.completePath(tt.input)
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("completePath(%q) = %q, %v, want %q, %v", tt.input, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
@@ -0,0 +1,134 @@
+package local
+
+import (
+	"context"
+	_ "embed"
+	"os/exec"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// specNode lists the subcommands known to follow a command word. A nil node
+// is a leaf whose arguments are not known.
+type specNode map[string]specNode
+
+// incompleteKey marks a node that does not list every subcommand, e.g.
+// because plugins or aliases can add more. Such a node is walked through but
+// never completed, since a single match may not be the only one.
+const incompleteKey = "..."
+
+// gitListTimeout bounds the git call listing its commands and aliases
+const gitListTimeout = 300 * time.Millisecond
+
+//go:embed spec.yaml
+var specData []byte
+
+var (
+	specOnce sync.Once
+	spec     specNode
+)
+
+// loadSpec parses the embedded spec once
+func loadSpec() specNode {
+	specOnce.Do(func() {
+		if err := yaml.Unmarshal(specData, &spec); err != nil {
+			spec = specNode{}
+		}
+	})
+	return spec
+}
+
+// completeSpec completes the last word of input when it is the prefix of
+// exactly one subcommand of a node that lists them all. Flags are not
+// completed, since no spec lists every flag of a command.
+func (c *Completer) completeSpec(input string) (string, bool) {
+	if strings.HasSuffix(input, " ") {
+		return "", false
+	}
+	words := strings.Fields(input)
+	if len(words) < 2 {
+		return "", false
+	}
+
+	node, ok := loadSpec()[words[0]]
+	if !ok || node == nil {
+		return "", false
+	}
+
+	// Walk the subcommands typed so far. Flags are skipped, but an unknown
+	// word means an argument the spec cannot follow.
+	for _, word := range words[1 : len(words)-1] {
+		if strings.HasPrefix(word, "-") {
+			continue
+		}
+		next, ok := node[word]
+		if !ok || next == nil {
+			return "", false
+		}
+		node = next
+	}
+
+	partial := words[len(words)-1]
+	if strings.HasPrefix(partial, "-") {
+		return "", false
+	}
+
+	names := node.subcommands()
+	if len(words) == 2 && words[0] == "git" {
+		// git runs any git-* program on the PATH and the user's aliases, so
+		// only git itself can list its subcommands
+		names = c.gitCommands()
+	} else if _, incomplete := node[incompleteKey]; incomplete {
+		return "", false
+	}
+
+	return uniqueCompletion(names, partial)
+}
+
+// subcommands returns the subcommands listed in the node
+func (n specNode) subcommands() []string {
+	names := make([]string, 0, len(n))
+	for name := range n {
+		if name != incompleteKey {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// gitCommands lists the commands git accepts in the completer's directory:
+// its own, git-* programs on the PATH and aliases, including those of the
+// repository. It returns nil when git cannot list them.
+func (c *Completer) gitCommands() []string {
+	ctx, cancel := context.WithTimeout(context.Background(), gitListTimeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, "git", "--list-cmds=main,others,alias,nohelpers")
+	cmd.Dir = c.Dir
+	output, err := cmd.Output()
+	if err != nil {
+		return nil
+	}
+	return strings.Fields(string(output))
+}
+
+// uniqueCompletion returns the rest of the one name extending partial
+func uniqueCompletion(names []string, partial string) (string, bool) {
+	var matches []string
+	for _, name := range names {
+		if strings.HasPrefix(name, partial) {
+			matches = append(matches, name)
+		}
+	}
+	slices.Sort(matches)
+	matches = slices.Compact(matches) // an alias may repeat a command name
+
+	if len(matches) != 1 || matches[0] == partial {
+		return "", false
+	}
+	return matches[0][len(partial):], true
+}
@@ -0,0 +1,98 @@
+package local
+
+import (
+	"os/exec"
+	"testing"
+)
+
+func TestLoadSpec(t *testing.T) {
+	spec := loadSpec()
+	for _, tool := range []string{"git", "docker", "kubectl", "go", "terraform", "helm"} {
+		if _, ok := spec[tool]; !ok {
+			t.Errorf("spec has no %s node", tool)
+		}
+	}
+	if _, ok := spec["git"][incompleteKey]; !ok {
+		t.Errorf("git node is not marked incomplete")
+	}
+}
+
+func TestCompleteSpec(t *testing.T) {
+	c := &Completer{Dir: t.TempDir()}
+
+	tests := []struct {
+		input  string
+		want   string
+		wantOK bool
+	}{
+		{"go mod ti", "dy", true},
+		{"go tes", "t", true},
+		{"go te", "", false}, // telemetry, test
+		{"go v", "", false},  // version, vet
+		{"git stash dr", "op", true},
+		{"git stash -p dr", "op", true},
+		{"git stash p", "", false}, // pop, push
+		{"git worktree rem", "ove", true},
+		{"kubectl rollout und", "o", true},
+		{"docker system d", "f", true},
+		{"docker compose u", "", false}, // not in the spec
+		{"docker ru", "", false},        // plugins add top-level commands
+		{"kubectl ap", "", false},       // plugins add top-level commands
+		{"terraform ap", "", false},     // not listed in full
+		{"go mod tidy", "", false},      // already complete
+		{"go mod ", "", false},          // nothing typed yet
+		{"go", "", false},
+		{"git stash push --ke", "", false}, // flags are not listed
+		{"go mod tidy -v x", "", false},    // leaf arguments are unknown
+		{"npm ins", "", false},
+	}
+
+	for _, tt := range tests {
+		got, ok := c.completeSpec(tt.input)
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("completeSpec(%q) = %q, %v, want %q, %v", tt.input, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
+func TestCompleteSpecGit(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+
+	dir := t.TempDir()
+	for _, args := range [][]string{
+		{"init", "--quiet"},
+		{"config", "alias.stk", "stash keep"},
+	} {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		if output, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v: %s", args, err, output)
+		}
+	}
+	c := &Completer{Dir: dir}
+
+	tests := []struct {
+		input  string
+		want   string
+		wantOK bool
+	}{
+		{"git co", "", false}, // commit, config, checkout, ...
+		{"git sh", "", false}, // show, shortlog, ...
+		{"git di", "", false}, // diff, difftool
+		{"git me", "", false}, // merge, mergetool
+		{"git stat", "us", true},
+		{"git st", "", false},    // stash, status, the stk alias, ...
+		{"git stk", "", false},   // the alias itself
+		{"git bisec", "t", true}, // bisect only
+		{"git rebase -i", "", false},
+	}
+
+	for _, tt := range tests {
+		got, ok := c.completeSpec(tt.input)
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("completeSpec(%q) = %q, %v, want %q, %v", tt.input, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}